@@ -3,87 +3,495 @@ package app_cli
 import (
 	"fmt"
 	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+	"github.com/slivtime/flibusta-cli/pkg/mirror"
+	"github.com/slivtime/flibusta-cli/pkg/pager"
+	"github.com/slivtime/flibusta-cli/pkg/server"
 	"github.com/urfave/cli/v2"
-	"io/ioutil"
-	"log"
 	"os"
 	"strings"
+	"sync"
+	"text/template"
 )
 
 const defaultBookFormat = "mobi"
 
-type FlibustaCLI struct{}
+type FlibustaCLI struct {
+	debug bool
+}
 
 func commandSearch(context *cli.Context) error {
 	query := strings.Join(context.Args().Slice(), " ")
-	flibusta, err := client.FromEnv()
+	p, err := commandPrinter(context, bookRecord{})
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if context.Bool("offline") {
+		return commandSearchOffline(p, query)
 	}
-	fmt.Println("search book: ", query)
-	searchResult, err := flibusta.Search(query, client.ParseSearch)
+	backend, err := backendFromEnv()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	for _, item := range *searchResult {
-		fmt.Println(item.String())
+	p.notice("search book: ", query)
+	searchResult, err := backend.Search(query)
+	if err != nil {
+		return err
 	}
-	return nil
+	return p.books(*searchResult)
 }
 
 func commandGet(context *cli.Context) error {
 	bookID := context.Args().First()
 	if bookID == "" {
-		log.Fatal("bookID is required parameter")
+		return usageErrorf("bookID is required parameter")
 	}
 
+	p, err := commandPrinter(context, downloadReport{})
+	if err != nil {
+		return err
+	}
 	flibusta, err := client.FromEnv()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	getter, err := newBookGetter(context, flibusta, books)
+	if err != nil {
+		return err
+	}
+	getter.notice = p.notice
+	report, err := getter.get(bookID, context.String("format"), warn)
+	if err != nil {
+		return err
 	}
-	bookFormat := context.String("format")
-	fmt.Printf("get book <%s> in `%s` format\n", bookID, bookFormat)
-	result, err := flibusta.Download(bookID, bookFormat)
+	return p.one(report)
+}
+
+// bookGetter saves books the way `get` does with its flags, the shell and interactive mode share it.
+type bookGetter struct {
+	context      *cli.Context
+	flibusta     *client.FlibustaClient
+	books        *library.Library
+	nameTemplate *template.Template
+	// info requests book page, the shell reuses pages shown before
+	info func(bookID string) (*client.InfoResult, error)
+	// notice gets progress messages, it is nil when nobody reads them
+	notice func(args ...interface{})
+
+	// mu guards library which is shared by downloads of a session
+	mu sync.Mutex
+}
+
+func newBookGetter(context *cli.Context, flibusta *client.FlibustaClient, books *library.Library) (*bookGetter, error) {
+	nameTemplate, err := parseNameTemplate(context)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	return &bookGetter{
+		context:      context,
+		flibusta:     flibusta,
+		books:        books,
+		nameTemplate: nameTemplate,
+		info: func(bookID string) (*client.InfoResult, error) {
+			return flibusta.Info(bookID, client.ParseInfo)
+		},
+	}, nil
+}
+
+func (g *bookGetter) noticef(format string, args ...interface{}) {
+	if g.notice != nil {
+		g.notice(fmt.Sprintf(format, args...))
+	}
+}
 
+// get downloads the book unless library already has it, converts, unpacks, saves and records it.
+// Failures which do not stop saving, like a book not recorded in library, go to warn.
+func (g *bookGetter) get(bookID string, bookFormat string, warn func(message string, err error)) (downloadReport, error) {
+	context := g.context
+	if g.books != nil && !context.Bool("redownload") {
+		g.mu.Lock()
+		entry := g.books.Owned(bookID, ownedFormats(bookFormat)...)
+		g.mu.Unlock()
+		if entry != nil {
+			return downloadReport{ID: bookID, Format: entry.Format, Path: entry.Path, Size: entry.Size, Status: downloadOwned}, nil
+		}
+	}
+	var info *client.InfoResult
+	var err error
+	if g.nameTemplate != nil || needsBookInfo(context, bookFormat) {
+		info, err = g.info(bookID)
+		if err != nil {
+			return downloadReport{}, err
+		}
+	} else if g.books != nil {
+		info, err = g.info(bookID)
+		if err != nil {
+			warn("book info is not recorded in library", err)
+		}
+	}
+	downloadFormat, err := chooseBookFormat(context, info, bookFormat)
+	if err != nil {
+		return downloadReport{}, err
+	}
+	g.noticef("get book <%s> in `%s` format", bookID, downloadFormat)
+	result, err := g.flibusta.Download(bookID, downloadFormat)
+	if err != nil {
+		return downloadReport{}, err
+	}
+	if downloadFormat == client.Fb2 && bookFormat == client.Epub && !context.Bool("no-convert") {
+		g.noticef("epub is not available, converting from fb2")
+		err = convertToEpub(result)
+		if err != nil {
+			return downloadReport{}, err
+		}
+	} else {
+		bookFormat = downloadFormat
+	}
+
+	err = client.VerifyFormat(bookFormat, result.File)
+	if err != nil {
+		return downloadReport{}, err
+	}
 	if result.Name == "" {
 		result.Name = fmt.Sprintf("%s.%s", bookID, bookFormat)
 	}
-	err = ioutil.WriteFile(result.Name, result.File, 0644)
+	var archive *client.DownloadResult
+	if context.Bool("unpack") && result.IsZipped() {
+		archive = result
+		result, bookFormat, err = unpackBook(result, bookFormat)
+		if err != nil {
+			return downloadReport{}, err
+		}
+	}
+	fileName, err := bookFileName(context, g.nameTemplate, info, result, bookFormat)
 	if err != nil {
-		log.Fatal(err)
+		return downloadReport{}, err
 	}
-	fmt.Println("File saved at", result.Name)
-	return nil
+	err = saveBook(context, fileName, result.File)
+	if err != nil {
+		return downloadReport{}, err
+	}
+	if archive != nil && context.Bool("keep-zip") {
+		err = saveBook(context, fileName+".zip", archive.File)
+		if err != nil {
+			return downloadReport{}, err
+		}
+		g.noticef("Archive saved at %s", fileName+".zip")
+	}
+	if g.books != nil {
+		g.mu.Lock()
+		err = recordBook(g.books, bookID, info, bookFormat, fileName, result.File)
+		g.mu.Unlock()
+		if err != nil {
+			warn("book is not recorded in library", err)
+		}
+	}
+	return downloadReport{ID: bookID, Format: bookFormat, Path: fileName, Size: int64(len(result.File)), Status: downloadSaved}, nil
+}
+
+// unpackBook extracts the book from zipped download and checks it is in the format inside the archive.
+func unpackBook(result *client.DownloadResult, bookFormat string) (*client.DownloadResult, string, error) {
+	book, err := result.Unpack()
+	if err != nil {
+		return nil, "", err
+	}
+	bookFormat = strings.TrimSuffix(bookFormat, ".zip")
+	err = client.VerifyFormat(bookFormat, book.File)
+	if err != nil {
+		return nil, "", err
+	}
+	return book, bookFormat, nil
+}
+
+// needsBookInfo tells if formats from book page are needed to choose the format
+func needsBookInfo(context *cli.Context, bookFormat string) bool {
+	convert := bookFormat == client.Epub && !context.Bool("no-convert")
+	return convert || len(context.StringSlice("fallback")) > 0
+}
+
+// chooseBookFormat checks formats advertised on the book page and picks the format to download.
+// Missing epub is converted from fb2, otherwise the first available fallback format is used.
+func chooseBookFormat(context *cli.Context, info *client.InfoResult, bookFormat string) (string, error) {
+	if !needsBookInfo(context, bookFormat) {
+		return bookFormat, nil
+	}
+	fallback := context.StringSlice("fallback")
+	preferred := []string{bookFormat}
+	if bookFormat == client.Epub && !context.Bool("no-convert") {
+		preferred = append(preferred, client.Fb2)
+	}
+	return client.ChooseFormat(info.Formats, append(preferred, fallback...))
 }
 
 func commandInfo(context *cli.Context) error {
 	bookID := context.Args().First()
+	if bookID == "" {
+		return usageErrorf("bookID is required parameter")
+	}
 
+	p, err := commandPrinter(context, infoRecord{&client.InfoResult{}})
+	if err != nil {
+		return err
+	}
 	flibusta, err := client.FromEnv()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	fmt.Println("book info: ", bookID)
+	p.notice("book info: ", bookID)
 	infoResult, err := flibusta.Info(bookID, client.ParseInfo)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	return p.one(infoRecord{infoResult})
+}
+
+func commandRead(context *cli.Context) error {
+	bookID := context.Args().First()
+	if bookID == "" {
+		return usageErrorf("bookID is required parameter")
+	}
+
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return err
+	}
+	book, err := flibusta.Read(bookID, client.ParseRead)
+	if err != nil {
+		return err
+	}
+
+	width := context.Int("width")
+	if width < 1 {
+		return usageErrorf("width must be positive, got %d", width)
+	}
+	lines := pager.Render(book, width)
+	bookPager := pager.New(lines, context.Int("height"), os.Stdin, os.Stdout)
+	bookPager.BookID = bookID
+	bookmarksFile, err := pager.DefaultBookmarksFile()
+	if err == nil {
+		bookPager.Bookmarks, err = pager.LoadBookmarks(bookmarksFile)
+	}
+	if err != nil {
+		warn("bookmarks are disabled", err)
+	}
+	return bookPager.Run()
+}
+
+func commandSelfTest(context *cli.Context) error {
+	p, err := commandPrinter(context, checkRecord{})
+	if err != nil {
+		return err
+	}
+	if selectorsFile := context.String("selectors"); selectorsFile != "" {
+		profile, err := client.LoadSelectors(selectorsFile)
+		if err != nil {
+			return err
+		}
+		err = client.UseSelectors(*profile)
+		if err != nil {
+			return err
+		}
+	}
+
+	var live *client.FlibustaClient
+	if context.Bool("live") {
+		flibusta, err := client.FromEnv()
+		if err != nil {
+			return err
+		}
+		live = flibusta
+	}
+
+	p.notice("selectors version:", client.ActiveSelectors().Version)
+	failed := 0
+	var records []record
+	for _, result := range client.SelfTest(live) {
+		if result.Error != nil {
+			failed++
+		}
+		records = append(records, newCheckRecord(&result))
+	}
+	err = p.list(checkRecord{}.header(), records)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
 	}
-	fmt.Println(infoResult.String())
 	return nil
 }
 
+// getFlags choose how books are downloaded and saved, they are shared by get, interactive and shell.
+var getFlags = []cli.Flag{
+	&cli.StringSliceFlag{
+		Name:    "fallback",
+		Usage:   "Formats to try in order when requested one is not available, e.g. epub,fb2,pdf",
+		EnvVars: []string{"FLIBUSTA_FALLBACK_FORMATS"},
+	},
+	&cli.BoolFlag{
+		Name:  "no-convert",
+		Usage: "Do not convert fb2 to epub when epub is not available",
+	},
+	&cli.StringFlag{
+		Name:    "output-dir",
+		Aliases: []string{"o"},
+		Value:   ".",
+		Usage:   "Directory to save books to",
+		EnvVars: []string{"FLIBUSTA_OUTPUT_DIR"},
+	},
+	&cli.StringFlag{
+		Name:    "name-template",
+		Aliases: []string{"t"},
+		Usage:   "File name template, e.g. '{{.Author}} - {{.Series}} {{.SeriesNumber}} - {{.Title}}'. Fields: ID, Title, Author, Authors, Series, SeriesNumber, Year, Format",
+		EnvVars: []string{"FLIBUSTA_NAME_TEMPLATE"},
+	},
+	&cli.BoolFlag{
+		Name:    "translit",
+		Usage:   "Transliterate Cyrillic in file names",
+		EnvVars: []string{"FLIBUSTA_TRANSLIT"},
+	},
+	&cli.BoolFlag{
+		Name:  "force",
+		Usage: "Overwrite existing file",
+	},
+	&cli.BoolFlag{
+		Name:  "redownload",
+		Usage: "Download book again when it is already in library",
+	},
+	&cli.BoolFlag{
+		Name:    "unpack",
+		Usage:   "Extract book from zip archive, e.g. fb2 from fb2.zip",
+		EnvVars: []string{"FLIBUSTA_UNPACK"},
+	},
+	&cli.BoolFlag{
+		Name:  "keep-zip",
+		Usage: "Keep zip archive next to unpacked book",
+	},
+	&cli.BoolFlag{
+		Name:  "no-library",
+		Usage: "Do not check and record the book in local library",
+	},
+	&cli.BoolFlag{
+		Name:  "keep-both",
+		Usage: "Save under a numbered name when file exists, e.g. 'book (2).epub'",
+	},
+}
+
 func (c *FlibustaCLI) Start() (err error) {
 	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Value:   outputText,
+				Usage:   "Output format: " + strings.Join(outputFormats, "|") + ", informational messages go to stderr in machine formats",
+				EnvVars: []string{"FLIBUSTA_OUTPUT"},
+			},
+			&cli.StringFlag{
+				Name:  "template",
+				Usage: "Go template for every result in text output, e.g. '{{.ID}}\\t{{.Title}}', helpers: join, truncate, transliterate, humanize",
+			},
+			&cli.StringFlag{
+				Name:  "template-file",
+				Usage: "File with template for every result in text output",
+			},
+			&cli.BoolFlag{
+				Name:    "debug",
+				Usage:   "Show failures of every mirror and wrapped errors",
+				EnvVars: []string{"FLIBUSTA_DEBUG"},
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "Log every mirror request, not only the one used",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Log errors only",
+			},
+		},
+		Before: func(context *cli.Context) error {
+			c.debug = context.Bool("debug")
+			err := setupLogging(context)
+			if err != nil {
+				return err
+			}
+			_, err = newPrinter(context)
+			return asUsageError(err)
+		},
+		OnUsageError: onUsageError,
+		// Errors are reported by Run, cli would exit right away
+		ExitErrHandler: func(*cli.Context, error) {},
 		Commands: cli.Commands{
 			&cli.Command{
 				Name:    "search",
 				Aliases: []string{"s"},
 				Usage:   "Search book",
 				Action:  commandSearch,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:    "offline",
+						Usage:   "Search in imported catalog instead of the site, see `catalog import`",
+						EnvVars: []string{"FLIBUSTA_OFFLINE"},
+					},
+				},
+			},
+			&cli.Command{
+				Name:      "author",
+				Usage:     "List books of the author (OPDS backend)",
+				ArgsUsage: "<authorID>",
+				Action:    commandAuthor,
+			},
+			&cli.Command{
+				Name:      "series",
+				Usage:     "List books of the series (OPDS backend)",
+				ArgsUsage: "<seriesID>",
+				Action:    commandSeries,
+			},
+			&cli.Command{
+				Name:   "new",
+				Usage:  "List new arrivals (OPDS backend)",
+				Action: commandNew,
+			},
+			&cli.Command{
+				Name:      "interactive",
+				Aliases:   []string{"ui"},
+				Usage:     "Search, preview and download books in full screen terminal interface",
+				ArgsUsage: "[query]",
+				Action:    commandInteractive,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Value:   defaultBookFormat,
+						Usage:   "Format selected by default when the book has it",
+						EnvVars: []string{"FLIBUSTA_PREFERRED_FORMAT"},
+					},
+				}, getFlags...),
+			},
+			&cli.Command{
+				Name:   "shell",
+				Usage:  "Run search, info, get, author and series commands in one session with history and completion",
+				Action: commandShell,
+				Flags: append([]cli.Flag{
+					&cli.StringFlag{
+						Name:    "format",
+						Aliases: []string{"f"},
+						Value:   defaultBookFormat,
+						Usage:   "Format `get` downloads when none is given",
+						EnvVars: []string{"FLIBUSTA_PREFERRED_FORMAT"},
+					},
+					&cli.StringFlag{
+						Name:    "history-file",
+						Usage:   "File to keep command history in, default is in user config directory",
+						EnvVars: []string{"FLIBUSTA_SHELL_HISTORY"},
+					},
+				}, getFlags...),
 			},
 			&cli.Command{
 				Name:    "info",
@@ -96,22 +504,275 @@ func (c *FlibustaCLI) Start() (err error) {
 				Aliases: []string{"g"},
 				Usage:   "Get book",
 				Action:  commandGet,
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.StringFlag{
 						Name:    "format",
 						Aliases: []string{"f"},
 						Value:   defaultBookFormat,
-						Usage:   "Format to download: mobi|epub|fb2",
+						Usage:   "Format to download: mobi|epub|fb2|pdf|djvu|...",
 						EnvVars: []string{"FLIBUSTA_PREFERRED_FORMAT"},
 					},
+				}, getFlags...),
+			},
+			&cli.Command{
+				Name:    "read",
+				Aliases: []string{"r"},
+				Usage:   "Read book in terminal",
+				Action:  commandRead,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "width",
+						Value:   80,
+						Usage:   "Text width",
+						EnvVars: []string{"COLUMNS"},
+					},
+					&cli.IntFlag{
+						Name:    "height",
+						Value:   24,
+						Usage:   "Lines per page",
+						EnvVars: []string{"LINES"},
+					},
+				},
+			},
+			&cli.Command{
+				Name:    "library",
+				Aliases: []string{"l"},
+				Usage:   "Books downloaded with get",
+				Subcommands: cli.Commands{
+					&cli.Command{
+						Name:   "list",
+						Usage:  "List downloaded books",
+						Action: commandLibraryList,
+					},
+					&cli.Command{
+						Name:   "search",
+						Usage:  "Search downloaded books by title, author or series",
+						Action: commandLibrarySearch,
+					},
+					&cli.Command{
+						Name:   "show",
+						Usage:  "Show book info and saved files",
+						Action: commandLibraryShow,
+					},
+					&cli.Command{
+						Name:   "remove",
+						Usage:  "Forget downloaded book",
+						Action: commandLibraryRemove,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "format",
+								Aliases: []string{"f"},
+								Usage:   "Remove only this format",
+							},
+							&cli.BoolFlag{
+								Name:  "delete-file",
+								Usage: "Also delete the book file",
+							},
+						},
+					},
+					&cli.Command{
+						Name:      "export-inpx",
+						Usage:     "Write INPX index of downloaded books for MyHomeLib and other catalog software",
+						ArgsUsage: "[library.inpx]",
+						Action:    commandLibraryExportINPX,
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "root",
+								Value:   ".",
+								Usage:   "Collection directory, books outside of it are skipped",
+								EnvVars: []string{"FLIBUSTA_OUTPUT_DIR"},
+							},
+							&cli.StringFlag{
+								Name:  "name",
+								Value: "Flibusta",
+								Usage: "Collection name",
+							},
+							&cli.StringFlag{
+								Name:  "description",
+								Value: "Books downloaded with flibusta-cli",
+								Usage: "Collection description",
+							},
+						},
+					},
+				},
+			},
+			&cli.Command{
+				Name:  "catalog",
+				Usage: "Catalog of all books for offline search",
+				Subcommands: cli.Commands{
+					&cli.Command{
+						Name:      "import",
+						Usage:     "Import catalog.zip downloaded from the site, or given file",
+						ArgsUsage: "[catalog.zip]",
+						Action:    commandCatalogImport,
+					},
+				},
+			},
+			&cli.Command{
+				Name:  "db",
+				Usage: "Local database of library metadata imported from the site's SQL dumps",
+				Subcommands: cli.Commands{
+					&cli.Command{
+						Name:      "import",
+						Usage:     "Import dumps downloaded from the site, or given .sql/.sql.gz files",
+						ArgsUsage: "[lib.libbook.sql.gz ...]",
+						Action:    commandDBImport,
+					},
+					&cli.Command{
+						Name:   "book",
+						Usage:  "Show book with authors, translators, series and genres",
+						Action: commandDBBook,
+					},
+					&cli.Command{
+						Name:   "search",
+						Usage:  "Search books by title",
+						Action: commandDBSearch,
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "limit",
+								Value: 50,
+								Usage: "Maximum number of results",
+							},
+						},
+					},
+					&cli.Command{
+						Name:      "author",
+						Usage:     "Show books of author, or find authors by name",
+						ArgsUsage: "<author ID|name>",
+						Action:    commandDBAuthor,
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "limit",
+								Value: 50,
+								Usage: "Maximum number of results",
+							},
+						},
+					},
+					&cli.Command{
+						Name:      "series",
+						Usage:     "Show books of series in order, or find series by name",
+						ArgsUsage: "<series ID|name>",
+						Action:    commandDBSeries,
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "limit",
+								Value: 50,
+								Usage: "Maximum number of results",
+							},
+						},
+					},
+					&cli.Command{
+						Name:   "stats",
+						Usage:  "Show number of imported records",
+						Action: commandDBStats,
+					},
+				},
+			},
+			&cli.Command{
+				Name:   "sync",
+				Usage:  "Update local mirror of the library from daily archives",
+				Action: commandSync,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "dir",
+						Aliases: []string{"d"},
+						Usage:   "Mirror directory",
+						EnvVars: []string{mirror.DirEnvKey},
+					},
+					&cli.StringFlag{
+						Name:  "match",
+						Usage: "Apply only archives matching pattern, e.g. 'f.fb2.*'",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Apply at most this number of archives",
+					},
+					&cli.BoolFlag{
+						Name:  "list",
+						Usage: "List applied and pending archives without applying them",
+					},
+					&cli.BoolFlag{
+						Name:  "keep-archives",
+						Usage: "Keep downloaded archives after extraction",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   "serve",
+				Usage:  "Serve OPDS catalog for e-readers in local network, optional JSON API and web UI, books are downloaded through configured proxy",
+				Action: commandServe,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "listen",
+						Aliases: []string{"l"},
+						Value:   server.DefaultListen,
+						Usage:   "Address to listen on",
+						EnvVars: []string{server.ListenEnvKey},
+					},
+					&cli.BoolFlag{
+						Name:  "no-library",
+						Usage: "Do not serve books of local library",
+					},
+					&cli.BoolFlag{
+						Name:  "api",
+						Usage: "Serve JSON API too, see /openapi.json",
+					},
+					&cli.BoolFlag{
+						Name:  "web",
+						Usage: "Serve web UI for searching and downloading in browser at /ui/",
+					},
+					&cli.DurationFlag{
+						Name:  "cache-ttl",
+						Value: server.DefaultCacheTTL,
+						Usage: "How long search results and book info are reused, 0 disables cache",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   "inspect",
+				Usage:  "Show metadata of downloaded fb2 or fb2.zip file",
+				Action: commandInspect,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "cover",
+						Usage: "Save cover image to file",
+					},
+				},
+			},
+			&cli.Command{
+				Name:   "selftest",
+				Usage:  "Check that parsers still understand site pages",
+				Action: commandSelfTest,
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "live",
+						Usage: "Also check pages fetched from mirrors",
+					},
+					&cli.StringFlag{
+						Name:    "selectors",
+						Usage:   "JSON file overriding parser selectors",
+						EnvVars: []string{client.SelectorsFileEnvKey},
+					},
 				},
 			},
 		},
 	}
 
+	markUsageErrors(app.Commands)
+
 	err = app.Run(os.Args)
 	if err != nil {
 		return
 	}
 	return nil
 }
+
+// Run starts the application and returns exit code, errors are reported to stderr.
+func (c *FlibustaCLI) Run() int {
+	err := c.Start()
+	if err == nil {
+		return 0
+	}
+	reportError(os.Stderr, err, c.debug)
+	return exitCode(err)
+}
@@ -0,0 +1,73 @@
+package app_cli
+
+import (
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// backendFromEnv returns listing backend chosen by FLIBUSTA_BACKEND.
+func backendFromEnv() (client.Backend, error) {
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return flibusta.Backend()
+}
+
+func commandAuthor(context *cli.Context) error {
+	authorID := context.Args().First()
+	if authorID == "" {
+		return usageErrorf("authorID is required parameter")
+	}
+	p, err := commandPrinter(context, bookRecord{})
+	if err != nil {
+		return err
+	}
+	backend, err := backendFromEnv()
+	if err != nil {
+		return err
+	}
+	author, err := backend.Author(authorID)
+	if err != nil {
+		return err
+	}
+	p.notice("author:", author.Name)
+	return p.books(author.Books)
+}
+
+func commandSeries(context *cli.Context) error {
+	seriesID := context.Args().First()
+	if seriesID == "" {
+		return usageErrorf("seriesID is required parameter")
+	}
+	p, err := commandPrinter(context, bookRecord{})
+	if err != nil {
+		return err
+	}
+	backend, err := backendFromEnv()
+	if err != nil {
+		return err
+	}
+	series, err := backend.Series(seriesID)
+	if err != nil {
+		return err
+	}
+	p.notice("series:", series.Name)
+	return p.books(series.Books)
+}
+
+func commandNew(context *cli.Context) error {
+	p, err := commandPrinter(context, bookRecord{})
+	if err != nil {
+		return err
+	}
+	backend, err := backendFromEnv()
+	if err != nil {
+		return err
+	}
+	books, err := backend.NewArrivals()
+	if err != nil {
+		return err
+	}
+	return p.books(*books)
+}
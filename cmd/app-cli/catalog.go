@@ -0,0 +1,68 @@
+package app_cli
+
+import (
+	"errors"
+	"os"
+
+	"github.com/slivtime/flibusta-cli/pkg/catalog"
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// commandCatalogImport reads catalog from file given as argument, or downloads it from mirrors.
+func commandCatalogImport(context *cli.Context) error {
+	var data []byte
+	var err error
+	if fileName := context.Args().First(); fileName != "" {
+		data, err = os.ReadFile(fileName)
+	} else {
+		var flibusta *client.FlibustaClient
+		flibusta, err = client.FromEnv()
+		if err != nil {
+			return err
+		}
+		var result *client.DownloadResult
+		result, err = flibusta.Catalog()
+		if result != nil {
+			data = result.File
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	books, err := catalog.ParseZip(data)
+	if err != nil {
+		return err
+	}
+	fileName, err := catalog.DefaultFile()
+	if err != nil {
+		return err
+	}
+	err = books.Save(fileName)
+	if err != nil {
+		return err
+	}
+	p, err := commandPrinter(context, nil)
+	if err != nil {
+		return err
+	}
+	p.noticef("%d books imported to %s\n", len(books.Books), fileName)
+	return nil
+}
+
+func commandSearchOffline(p *printer, query string) error {
+	fileName, err := catalog.DefaultFile()
+	if err != nil {
+		return err
+	}
+	books, err := catalog.Load(fileName)
+	if os.IsNotExist(err) {
+		return errors.New("catalog is not imported yet, run `catalog import` first")
+	}
+	if err != nil {
+		return err
+	}
+	p.notice("search book offline: ", query)
+	return p.books(books.Search(query, 0))
+}
@@ -0,0 +1,29 @@
+package app_cli
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/epub"
+	"github.com/slivtime/flibusta-cli/pkg/fb2"
+)
+
+// convertToEpub replaces downloaded fb2 or fb2.zip with epub converted locally.
+func convertToEpub(result *client.DownloadResult) error {
+	book, err := fb2.OpenBytes(result.File)
+	if err != nil {
+		return err
+	}
+	out := &bytes.Buffer{}
+	err = epub.ConvertFB2(book, out)
+	if err != nil {
+		return err
+	}
+	result.File = out.Bytes()
+	if result.Name != "" {
+		name := strings.TrimSuffix(result.Name, ".zip")
+		result.Name = strings.TrimSuffix(name, "."+client.Fb2) + "." + client.Epub
+	}
+	return nil
+}
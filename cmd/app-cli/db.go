@@ -0,0 +1,233 @@
+package app_cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/localdb"
+	"github.com/urfave/cli/v2"
+)
+
+func openLocalDB() (*localdb.DB, error) {
+	fileName, err := localdb.DefaultFile()
+	if err != nil {
+		return nil, err
+	}
+	return localdb.Open(fileName)
+}
+
+// commandDBImport imports dump files given as arguments, or downloads all dumps from mirrors.
+func commandDBImport(context *cli.Context) error {
+	p, err := commandPrinter(context, importRecord{})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var records []record
+	files := context.Args().Slice()
+	if len(files) > 0 {
+		for _, fileName := range files {
+			file, err := os.Open(fileName)
+			if err != nil {
+				return err
+			}
+			p.noticef("Import %s\n", fileName)
+			stats, err := db.Import(file)
+			_ = file.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", fileName, err)
+			}
+			records = append(records, importRecords(fileName, stats)...)
+		}
+		return p.list(importRecord{}.header(), records)
+	}
+
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return err
+	}
+	for _, name := range localdb.DumpFiles {
+		p.noticef("Import %s\n", name)
+		var stats *localdb.ImportStats
+		err = flibusta.SQLDump(name, func(stream io.Reader) (err error) {
+			stats, err = db.Import(stream)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		records = append(records, importRecords(name, stats)...)
+	}
+	return p.list(importRecord{}.header(), records)
+}
+
+func commandDBBook(context *cli.Context) error {
+	bookID := context.Args().First()
+	if bookID == "" {
+		return usageErrorf("bookID is required parameter")
+	}
+	p, err := commandPrinter(context, dbBookRecord{&localdb.BookDetails{}})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	book, err := db.Book(bookID)
+	if err != nil {
+		return fmt.Errorf("book %s: %w", bookID, err)
+	}
+	return p.one(dbBookRecord{book})
+}
+
+func commandDBSearch(context *cli.Context) error {
+	query := strings.Join(context.Args().Slice(), " ")
+	p, err := commandPrinter(context, bookRecord{})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	books, err := db.SearchBooks(query, context.Int("limit"))
+	if err != nil {
+		return err
+	}
+	items := make([]client.ListItem, 0, len(books))
+	for _, book := range dbBookItems(db, books, nil) {
+		items = append(items, book.ListItem)
+	}
+	return p.books(items)
+}
+
+// commandDBAuthor shows books of author by ID, or lists authors matching the name.
+func commandDBAuthor(context *cli.Context) error {
+	query := strings.Join(context.Args().Slice(), " ")
+	p, err := commandPrinter(context, authorRecord{Author: &localdb.Author{}})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !isID(query) {
+		authors, err := db.SearchAuthors(query, context.Int("limit"))
+		if err != nil {
+			return err
+		}
+		records := make([]record, 0, len(authors))
+		for _, author := range authors {
+			records = append(records, authorRecord{Author: author, Name: author.String()})
+		}
+		return p.list(authorRecord{}.header(), records)
+	}
+	author, books, err := db.Author(query)
+	if err != nil {
+		return fmt.Errorf("author %s: %w", query, err)
+	}
+	return p.one(authorRecord{Author: author, Name: author.String(), Books: dbBookItems(db, books, nil)})
+}
+
+// commandDBSeries shows books of series by ID, or lists series matching the name.
+func commandDBSeries(context *cli.Context) error {
+	query := strings.Join(context.Args().Slice(), " ")
+	p, err := commandPrinter(context, seriesRecord{Series: &localdb.Series{}})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if !isID(query) {
+		series, err := db.SearchSeries(query, context.Int("limit"))
+		if err != nil {
+			return err
+		}
+		records := make([]record, 0, len(series))
+		for _, item := range series {
+			records = append(records, seriesRecord{Series: item})
+		}
+		return p.list(seriesRecord{}.header(), records)
+	}
+	series, seriesBooks, err := db.Series(query)
+	if err != nil {
+		return fmt.Errorf("series %s: %w", query, err)
+	}
+	books := make([]*localdb.Book, 0, len(seriesBooks))
+	numbers := make([]string, 0, len(seriesBooks))
+	for _, book := range seriesBooks {
+		books = append(books, &book.Book)
+		numbers = append(numbers, book.Number)
+	}
+	return p.one(seriesRecord{Series: series, Books: dbBookItems(db, books, numbers)})
+}
+
+func commandDBStats(context *cli.Context) error {
+	p, err := commandPrinter(context, statsRecord{})
+	if err != nil {
+		return err
+	}
+	db, err := openLocalDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	stats, err := db.Stats()
+	if err != nil {
+		return err
+	}
+	records := make([]record, 0, len(stats))
+	for _, name := range sortedKeys(stats) {
+		records = append(records, statsRecord{name, stats[name]})
+	}
+	return p.list(statsRecord{}.header(), records)
+}
+
+// dbBookItems lists books in the same form as search results, numbers are numbers of books in series.
+func dbBookItems(db *localdb.DB, books []*localdb.Book, numbers []string) []dbBookItem {
+	items := make([]dbBookItem, 0, len(books))
+	for i, book := range books {
+		details, err := db.Book(book.ID)
+		if err != nil {
+			continue
+		}
+		item := dbBookItem{ListItem: client.ListItem{ID: book.ID, Title: book.Title, Authors: dbAuthorNames(details.Authors)}}
+		if numbers != nil {
+			item.Number = numbers[i]
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func isID(text string) bool {
+	_, err := strconv.ParseUint(text, 10, 64)
+	return err == nil
+}
+
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
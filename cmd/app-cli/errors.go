@@ -0,0 +1,110 @@
+package app_cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/localdb"
+	"github.com/urfave/cli/v2"
+)
+
+// Exit codes let scripts tell what went wrong.
+const (
+	exitFailure  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitNetwork  = 4
+	exitParse    = 5
+)
+
+// usageError is a mistake in arguments or flags.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+func asUsageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &usageError{err}
+}
+
+// errNotInLibrary is returned for books missing in local library
+var errNotInLibrary = errors.New("not in library")
+
+func onUsageError(context *cli.Context, err error, isSubcommand bool) error {
+	return asUsageError(err)
+}
+
+// markUsageErrors makes flag parsing errors of all commands usage errors.
+func markUsageErrors(commands []*cli.Command) {
+	for _, command := range commands {
+		if command.OnUsageError == nil {
+			command.OnUsageError = onUsageError
+		}
+		markUsageErrors(command.Subcommands)
+	}
+}
+
+func exitCode(err error) int {
+	var usage *usageError
+	var network *client.NetworkError
+	var netErr net.Error
+	var parse *client.ParseError
+	var drift *client.LayoutDriftError
+	// cli returns them for unknown commands
+	var exitCoder cli.ExitCoder
+	switch {
+	case errors.As(err, &usage), errors.As(err, &exitCoder):
+		return exitUsage
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, localdb.ErrNotFound), errors.Is(err, errNotInLibrary):
+		return exitNotFound
+	case errors.As(err, &network), errors.As(err, &netErr):
+		return exitNetwork
+	case errors.As(err, &parse), errors.As(err, &drift):
+		return exitParse
+	}
+	return exitFailure
+}
+
+// reportError prints the message, with debug also failures of every mirror and types of wrapped errors.
+func reportError(w io.Writer, err error, debug bool) {
+	fmt.Fprintln(w, "error:", err)
+	code := exitCode(err)
+	if code == exitUsage {
+		fmt.Fprintln(w, "see --help for usage")
+	}
+	if !debug {
+		if code == exitNetwork || code == exitParse {
+			fmt.Fprintln(w, "run with --debug for details")
+		}
+		return
+	}
+	var network *client.NetworkError
+	if errors.As(err, &network) {
+		fmt.Fprintln(w, "mirrors:")
+		fmt.Fprintln(w, network.Detail())
+	}
+	var chain []string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, fmt.Sprintf("%T", e))
+	}
+	fmt.Fprintln(w, "error types:", strings.Join(chain, " > "))
+	fmt.Fprintln(w, "exit code:", code)
+}
@@ -0,0 +1,114 @@
+package app_cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/localdb"
+	"github.com/urfave/cli/v2"
+)
+
+func Test_exitCode(t *testing.T) {
+	network := &client.NetworkError{Failures: []string{"flibusta.is: captcha page (status 200)"}}
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"Usage", usageErrorf("bookID is required parameter"), exitUsage},
+		{"Wrapped usage", fmt.Errorf("get: %w", asUsageError(errors.New("bad flag"))), exitUsage},
+		{"Unknown command", cli.Exit("No help topic for 'bogus'", 3), exitUsage},
+		{"Not found", fmt.Errorf("info: %w", client.ErrNotFound), exitNotFound},
+		{"Not found in local database", localdb.ErrNotFound, exitNotFound},
+		{"Not in library", fmt.Errorf("12345: %w", errNotInLibrary), exitNotFound},
+		{"Network", network, exitNetwork},
+		{"Connection", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, exitNetwork},
+		{"Parse", &client.ParseError{What: "book info"}, exitParse},
+		{"Layout drift", &client.LayoutDriftError{Field: "title", Selector: "//h1", Version: "2021.07"}, exitParse},
+		{"Unsupported", fmt.Errorf("author is %w html", client.ErrUnsupported), exitFailure},
+		{"Other", errors.New("disk is full"), exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_reportError(t *testing.T) {
+	network := fmt.Errorf("search: %w", &client.NetworkError{Failures: []string{"flibusta.is: captcha page (status 200)", "flibusta.site: timeout"}})
+	tests := []struct {
+		name    string
+		err     error
+		debug   bool
+		want    []string
+		notWant []string
+	}{
+		{
+			"Usage",
+			usageErrorf("bookID is required parameter"),
+			false,
+			[]string{"error: bookID is required parameter\n", "see --help for usage\n"},
+			[]string{"--debug"},
+		},
+		{
+			"Not found",
+			client.ErrNotFound,
+			false,
+			[]string{"error: nothing found\n"},
+			[]string{"--help", "--debug"},
+		},
+		{
+			"Network suggests debug",
+			network,
+			false,
+			[]string{"error: search: all request attempts failed", "run with --debug for details\n"},
+			[]string{"mirrors:"},
+		},
+		{
+			"Network with debug",
+			network,
+			true,
+			[]string{"mirrors:\nflibusta.is: captcha page (status 200)\nflibusta.site: timeout\n", "error types: *fmt.wrapError > *client.NetworkError\n", "exit code: 4\n"},
+			[]string{"run with --debug"},
+		},
+		{
+			"Parse suggests debug",
+			&client.ParseError{What: "book info"},
+			false,
+			[]string{"error: cannot parse book info\n", "run with --debug for details\n"},
+			nil,
+		},
+		{
+			"Other with debug",
+			errors.New("disk is full"),
+			true,
+			[]string{"error: disk is full\n", "error types: *errors.errorString\n", "exit code: 1\n"},
+			[]string{"mirrors:", "--help"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			reportError(buf, tt.err, tt.debug)
+			got := buf.String()
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("reportError() = %q, want %q in it", got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("reportError() = %q, want no %q in it", got, notWant)
+				}
+			}
+		})
+	}
+}
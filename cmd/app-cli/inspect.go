@@ -0,0 +1,98 @@
+package app_cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/fb2"
+	"github.com/urfave/cli/v2"
+)
+
+func commandInspect(context *cli.Context) error {
+	fileName := context.Args().First()
+	if fileName == "" {
+		return usageErrorf("file is required parameter")
+	}
+	p, err := commandPrinter(context, inspectRecord{})
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(fileName)
+	if err != nil {
+		return err
+	}
+	book, err := fb2.Open(fileName)
+	if err != nil {
+		return err
+	}
+
+	err = p.one(newInspectRecord(book, fileName, stat.Size()))
+	if err != nil {
+		return err
+	}
+
+	if coverFile := context.String("cover"); coverFile != "" {
+		if book.Cover == nil {
+			return errors.New("book has no cover")
+		}
+		err = ioutil.WriteFile(coverFile, book.Cover.Data, 0644)
+		if err != nil {
+			return err
+		}
+		p.notice("Cover saved at", coverFile)
+	}
+	return nil
+}
+
+func newInspectRecord(book *fb2.Book, fileName string, size int64) inspectRecord {
+	title := book.TitleInfo
+	doc := book.DocumentInfo
+	format := client.Fb2
+	if strings.HasSuffix(strings.ToLower(fileName), ".zip") {
+		format = client.Fb2Zip
+	}
+	r := inspectRecord{
+		File:        fileName,
+		Format:      format,
+		Size:        size,
+		Title:       title.Title,
+		Authors:     authorNames(title.Authors),
+		Translators: authorNames(title.Translators),
+		Genres:      title.Genres,
+		Date:        title.Date,
+		Lang:        title.Lang,
+		SrcLang:     title.SrcLang,
+		Annotation:  title.Annotation.String(),
+		Document: inspectDoc{
+			Authors:     authorNames(doc.Authors),
+			ProgramUsed: doc.ProgramUsed,
+			Date:        doc.Date,
+			ID:          doc.ID,
+			Version:     doc.Version,
+			SrcURLs:     doc.SrcURLs,
+		},
+	}
+	for _, sequence := range title.Sequences {
+		if sequence.Number != "" {
+			r.Sequences = append(r.Sequences, fmt.Sprintf("%s #%s", sequence.Name, sequence.Number))
+		} else {
+			r.Sequences = append(r.Sequences, sequence.Name)
+		}
+	}
+	if book.Cover != nil {
+		r.Cover = &inspectCover{Type: book.Cover.ContentType, Size: len(book.Cover.Data)}
+	}
+	return r
+}
+
+func authorNames(authors []fb2.Author) []string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		names = append(names, author.String())
+	}
+	return names
+}
@@ -0,0 +1,22 @@
+package app_cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/tui"
+	"github.com/urfave/cli/v2"
+)
+
+func commandInteractive(context *cli.Context) error {
+	source, err := newSession(context)
+	if err != nil {
+		return err
+	}
+	// Request log would break the screen
+	defer client.SetLogger(client.SetLogger(nil))
+
+	query := strings.Join(context.Args().Slice(), " ")
+	return tui.New(source, query, context.String("format"), os.Stdin, os.Stdout).Run()
+}
@@ -0,0 +1,174 @@
+package app_cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+	"github.com/urfave/cli/v2"
+)
+
+// openLibrary loads library of downloaded books, nil when it is disabled with --no-library.
+func openLibrary(context *cli.Context) (*library.Library, error) {
+	if context.Bool("no-library") {
+		return nil, nil
+	}
+	fileName, err := library.DefaultFile()
+	if err != nil {
+		return nil, err
+	}
+	return library.Load(fileName)
+}
+
+// ownedFormats are formats of saved files which satisfy request for the format
+func ownedFormats(bookFormat string) []string {
+	switch bookFormat {
+	case client.Fb2, client.Fb2Zip:
+		return []string{client.Fb2, client.Fb2Zip}
+	}
+	return []string{bookFormat}
+}
+
+func recordBook(books *library.Library, bookID string, info *client.InfoResult, bookFormat string, fileName string, data []byte) error {
+	bookInfo := client.InfoResult{ID: bookID}
+	if info != nil {
+		bookInfo = *info
+	}
+	entry, err := library.NewEntry(bookInfo, bookFormat, fileName, data)
+	if err != nil {
+		return err
+	}
+	books.Add(entry)
+	return books.Save()
+}
+
+func commandLibraryList(context *cli.Context) error {
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	p, err := commandPrinter(context, entryRecord{&library.Entry{}})
+	if err != nil {
+		return err
+	}
+	return p.entries(books.List())
+}
+
+func commandLibrarySearch(context *cli.Context) error {
+	query := strings.Join(context.Args().Slice(), " ")
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	p, err := commandPrinter(context, entryRecord{&library.Entry{}})
+	if err != nil {
+		return err
+	}
+	return p.entries(books.Search(query))
+}
+
+func commandLibraryShow(context *cli.Context) error {
+	bookID := context.Args().First()
+	if bookID == "" {
+		return usageErrorf("bookID is required parameter")
+	}
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	entries := books.Get(bookID)
+	if len(entries) == 0 {
+		return fmt.Errorf("book %s is %w", bookID, errNotInLibrary)
+	}
+	p, err := commandPrinter(context, entryRecord{&library.Entry{}})
+	if err != nil {
+		return err
+	}
+	if p.machine() || p.template != nil {
+		return p.entries(entries)
+	}
+	b := &strings.Builder{}
+	b.WriteString(entries[0].Info.String() + "\n")
+	for _, entry := range entries {
+		missing := ""
+		if !entry.Exists() {
+			missing = " (missing)"
+		}
+		fmt.Fprintf(b, "\t%s: %s%s\n", entry.Format, entry.Path, missing)
+		fmt.Fprintf(b, "\t\tsha256 %s, %d bytes, added %s\n", entry.Checksum, entry.Size, entry.Added.Format("2006-01-02 15:04"))
+	}
+	return p.text(strings.TrimSuffix(b.String(), "\n"))
+}
+
+func commandLibraryRemove(context *cli.Context) error {
+	bookID := context.Args().First()
+	if bookID == "" {
+		return usageErrorf("bookID is required parameter")
+	}
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	p, err := commandPrinter(context, nil)
+	if err != nil {
+		return err
+	}
+	removed := books.Remove(bookID, context.String("format"))
+	if len(removed) == 0 {
+		return fmt.Errorf("book %s is %w", bookID, errNotInLibrary)
+	}
+	for _, entry := range removed {
+		if context.Bool("delete-file") {
+			err = os.Remove(entry.Path)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			p.notice("File deleted:", entry.Path)
+		}
+		p.notice("Removed from library:", entry.String())
+	}
+	return books.Save()
+}
+
+// commandLibraryExportINPX writes index of books under collection root for MyHomeLib and similar software.
+func commandLibraryExportINPX(context *cli.Context) error {
+	books, err := openLibrary(context)
+	if err != nil {
+		return err
+	}
+	root, err := filepath.Abs(context.String("root"))
+	if err != nil {
+		return err
+	}
+	fileName := context.Args().First()
+	if fileName == "" {
+		fileName = filepath.Join(root, "library.inpx")
+	}
+	options := library.INPXOptions{
+		Name:        context.String("name"),
+		FileName:    strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName)),
+		Description: context.String("description"),
+		Root:        root,
+		Version:     time.Now(),
+	}
+	count := 0
+	err = fsutil.WriteAtomic(fileName, 0644, true, func(w io.Writer) error {
+		count, err = books.ExportINPX(w, options)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	p, err := commandPrinter(context, nil)
+	if err != nil {
+		return err
+	}
+	p.noticef("%d books exported to %s\n", count, fileName)
+	return nil
+}
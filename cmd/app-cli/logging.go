@@ -0,0 +1,31 @@
+package app_cli
+
+import (
+	"os"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// logger gets warnings of the commands, requests are logged by client with the same level.
+var logger client.Logger = client.NewTextLogger(os.Stderr, client.LevelInfo)
+
+// setupLogging chooses level by --verbose and --quiet.
+func setupLogging(context *cli.Context) error {
+	level := client.LevelInfo
+	switch {
+	case context.Bool("verbose") && context.Bool("quiet"):
+		return usageErrorf("use either --verbose or --quiet")
+	case context.Bool("verbose"):
+		level = client.LevelDebug
+	case context.Bool("quiet"):
+		level = client.LevelError
+	}
+	logger = client.NewTextLogger(os.Stderr, level)
+	client.SetLogger(logger)
+	return nil
+}
+
+func warn(message string, err error) {
+	logger.Log(client.LevelWarn, message, client.Field{Key: "error", Value: err})
+}
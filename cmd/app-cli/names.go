@@ -0,0 +1,70 @@
+package app_cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/filename"
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+	"github.com/urfave/cli/v2"
+)
+
+func parseNameTemplate(context *cli.Context) (*template.Template, error) {
+	text := context.String("name-template")
+	if text == "" {
+		return nil, nil
+	}
+	return filename.Parse(text)
+}
+
+// bookFileName returns path in output directory, with --keep-both it is numbered
+// so existing file is not touched. Without template the name suggested by the site is used.
+func bookFileName(context *cli.Context, tpl *template.Template, info *client.InfoResult, result *client.DownloadResult, bookFormat string) (string, error) {
+	dir := context.String("output-dir")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	translit := context.Bool("translit")
+	extension := filename.Extension(result.Name, bookFormat)
+	base := filename.Sanitize(filename.TrimExtension(filepath.Base(result.Name), extension), translit)
+	if tpl != nil {
+		base, err = filename.Render(tpl, nameFields(info, bookFormat), translit)
+		if err != nil {
+			return "", err
+		}
+	}
+	if context.Bool("keep-both") {
+		return filename.Unique(dir, base, extension), nil
+	}
+	return filepath.Join(dir, base+extension), nil
+}
+
+// saveBook writes file atomically, existing file is overwritten only with --force.
+func saveBook(context *cli.Context, fileName string, data []byte) error {
+	err := fsutil.WriteFileAtomic(fileName, data, 0644, context.Bool("force"))
+	if errors.Is(err, fsutil.ErrExists) {
+		return fmt.Errorf("%w, use --force to overwrite or --keep-both to save under another name", err)
+	}
+	return err
+}
+
+func nameFields(info *client.InfoResult, bookFormat string) filename.Fields {
+	fields := filename.Fields{
+		ID:           info.ID,
+		Title:        client.TitleWithoutFormat(info.Title),
+		Authors:      info.Authors,
+		Series:       info.Series,
+		SeriesNumber: info.SeriesNumber,
+		Year:         info.Year,
+		Format:       bookFormat,
+	}
+	if len(info.Authors) > 0 {
+		fields.Author = info.Authors[0]
+	}
+	return fields
+}
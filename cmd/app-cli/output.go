@@ -0,0 +1,677 @@
+package app_cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+	"github.com/slivtime/flibusta-cli/pkg/localdb"
+	"github.com/slivtime/flibusta-cli/pkg/report"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+var outputFormats = []string{outputText, outputJSON, outputJSONL, outputCSV, outputTSV}
+
+// record is a result printed by commands. Machine formats use value for JSON
+// and header with row for CSV and TSV, these are stable between releases.
+type record interface {
+	text() string
+	value() interface{}
+	header() []string
+	row() []string
+}
+
+// printer writes results to stdout in chosen format. Informational messages go to
+// stdout only in text mode, machine readable output is kept clean.
+type printer struct {
+	format string
+	// Template replaces text of results in text mode
+	template *report.Template
+	out      io.Writer
+	info     io.Writer
+}
+
+func newPrinter(context *cli.Context) (*printer, error) {
+	format := context.String("output")
+	if format == "" {
+		format = outputText
+	}
+	known := false
+	for _, name := range outputFormats {
+		known = known || format == name
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown output format `%s`, use one of %s", format, strings.Join(outputFormats, ", "))
+	}
+	p := &printer{format: format, out: os.Stdout, info: os.Stdout}
+	if format != outputText {
+		p.info = os.Stderr
+	}
+	tpl, err := parseOutputTemplate(context)
+	if err != nil {
+		return nil, err
+	}
+	if tpl != nil && format != outputText {
+		return nil, fmt.Errorf("--template is used with text output, not %s", format)
+	}
+	p.template = tpl
+	return p, nil
+}
+
+func parseOutputTemplate(context *cli.Context) (*report.Template, error) {
+	text, fileName := context.String("template"), context.String("template-file")
+	switch {
+	case text != "" && fileName != "":
+		return nil, errors.New("use either --template or --template-file")
+	case text != "":
+		return report.Parse(text)
+	case fileName != "":
+		return report.ParseFile(fileName)
+	}
+	return nil, nil
+}
+
+// commandPrinter is newPrinter for commands, flags are validated before any request.
+// Template is checked against sample of results the command prints, nil for commands without results.
+func commandPrinter(context *cli.Context, sample record) (*printer, error) {
+	p, err := newPrinter(context)
+	if err == nil && p.template != nil && sample != nil {
+		err = p.template.Check(sample.value())
+	}
+	if err != nil {
+		return nil, asUsageError(err)
+	}
+	return p, nil
+}
+
+func (p *printer) machine() bool {
+	return p.format != outputText
+}
+
+// notice prints informational message, like "File saved at".
+func (p *printer) notice(args ...interface{}) {
+	fmt.Fprintln(p.info, args...)
+}
+
+func (p *printer) noticef(format string, args ...interface{}) {
+	fmt.Fprintf(p.info, format, args...)
+}
+
+// list prints records, JSON output is an array and CSV has header even when the list is empty.
+func (p *printer) list(header []string, records []record) error {
+	switch p.format {
+	case outputJSON:
+		values := make([]interface{}, 0, len(records))
+		for _, r := range records {
+			values = append(values, r.value())
+		}
+		return p.writeJSON(values)
+	case outputCSV, outputTSV:
+		return p.writeTable(header, records)
+	}
+	for _, r := range records {
+		if err := p.one(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// one prints single record, e.g. book info.
+func (p *printer) one(r record) error {
+	switch p.format {
+	case outputJSON:
+		return p.writeJSON(r.value())
+	case outputJSONL:
+		data, err := json.Marshal(r.value())
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.out, string(data))
+		return err
+	case outputCSV, outputTSV:
+		return p.writeTable(r.header(), []record{r})
+	}
+	if p.template != nil {
+		return p.template.Execute(p.out, r.value())
+	}
+	_, err := fmt.Fprintln(p.out, r.text())
+	return err
+}
+
+func (p *printer) writeJSON(value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(p.out, string(data))
+	return err
+}
+
+func (p *printer) writeTable(header []string, records []record) error {
+	w := csv.NewWriter(p.out)
+	if p.format == outputTSV {
+		w.Comma = '\t'
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(r.row()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (p *printer) books(items []client.ListItem) error {
+	records := make([]record, 0, len(items))
+	for _, item := range items {
+		records = append(records, bookRecord(item))
+	}
+	return p.list(bookRecord{}.header(), records)
+}
+
+func (p *printer) entries(entries []*library.Entry) error {
+	records := make([]record, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, entryRecord{entry})
+	}
+	return p.list(entryRecord{}.header(), records)
+}
+
+// bookRecord is a book of search results and listings.
+type bookRecord client.ListItem
+
+func (r bookRecord) text() string {
+	item := client.ListItem(r)
+	if len(item.Links) == 0 {
+		return item.String()
+	}
+	return fmt.Sprintf("%s [%s]", item.String(), strings.Join(r.formats(), ", "))
+}
+
+func (r bookRecord) value() interface{} {
+	return client.ListItem(r)
+}
+
+func (r bookRecord) header() []string {
+	return []string{"id", "title", "authors", "formats"}
+}
+
+func (r bookRecord) row() []string {
+	return []string{r.ID, r.Title, strings.Join(r.Authors, "; "), strings.Join(r.formats(), ",")}
+}
+
+func (r bookRecord) formats() []string {
+	formats := make([]string, 0, len(r.Links))
+	for _, link := range r.Links {
+		formats = append(formats, link.Format)
+	}
+	return formats
+}
+
+type infoRecord struct {
+	*client.InfoResult
+}
+
+func (r infoRecord) text() string {
+	return r.InfoResult.String()
+}
+
+func (r infoRecord) value() interface{} {
+	return r.InfoResult
+}
+
+func (r infoRecord) header() []string {
+	return []string{"id", "title", "authors", "genre", "series", "series_number", "year", "size", "formats", "annotation"}
+}
+
+func (r infoRecord) row() []string {
+	return []string{
+		r.ID, r.Title, strings.Join(r.Authors, "; "), r.Genre, r.Series, r.SeriesNumber,
+		r.Year, r.Size, strings.Join(r.Formats, ","), r.Annotation,
+	}
+}
+
+const (
+	downloadSaved = "saved"
+	downloadOwned = "owned"
+)
+
+// downloadReport describes book saved by `get`.
+type downloadReport struct {
+	ID     string `json:"id"`
+	Format string `json:"format"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	// saved or owned when the book was already in library
+	Status string `json:"status"`
+}
+
+func (r downloadReport) text() string {
+	if r.Status == downloadOwned {
+		return "Book is already in library: " + r.Path
+	}
+	return "File saved at " + r.Path
+}
+
+func (r downloadReport) value() interface{} {
+	return r
+}
+
+func (r downloadReport) header() []string {
+	return []string{"id", "format", "path", "size", "status"}
+}
+
+func (r downloadReport) row() []string {
+	return []string{r.ID, r.Format, r.Path, strconv.FormatInt(r.Size, 10), r.Status}
+}
+
+type entryRecord struct {
+	*library.Entry
+}
+
+func (r entryRecord) text() string {
+	return r.Entry.String()
+}
+
+func (r entryRecord) value() interface{} {
+	return r.Entry
+}
+
+func (r entryRecord) header() []string {
+	return []string{"id", "format", "title", "authors", "path", "size", "sha256", "added"}
+}
+
+func (r entryRecord) row() []string {
+	return []string{
+		r.ID, r.Format, r.Info.Title, strings.Join(r.Info.Authors, "; "), r.Path,
+		strconv.FormatInt(r.Size, 10), r.Checksum, r.Added.Format(time.RFC3339),
+	}
+}
+
+// text prints output which has no machine form, like details of a library book in text mode.
+func (p *printer) text(text string) error {
+	_, err := fmt.Fprintln(p.out, text)
+	return err
+}
+
+// writeField adds `Name: value` line of details, empty values are skipped.
+func writeField(b *strings.Builder, name string, value string) {
+	if value == "" {
+		return
+	}
+	fmt.Fprintf(b, "\t%s: %s\n", name, value)
+}
+
+const (
+	importImported = "imported"
+	importSkipped  = "skipped"
+)
+
+// importRecord counts rows of a table imported from dump, rows of unknown tables are skipped.
+type importRecord struct {
+	File  string `json:"file"`
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+	// imported or skipped
+	Status string `json:"status"`
+}
+
+func importRecords(fileName string, stats *localdb.ImportStats) []record {
+	var records []record
+	for _, table := range sortedKeys(stats.Rows) {
+		records = append(records, importRecord{fileName, table, stats.Rows[table], importImported})
+	}
+	for _, table := range sortedKeys(stats.Skipped) {
+		records = append(records, importRecord{fileName, table, stats.Skipped[table], importSkipped})
+	}
+	return records
+}
+
+func (r importRecord) text() string {
+	return fmt.Sprintf("%s: %d rows of %s %s", r.File, r.Rows, r.Table, r.Status)
+}
+
+func (r importRecord) value() interface{} {
+	return r
+}
+
+func (r importRecord) header() []string {
+	return []string{"file", "table", "rows", "status"}
+}
+
+func (r importRecord) row() []string {
+	return []string{r.File, r.Table, strconv.Itoa(r.Rows), r.Status}
+}
+
+// dbBookRecord is a book of local database with all its relations.
+type dbBookRecord struct {
+	*localdb.BookDetails
+}
+
+func (r dbBookRecord) text() string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "%s: %s\n", r.ID, r.Title)
+	writeField(b, "Authors", strings.Join(dbAuthorNames(r.Authors), ", "))
+	writeField(b, "Translators", strings.Join(dbAuthorNames(r.Translators), ", "))
+	for _, series := range r.dbSeries() {
+		writeField(b, "Series", series)
+	}
+	writeField(b, "Genres", strings.Join(r.genres(), ", "))
+	writeField(b, "Language", r.Lang)
+	writeField(b, "Year", r.Year)
+	writeField(b, "File", fmt.Sprintf("%s, %d bytes", r.FileType, r.Size))
+	writeField(b, "Added", r.Added)
+	if r.Deleted {
+		writeField(b, "Deleted", "yes")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (r dbBookRecord) value() interface{} {
+	return r.BookDetails
+}
+
+func (r dbBookRecord) header() []string {
+	return []string{"id", "title", "authors", "translators", "series", "genres", "lang", "year", "file_type", "size", "added", "deleted"}
+}
+
+func (r dbBookRecord) row() []string {
+	return []string{
+		r.ID, r.Title, strings.Join(dbAuthorNames(r.Authors), "; "), strings.Join(dbAuthorNames(r.Translators), "; "),
+		strings.Join(r.dbSeries(), "; "), strings.Join(r.genres(), ","), r.Lang, r.Year, r.FileType,
+		strconv.FormatInt(r.Size, 10), r.Added, strconv.FormatBool(r.Deleted),
+	}
+}
+
+// dbSeries are series like `Остап Бендер 1 (7)`
+func (r dbBookRecord) dbSeries() []string {
+	series := make([]string, 0, len(r.Series))
+	for _, item := range r.Series {
+		series = append(series, fmt.Sprintf("%s %s (%s)", item.Name, item.Number, item.ID))
+	}
+	return series
+}
+
+func (r dbBookRecord) genres() []string {
+	genres := make([]string, 0, len(r.Genres))
+	for _, genre := range r.Genres {
+		genres = append(genres, genre.Code)
+	}
+	return genres
+}
+
+func dbAuthorNames(authors []*localdb.Author) []string {
+	names := make([]string, 0, len(authors))
+	for _, author := range authors {
+		names = append(names, author.String())
+	}
+	return names
+}
+
+// dbBookItem is a book of local database listings, number is set for books of a series.
+type dbBookItem struct {
+	client.ListItem
+	Number string `json:"number,omitempty"`
+}
+
+func (item dbBookItem) String() string {
+	if item.Number == "" {
+		return item.ListItem.String()
+	}
+	return item.Number + ". " + item.ListItem.String()
+}
+
+func dbBookIDs(books []dbBookItem) string {
+	ids := make([]string, 0, len(books))
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+// authorRecord is an author of local database, books are listed when author is shown by ID.
+type authorRecord struct {
+	*localdb.Author
+	Name  string       `json:"name"`
+	Books []dbBookItem `json:"books,omitempty"`
+}
+
+func (r authorRecord) text() string {
+	lines := []string{r.ID + ": " + r.Name}
+	for _, book := range r.Books {
+		lines = append(lines, "\t"+book.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r authorRecord) value() interface{} {
+	return r
+}
+
+func (r authorRecord) header() []string {
+	return []string{"id", "name", "books"}
+}
+
+func (r authorRecord) row() []string {
+	return []string{r.ID, r.Name, dbBookIDs(r.Books)}
+}
+
+// seriesRecord is a series of local database, books are listed when series is shown by ID.
+type seriesRecord struct {
+	*localdb.Series
+	Books []dbBookItem `json:"books,omitempty"`
+}
+
+func (r seriesRecord) text() string {
+	lines := []string{r.ID + ": " + r.Name}
+	for _, book := range r.Books {
+		lines = append(lines, "\t"+book.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r seriesRecord) value() interface{} {
+	return r
+}
+
+func (r seriesRecord) header() []string {
+	return []string{"id", "name", "books"}
+}
+
+func (r seriesRecord) row() []string {
+	return []string{r.ID, r.Name, dbBookIDs(r.Books)}
+}
+
+// statsRecord is number of records in a bucket of local database.
+type statsRecord struct {
+	Bucket  string `json:"bucket"`
+	Records int    `json:"records"`
+}
+
+func (r statsRecord) text() string {
+	return fmt.Sprintf("%s: %d", r.Bucket, r.Records)
+}
+
+func (r statsRecord) value() interface{} {
+	return r
+}
+
+func (r statsRecord) header() []string {
+	return []string{"bucket", "records"}
+}
+
+func (r statsRecord) row() []string {
+	return []string{r.Bucket, strconv.Itoa(r.Records)}
+}
+
+// inspectRecord is metadata of FictionBook file.
+type inspectRecord struct {
+	File        string        `json:"file"`
+	Format      string        `json:"format"`
+	Size        int64         `json:"size"`
+	Title       string        `json:"title"`
+	Authors     []string      `json:"authors"`
+	Translators []string      `json:"translators,omitempty"`
+	Genres      []string      `json:"genres,omitempty"`
+	Sequences   []string      `json:"sequences,omitempty"`
+	Date        string        `json:"date,omitempty"`
+	Lang        string        `json:"lang,omitempty"`
+	SrcLang     string        `json:"src_lang,omitempty"`
+	Annotation  string        `json:"annotation,omitempty"`
+	Cover       *inspectCover `json:"cover,omitempty"`
+	Document    inspectDoc    `json:"document"`
+}
+
+type inspectCover struct {
+	Type string `json:"type"`
+	Size int    `json:"size"`
+}
+
+type inspectDoc struct {
+	Authors     []string `json:"authors,omitempty"`
+	ProgramUsed string   `json:"program_used,omitempty"`
+	Date        string   `json:"date,omitempty"`
+	ID          string   `json:"id,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	SrcURLs     []string `json:"src_urls,omitempty"`
+}
+
+// text starts with the fields shared with book page info, so they are rendered the same way.
+func (r inspectRecord) text() string {
+	info := &client.InfoResult{
+		Title:      r.Title,
+		Genre:      strings.Join(r.Genres, ", "),
+		Annotation: r.Annotation,
+		Size:       fmt.Sprintf("%dK", r.Size/1024),
+		Formats:    []string{r.Format},
+	}
+	b := &strings.Builder{}
+	b.WriteString(info.String() + "\n")
+	writeField(b, "Authors", strings.Join(r.Authors, ", "))
+	writeField(b, "Translators", strings.Join(r.Translators, ", "))
+	writeField(b, "Genres", strings.Join(r.Genres, ", "))
+	for _, sequence := range r.Sequences {
+		writeField(b, "Sequence", sequence)
+	}
+	writeField(b, "Date", r.Date)
+	writeField(b, "Language", r.Lang)
+	writeField(b, "Source language", r.SrcLang)
+	if r.Cover != nil {
+		writeField(b, "Cover", fmt.Sprintf("%s, %d bytes", r.Cover.Type, r.Cover.Size))
+	}
+	b.WriteString("\n")
+	writeField(b, "Document authors", strings.Join(r.Document.Authors, ", "))
+	writeField(b, "Program used", r.Document.ProgramUsed)
+	writeField(b, "Document date", r.Document.Date)
+	writeField(b, "Document ID", r.Document.ID)
+	writeField(b, "Document version", r.Document.Version)
+	writeField(b, "Source URL", strings.Join(r.Document.SrcURLs, ", "))
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func (r inspectRecord) value() interface{} {
+	return r
+}
+
+func (r inspectRecord) header() []string {
+	return []string{"file", "format", "size", "title", "authors", "translators", "genres", "sequences", "date", "lang", "src_lang", "document_id"}
+}
+
+func (r inspectRecord) row() []string {
+	return []string{
+		r.File, r.Format, strconv.FormatInt(r.Size, 10), r.Title, strings.Join(r.Authors, "; "),
+		strings.Join(r.Translators, "; "), strings.Join(r.Genres, ","), strings.Join(r.Sequences, "; "),
+		r.Date, r.Lang, r.SrcLang, r.Document.ID,
+	}
+}
+
+const (
+	archiveApplied = "applied"
+	archivePending = "pending"
+)
+
+// archiveRecord is a daily archive of the mirror.
+type archiveRecord struct {
+	Name string `json:"name"`
+	// applied or pending
+	Status  string     `json:"status"`
+	Applied *time.Time `json:"applied,omitempty"`
+	Files   int        `json:"files,omitempty"`
+}
+
+func (r archiveRecord) text() string {
+	if r.Applied == nil {
+		return r.Name + ": " + r.Status
+	}
+	return fmt.Sprintf("%s: %s %s, %d files", r.Name, r.Status, r.Applied.Format("2006-01-02 15:04"), r.Files)
+}
+
+func (r archiveRecord) value() interface{} {
+	return r
+}
+
+func (r archiveRecord) header() []string {
+	return []string{"name", "status", "applied", "files"}
+}
+
+func (r archiveRecord) row() []string {
+	applied := ""
+	if r.Applied != nil {
+		applied = r.Applied.Format(time.RFC3339)
+	}
+	return []string{r.Name, r.Status, applied, strconv.Itoa(r.Files)}
+}
+
+// checkRecord is a result of parser self test.
+type checkRecord struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+func newCheckRecord(result *client.SelfTestResult) checkRecord {
+	r := checkRecord{Name: result.Name, OK: result.Error == nil}
+	if result.Error != nil {
+		r.Error = result.Error.Error()
+	}
+	return r
+}
+
+func (r checkRecord) text() string {
+	if !r.OK {
+		return fmt.Sprintf("FAIL %s: %s", r.Name, r.Error)
+	}
+	return "ok   " + r.Name
+}
+
+func (r checkRecord) value() interface{} {
+	return r
+}
+
+func (r checkRecord) header() []string {
+	return []string{"name", "ok", "error"}
+}
+
+func (r checkRecord) row() []string {
+	return []string{r.Name, strconv.FormatBool(r.OK), r.Error}
+}
@@ -0,0 +1,127 @@
+package app_cli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+	"github.com/slivtime/flibusta-cli/pkg/localdb"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files of printer output")
+
+var added = time.Date(2021, 6, 1, 10, 30, 0, 0, time.UTC)
+
+var testInfo = &client.InfoResult{
+	ID:           "325729",
+	Title:        "Нежить",
+	Authors:      []string{"Джон Джозеф Адамс", "Стивен Кинг"},
+	Genre:        "Ужасы",
+	Series:       "Антология",
+	SeriesNumber: "1",
+	Year:         "2012",
+	Annotation:   "Лучшие рассказы о нежити",
+	Size:         "2263K",
+	Formats:      []string{client.Fb2, client.Epub},
+}
+
+var testDBBook = &localdb.BookDetails{
+	Book:    localdb.Book{ID: "12345", Title: "Двенадцать стульев", Lang: "ru", FileType: "fb2", Year: "1928", Size: 1000, Added: "2009-01-01"},
+	Authors: []*localdb.Author{{ID: "2", FirstName: "Илья", LastName: "Ильф"}, {ID: "3", FirstName: "Евгений", LastName: "Петров"}},
+	Series:  []*localdb.BookSeries{{Series: localdb.Series{ID: "7", Name: "Остап Бендер"}, Number: "1"}},
+	Genres:  []*localdb.Genre{{ID: "1", Code: "prose_classic"}, {ID: "2", Code: "humor_prose"}},
+}
+
+// outputCases are results of every record type, one is printed as single result
+var outputCases = []struct {
+	name    string
+	one     bool
+	header  []string
+	records []record
+}{
+	{"books", false, bookRecord{}.header(), []record{
+		bookRecord{ID: "325729", Title: "Нежить", Authors: []string{"Джон Джозеф Адамс", "Стивен Кинг"}},
+		bookRecord{ID: "400001", Title: "Сборник, \"избранное\"", Links: []client.Link{{Format: "pdf", Type: "application/pdf", Href: "/b/400001/download"}}},
+	}},
+	{"info", true, nil, []record{infoRecord{testInfo}}},
+	{"download", true, nil, []record{downloadReport{ID: "325729", Format: client.Epub, Path: "Books/Нежить.epub", Size: 2317312, Status: downloadSaved}}},
+	{"entries", false, entryRecord{}.header(), []record{
+		entryRecord{&library.Entry{ID: "325729", Format: client.Epub, Path: "Books/Нежить.epub", Checksum: "e3b0c442", Size: 2317312, Info: *testInfo, Added: added}},
+	}},
+	{"import", false, importRecord{}.header(), importRecords("lib.sql", &localdb.ImportStats{
+		Rows:    map[string]int{"libbook": 4, "libavtor": 6},
+		Skipped: map[string]int{"librate": 2},
+	})},
+	{"db_book", true, nil, []record{dbBookRecord{testDBBook}}},
+	{"db_author", true, nil, []record{authorRecord{Author: testDBBook.Authors[0], Name: "Илья Ильф", Books: []dbBookItem{
+		{ListItem: client.ListItem{ID: "12345", Title: "Двенадцать стульев", Authors: []string{"Илья Ильф", "Евгений Петров"}}},
+	}}}},
+	{"db_series", false, seriesRecord{}.header(), []record{
+		seriesRecord{Series: &localdb.Series{ID: "7", Name: "Остап Бендер"}, Books: []dbBookItem{
+			{ListItem: client.ListItem{ID: "12345", Title: "Двенадцать стульев"}, Number: "1"},
+			{ListItem: client.ListItem{ID: "12346", Title: "Золотой телёнок"}, Number: "2"},
+		}},
+		seriesRecord{Series: &localdb.Series{ID: "8", Name: "Без книг"}},
+	}},
+	{"db_stats", false, statsRecord{}.header(), []record{statsRecord{"authors", 4}, statsRecord{"books", 4}}},
+	{"inspect", true, nil, []record{inspectRecord{
+		File:      "book.fb2",
+		Format:    client.Fb2,
+		Size:      4096,
+		Title:     "Нежить",
+		Authors:   []string{"Джон Джозеф Адамс"},
+		Genres:    []string{"sf_horror"},
+		Sequences: []string{"Антология #1"},
+		Lang:      "ru",
+		Cover:     &inspectCover{Type: "image/jpeg", Size: 100},
+		Document:  inspectDoc{ProgramUsed: "FictionBook Editor", ID: "doc-1"},
+	}}},
+	{"archives", false, archiveRecord{}.header(), []record{
+		archiveRecord{Name: "f.fb2.590001-590290.zip", Status: archiveApplied, Applied: &added, Files: 290},
+		archiveRecord{Name: "f.fb2.590291-590511.zip", Status: archivePending},
+	}},
+	{"selftest", false, checkRecord{}.header(), []record{
+		checkRecord{Name: "bundled search page", OK: true},
+		checkRecord{Name: "live search page", Error: "nothing found"},
+	}},
+}
+
+func TestPrinter_golden(t *testing.T) {
+	for _, tt := range outputCases {
+		for _, format := range outputFormats {
+			t.Run(tt.name+"."+format, func(t *testing.T) {
+				buf := &bytes.Buffer{}
+				p := &printer{format: format, out: buf, info: io.Discard}
+				var err error
+				if tt.one {
+					err = p.one(tt.records[0])
+				} else {
+					err = p.list(tt.header, tt.records)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				fileName := path.Join("testdata", "output", tt.name+"."+format)
+				if *update {
+					if err = os.WriteFile(fileName, buf.Bytes(), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := buf.String(); got != string(want) {
+					t.Errorf("output differs from %s:\n%s\nwant:\n%s", fileName, got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package app_cli
+
+import (
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+	"github.com/slivtime/flibusta-cli/pkg/server"
+	"github.com/urfave/cli/v2"
+)
+
+func commandServe(context *cli.Context) error {
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return err
+	}
+	backend, err := flibusta.Backend()
+	if err != nil {
+		return err
+	}
+	libraryFile := ""
+	if !context.Bool("no-library") {
+		libraryFile, err = library.DefaultFile()
+		if err != nil {
+			return err
+		}
+	}
+	flibustaServer := server.New(backend, flibusta, libraryFile)
+	flibustaServer.Logger = logger
+	flibustaServer.API = context.Bool("api")
+	flibustaServer.Web = context.Bool("web")
+	flibustaServer.SetCacheTTL(context.Duration("cache-ttl"))
+	return flibustaServer.ListenAndServe(context.String("listen"))
+}
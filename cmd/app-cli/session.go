@@ -0,0 +1,108 @@
+package app_cli
+
+import (
+	"sync"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/urfave/cli/v2"
+)
+
+// session runs requests of `interactive` and `shell` with one client, books are saved like `get` does.
+type session struct {
+	flibusta *client.FlibustaClient
+	backend  client.Backend
+	getter   *bookGetter
+
+	mu    sync.Mutex
+	infos map[string]*client.InfoResult
+}
+
+// newSession checks name template and opens library before any request.
+func newSession(context *cli.Context) (*session, error) {
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	backend, err := flibusta.Backend()
+	if err != nil {
+		return nil, err
+	}
+	books, err := openLibrary(context)
+	if err != nil {
+		return nil, err
+	}
+	getter, err := newBookGetter(context, flibusta, books)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{
+		flibusta: flibusta,
+		backend:  backend,
+		getter:   getter,
+		infos:    map[string]*client.InfoResult{},
+	}
+	getter.info = s.bookInfo
+	return s, nil
+}
+
+func (s *session) Supports(listing string) bool {
+	return s.backend.Supports(listing)
+}
+
+func (s *session) Search(query string) ([]client.ListItem, error) {
+	items, err := s.backend.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	return *items, nil
+}
+
+func (s *session) Author(id string) (*client.AuthorResult, error) {
+	return s.backend.Author(id)
+}
+
+func (s *session) Series(id string) (*client.SeriesResult, error) {
+	return s.backend.Series(id)
+}
+
+func (s *session) NewArrivals() ([]client.ListItem, error) {
+	items, err := s.backend.NewArrivals()
+	if err != nil {
+		return nil, err
+	}
+	return *items, nil
+}
+
+func (s *session) Info(id string) (*client.InfoResult, error) {
+	info, err := s.flibusta.Info(id, client.ParseInfo)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.infos[id] = info
+	s.mu.Unlock()
+	return info, nil
+}
+
+// Download saves the book like `get`, failures which did not stop saving are returned as warnings.
+func (s *session) Download(id string, format string) (*client.SavedBook, error) {
+	var warnings []string
+	report, err := s.getter.get(id, format, func(message string, err error) {
+		warnings = append(warnings, message+": "+err.Error())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &client.SavedBook{Path: report.Path, Owned: report.Status == downloadOwned, Warnings: warnings}, nil
+}
+
+// bookInfo returns info shown earlier, otherwise it is requested.
+func (s *session) bookInfo(id string) (*client.InfoResult, error) {
+	s.mu.Lock()
+	info := s.infos[id]
+	s.mu.Unlock()
+	if info != nil {
+		return info, nil
+	}
+	return s.Info(id)
+}
@@ -0,0 +1,32 @@
+package app_cli
+
+import (
+	"os"
+
+	"github.com/slivtime/flibusta-cli/pkg/shell"
+	"github.com/urfave/cli/v2"
+)
+
+func commandShell(context *cli.Context) error {
+	books, err := newSession(context)
+	if err != nil {
+		return err
+	}
+	historyFile := context.String("history-file")
+	if historyFile == "" {
+		historyFile, err = shell.DefaultHistoryFile()
+		if err != nil {
+			return err
+		}
+	}
+	editor := shell.NewEditor(os.Stdin, os.Stdout)
+	editor.History, err = shell.LoadHistory(historyFile)
+	if err != nil {
+		warn("history is not loaded", err)
+	}
+	err = shell.New(books, context.String("format"), os.Stdout).Run(editor)
+	if saveErr := shell.SaveHistory(historyFile, editor.History); saveErr != nil {
+		warn("history is not saved", saveErr)
+	}
+	return err
+}
@@ -0,0 +1,95 @@
+package app_cli
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/mirror"
+	"github.com/urfave/cli/v2"
+)
+
+// commandSync applies daily archives not applied yet, in order they are listed on the site.
+func commandSync(context *cli.Context) error {
+	dir := context.String("dir")
+	if dir == "" {
+		return usageErrorf("mirror directory is required, use --dir or %s", mirror.DirEnvKey)
+	}
+	var sample record
+	if context.Bool("list") {
+		sample = archiveRecord{}
+	}
+	p, err := commandPrinter(context, sample)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	flibusta, err := client.FromEnv()
+	if err != nil {
+		return err
+	}
+	books, err := mirror.Open(dir, flibusta)
+	if err != nil {
+		return err
+	}
+	books.KeepArchives = context.Bool("keep-archives")
+
+	pending, err := books.Pending()
+	if err != nil {
+		return err
+	}
+	pending, err = matchArchives(pending, context.String("match"))
+	if err != nil {
+		return err
+	}
+	if limit := context.Int("limit"); limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+
+	if context.Bool("list") {
+		var records []record
+		for _, name := range books.AppliedNames() {
+			applied := books.Applied[name]
+			records = append(records, archiveRecord{Name: name, Status: archiveApplied, Applied: &applied.Time, Files: applied.Books})
+		}
+		for _, name := range pending {
+			records = append(records, archiveRecord{Name: name, Status: archivePending})
+		}
+		return p.list(archiveRecord{}.header(), records)
+	}
+
+	if len(pending) == 0 {
+		p.notice("Mirror is up to date")
+		return nil
+	}
+	for i, name := range pending {
+		p.noticef("[%d/%d] %s\n", i+1, len(pending), name)
+		count, err := books.Apply(name)
+		if err != nil {
+			return err
+		}
+		p.noticef("%d files extracted\n", count)
+	}
+	return nil
+}
+
+func matchArchives(names []string, pattern string) ([]string, error) {
+	if pattern == "" {
+		return names, nil
+	}
+	var matched []string
+	for _, name := range names {
+		ok, err := path.Match(pattern, name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --match pattern: %w", err)
+		}
+		if ok {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
@@ -5,5 +5,7 @@ go 1.16
 require (
 	github.com/antchfx/htmlquery v1.2.3
 	github.com/urfave/cli/v2 v2.3.0
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/net v0.0.0-20210614182718-04defd469f4e
+	golang.org/x/sys v0.0.0-20210423082822-04245dca01da
 )
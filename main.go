@@ -1,14 +1,12 @@
 package main
 
 import (
+	"os"
+
 	"github.com/slivtime/flibusta-cli/cmd/app-cli"
-	"log"
 )
 
 func main() {
 	appCli := app_cli.FlibustaCLI{}
-	err := appCli.Start()
-	if err != nil {
-		log.Fatal(err)
-	}
+	os.Exit(appCli.Run())
 }
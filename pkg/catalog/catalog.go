@@ -0,0 +1,208 @@
+// Package catalog imports the list of all books published by the site,
+// so books can be searched when no mirror is reachable.
+package catalog
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+)
+
+const (
+	appCacheDir = "flibusta-cli"
+	FileEnvKey  = "FLIBUSTA_CATALOG_FILE"
+	separator   = ";"
+	utf8BOM     = "\ufeff"
+)
+
+var ErrNoCatalog = errors.New("no catalog file in archive")
+
+// Columns of catalog.txt, header line tells their order
+var columns = []string{"Last Name", "First Name", "Middle Name", "Title", "Subtitle", "Language", "Year", "Series", "ID"}
+
+type Book struct {
+	ID      string
+	Title   string
+	Authors []string
+	Lang    string
+	Year    string
+	Series  string
+}
+
+// Catalog is the imported list of books.
+type Catalog struct {
+	Books    []*Book
+	Imported time.Time
+}
+
+// DefaultFile is located in user cache directory, catalog can be imported again anytime.
+func DefaultFile() (string, error) {
+	if fileName := os.Getenv(FileEnvKey); fileName != "" {
+		return fileName, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appCacheDir, "catalog.gob"), nil
+}
+
+// ParseZip reads catalog.zip published by the site.
+func ParseZip(data []byte) (*Catalog, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open catalog archive: %w", err)
+	}
+	for _, entry := range archive.File {
+		if !strings.EqualFold(path.Ext(entry.Name), ".txt") {
+			continue
+		}
+		content, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer content.Close()
+		return Parse(content)
+	}
+	return nil, ErrNoCatalog
+}
+
+// Parse reads catalog.txt: semicolon separated lines with a header.
+// Book with several authors takes several lines with the same ID.
+func Parse(stream io.Reader) (*Catalog, error) {
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("catalog is empty")
+	}
+	index, err := parseHeader(scanner.Text())
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{Imported: time.Now()}
+	byID := map[string]*Book{}
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), separator)
+		if len(fields) < len(columns) {
+			continue
+		}
+		get := func(column string) string {
+			return strings.TrimSpace(fields[index[column]])
+		}
+		id := get("ID")
+		if id == "" {
+			continue
+		}
+		author := strings.Join(strings.Fields(strings.Join([]string{get("First Name"), get("Middle Name"), get("Last Name")}, " ")), " ")
+		if book, ok := byID[id]; ok {
+			if author != "" {
+				book.Authors = append(book.Authors, author)
+			}
+			continue
+		}
+		book := &Book{ID: id, Title: get("Title"), Lang: get("Language"), Year: get("Year"), Series: get("Series")}
+		if author != "" {
+			book.Authors = []string{author}
+		}
+		byID[id] = book
+		catalog.Books = append(catalog.Books, book)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return catalog, nil
+}
+
+// parseHeader maps column names to positions, fields may go in any order.
+func parseHeader(header string) (map[string]int, error) {
+	names := strings.Split(strings.TrimRight(strings.TrimPrefix(header, utf8BOM), "\r"), separator)
+	index := map[string]int{}
+	for i, name := range names {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("catalog has no `%s` column", column)
+		}
+	}
+	return index, nil
+}
+
+// Load reads catalog saved by Save.
+func Load(fileName string) (*Catalog, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	catalog := &Catalog{}
+	err = gob.NewDecoder(bufio.NewReader(file)).Decode(catalog)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read catalog %s: %w", fileName, err)
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Save(fileName string) error {
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteAtomic(fileName, 0644, true, func(w io.Writer) error {
+		out := bufio.NewWriter(w)
+		if err := gob.NewEncoder(out).Encode(c); err != nil {
+			return err
+		}
+		return out.Flush()
+	})
+}
+
+// Search returns books whose title or authors contain every word of query,
+// in the same form as search on the site. Zero limit means no limit.
+func (c *Catalog) Search(query string, limit int) []client.ListItem {
+	words := strings.Fields(normalize(query))
+	result := []client.ListItem{}
+	if len(words) == 0 {
+		return result
+	}
+	for _, book := range c.Books {
+		if !book.matches(words) {
+			continue
+		}
+		result = append(result, client.ListItem{ID: book.ID, Title: book.Title, Authors: book.Authors})
+		if limit > 0 && len(result) == limit {
+			break
+		}
+	}
+	return result
+}
+
+func (b *Book) matches(words []string) bool {
+	text := normalize(b.Title + " " + strings.Join(b.Authors, " "))
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(text string) string {
+	return strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+}
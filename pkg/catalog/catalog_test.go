@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+func loadTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	file, err := os.Open("testdata/catalog.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	catalog, err := Parse(file)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return catalog
+}
+
+func TestParse(t *testing.T) {
+	catalog := loadTestCatalog(t)
+	want := []*Book{
+		{ID: "175105", Title: "Война и мир", Authors: []string{"Лев Николаевич Толстой"}, Lang: "ru", Year: "1869", Series: "Роман-эпопея"},
+		{ID: "325729", Title: "Нежить", Authors: []string{"Елена Блашкович"}, Lang: "ru", Year: "2012"},
+		{ID: "12345", Title: "Двенадцать стульев", Authors: []string{"Илья Арнольдович Ильф", "Евгений Петрович Петров"}, Lang: "ru", Year: "1928", Series: "Остап Бендер"},
+		{ID: "5678", Title: "The Hobbit", Authors: []string{"John Ronald Reuel Tolkien"}, Lang: "en", Year: "1937"},
+		{ID: "999", Title: "Без автора", Lang: "ru"},
+	}
+	if !reflect.DeepEqual(catalog.Books, want) {
+		for _, book := range catalog.Books {
+			t.Logf("%+v", book)
+		}
+		t.Errorf("Parse() books differ")
+	}
+}
+
+func TestParse_BadHeader(t *testing.T) {
+	_, err := Parse(strings.NewReader("Title;ID\nВойна и мир;175105\n"))
+	if err == nil || !strings.Contains(err.Error(), "Last Name") {
+		t.Errorf("Parse() error = %v, want missing column", err)
+	}
+}
+
+func TestParseZip(t *testing.T) {
+	data, err := os.ReadFile("testdata/catalog.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	w, _ := archive.Create("catalog.txt")
+	_, _ = w.Write(data)
+	_ = archive.Close()
+
+	catalog, err := ParseZip(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseZip() error = %v", err)
+	}
+	if len(catalog.Books) != 5 {
+		t.Errorf("ParseZip() books = %d, want 5", len(catalog.Books))
+	}
+
+	buf.Reset()
+	archive = zip.NewWriter(buf)
+	_, _ = archive.Create("readme.md")
+	_ = archive.Close()
+	_, err = ParseZip(buf.Bytes())
+	if !errors.Is(err, ErrNoCatalog) {
+		t.Errorf("ParseZip() error = %v, want ErrNoCatalog", err)
+	}
+}
+
+func TestCatalog_Search(t *testing.T) {
+	catalog := loadTestCatalog(t)
+	tests := []struct {
+		query string
+		limit int
+		want  []client.ListItem
+	}{
+		{"война", 0, []client.ListItem{{ID: "175105", Title: "Война и мир", Authors: []string{"Лев Николаевич Толстой"}}}},
+		{"Стульев петров", 0, []client.ListItem{{ID: "12345", Title: "Двенадцать стульев", Authors: []string{"Илья Арнольдович Ильф", "Евгений Петрович Петров"}}}},
+		{"нежить", 0, []client.ListItem{{ID: "325729", Title: "Нежить", Authors: []string{"Елена Блашкович"}}}},
+		{"и", 2, []client.ListItem{
+			{ID: "175105", Title: "Война и мир", Authors: []string{"Лев Николаевич Толстой"}},
+			{ID: "325729", Title: "Нежить", Authors: []string{"Елена Блашкович"}},
+		}},
+		{"толстой нежить", 0, []client.ListItem{}},
+		{"", 0, []client.ListItem{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := catalog.Search(tt.query, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_SaveLoad(t *testing.T) {
+	catalog := loadTestCatalog(t)
+	fileName := filepath.Join(t.TempDir(), "cache", "catalog.gob")
+	if err := catalog.Save(fileName); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Books, catalog.Books) || !loaded.Imported.Equal(catalog.Imported) {
+		t.Errorf("Load() = %+v, want %+v", loaded, catalog)
+	}
+}
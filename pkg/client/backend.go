@@ -0,0 +1,103 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	BackendEnvKey = "FLIBUSTA_BACKEND"
+	// HTMLBackend scrapes site pages
+	HTMLBackend = "html"
+	// OPDSBackend reads OPDS catalog feeds
+	OPDSBackend = "opds"
+)
+
+// Listings of a backend besides search, see Backend.Supports
+const (
+	AuthorListing = "author"
+	SeriesListing = "series"
+	NewListing    = "new arrivals"
+)
+
+var ErrUnsupported = errors.New("not supported by backend")
+
+// Link is a book acquisition link.
+type Link struct {
+	Format string `json:"format"`
+	Type   string `json:"type"`
+	Href   string `json:"href"`
+}
+
+type AuthorResult struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Books []ListItem `json:"books"`
+}
+
+type SeriesResult struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name"`
+	Books []ListItem `json:"books"`
+}
+
+// Backend is a source of book listings. Both backends return the same ListItem,
+// OPDS also fills acquisition links. Listings which are not supported return ErrUnsupported.
+type Backend interface {
+	Name() string
+	// Supports tells whether listing is available, every backend supports search
+	Supports(listing string) bool
+	Search(query string) (*[]ListItem, error)
+	Author(id string) (*AuthorResult, error)
+	Series(id string) (*SeriesResult, error)
+	NewArrivals() (*[]ListItem, error)
+}
+
+// Backend returns backend chosen by FLIBUSTA_BACKEND, HTML by default.
+func (c *FlibustaClient) Backend() (Backend, error) {
+	return NewBackend(os.Getenv(BackendEnvKey), c)
+}
+
+func NewBackend(name string, c *FlibustaClient) (Backend, error) {
+	switch name {
+	case "", HTMLBackend:
+		return &htmlBackend{c}, nil
+	case OPDSBackend:
+		return &opdsBackend{c}, nil
+	}
+	return nil, fmt.Errorf("unknown backend `%s`, use %s or %s", name, HTMLBackend, OPDSBackend)
+}
+
+// htmlBackend uses search page parser, other listings are not parsed from HTML.
+type htmlBackend struct {
+	client *FlibustaClient
+}
+
+func (b *htmlBackend) Name() string {
+	return HTMLBackend
+}
+
+func (b *htmlBackend) Supports(string) bool {
+	return false
+}
+
+func (b *htmlBackend) Search(query string) (*[]ListItem, error) {
+	return b.client.Search(query, ParseSearch)
+}
+
+func (b *htmlBackend) Author(string) (*AuthorResult, error) {
+	return nil, b.unsupported(AuthorListing)
+}
+
+func (b *htmlBackend) Series(string) (*SeriesResult, error) {
+	return nil, b.unsupported(SeriesListing)
+}
+
+func (b *htmlBackend) NewArrivals() (*[]ListItem, error) {
+	return nil, b.unsupported(NewListing)
+}
+
+func (b *htmlBackend) unsupported(what string) error {
+	return fmt.Errorf("%s is %w %s, set %s=%s", what, ErrUnsupported, HTMLBackend, BackendEnvKey, OPDSBackend)
+}
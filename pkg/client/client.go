@@ -4,17 +4,23 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"strings"
+	"time"
 )
 
 const (
 	defaultScheme      = "http"
 	searchPath         = "/booksearch"
 	downloadPath       = "/b/"
+	readPath           = "read"
+	catalogPath        = "/catalog/catalog.zip"
+	sqlDumpPath        = "/sql/"
+	dailyPath          = "/daily/"
+	opdsPath           = "/opds"
 	browserUserAgent   = "Mozilla/5.0 (Windows NT 10.0; rv:78.0) Gecko/20100101 Firefox/78.0"
 	defaultProxyScheme = "http"
 	defaultProxyUrl    = "http://localhost:8118"
@@ -26,27 +32,58 @@ type FlibustaClient struct {
 }
 
 type DownloadResult struct {
-	Name string
-	File []byte
+	Name string `json:"name"`
+	File []byte `json:"-"`
+}
+
+// SavedBook is a book saved by `get` of the shell and interactive mode.
+type SavedBook struct {
+	Path string
+	// Owned is set when the book is already in library and was not downloaded again
+	Owned bool
+	// Warnings are failures which did not stop saving, e.g. book is not recorded in library
+	Warnings []string
 }
 
 type InfoResult struct {
-	ID         string
-	Title      string
-	Genre      string
-	Annotation string
-	Size       string
-	Formats    []string
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Authors      []string `json:"authors"`
+	Genre        string   `json:"genre"`
+	Series       string   `json:"series"`
+	SeriesNumber string   `json:"series_number"`
+	Year         string   `json:"year"`
+	Annotation   string   `json:"annotation"`
+	Size         string   `json:"size"`
+	Formats      []string `json:"formats"`
+	// Path of cover image on the site, empty when the book has no cover
+	Cover string `json:"cover,omitempty"`
+	// Rating as the site shows it, e.g. `хорошо` or `файл не оценен`
+	Rating string `json:"rating,omitempty"`
+	// Download link of every format, the original file has its own format, e.g. `djvu`
+	Links []Link `json:"links,omitempty"`
 }
 
 func validateBookFormat(format string) (err error) {
+	if !BookFormatRe.MatchString(format) {
+		return errors.New("invalid book format")
+	}
+	return nil
+}
 
-	for _, valid := range validFormats {
-		if format == valid {
-			return nil
+// formatPath returns the download path segment for the format.
+// Converted formats and the original file have known paths, others are found on the book page.
+func formatPath(format string) (string, bool) {
+	if format == Fb2Zip {
+		// fb2 is always served zipped
+		return Fb2, true
+	}
+	for _, converted := range append(convertedFormats, originalFilePath) {
+		if format == converted {
+			return format, true
 		}
 	}
-	return errors.New("invalid book format")
+	return "", false
 }
 
 func isHttpProxy(url string) bool {
@@ -54,12 +91,15 @@ func isHttpProxy(url string) bool {
 }
 
 func FromEnv() (*FlibustaClient, error) {
+	if err := loadEnvSelectors(); err != nil {
+		return nil, err
+	}
 	proxyUrlString := os.Getenv("FLIBUSTA_PROXY_URL")
 	if proxyUrlString == "" {
 		proxyUrlString = defaultProxyUrl
 	}
 	if !isHttpProxy(proxyUrlString) {
-		return nil, fmt.Errorf("%s does not contain scheme (http or https)", proxyUrlString)
+		return nil, fmt.Errorf("%s does not contain scheme (http or https)", RedactURL(proxyUrlString))
 	}
 	proxyUrl, err := url.Parse(proxyUrlString)
 	if err != nil {
@@ -71,6 +111,7 @@ func FromEnv() (*FlibustaClient, error) {
 	myClient := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
 	client.httpClient = myClient
 	client.proxyUrl = proxyUrl
+	logEvent(LevelDebug, "client", Field{"proxy", proxyUrl.Redacted()})
 
 	return &client, nil
 }
@@ -81,19 +122,25 @@ type ResponseResult struct {
 	Error    error
 }
 
-// Fetch all known mirrors and return first response
+// Fetch all known mirrors and return first response with real result.
+// Block, captcha and maintenance pages are counted as failed attempts.
 func executeRequest(client *http.Client, url *url.URL, headers Headers) (*http.Response, error) {
 	mirrors := FlibustaMirrors
 	envHost := getEnvHost()
 	if envHost != "" {
 		mirrors = append(mirrors, envHost)
 	}
-	result := make(chan *ResponseResult)
+	requestPath := url.RequestURI()
+	start := time.Now()
+	result := make(chan *ResponseResult, len(mirrors))
+	started := 0
 	for _, host := range mirrors {
 		req, err := buildRequest(host, url, headers)
 		if err != nil {
+			logEvent(LevelWarn, "invalid mirror", Field{"mirror", host}, Field{"error", err})
 			continue
 		}
+		started++
 		go func(r *http.Request, h string, out chan *ResponseResult) {
 			resp, err := client.Do(r)
 			out <- &ResponseResult{
@@ -103,27 +150,55 @@ func executeRequest(client *http.Client, url *url.URL, headers Headers) (*http.R
 			}
 		}(req, host, result)
 	}
-	for i := 0; i < len(mirrors); i++ {
+	failure := &NetworkError{}
+	for i := 0; i < started; i++ {
 		rr := <-result
+		fields := []Field{{"mirror", rr.Host}, {"path", requestPath}}
 		if rr.Error != nil {
-			log.Println(rr.Error)
-		} else if rr.Response.StatusCode != 200 {
-			// TODO: should handle this?
-			bodyBytes, _ := io.ReadAll(rr.Response.Body)
-			body := string(bodyBytes)
-			log.Println(body)
-			defer rr.Response.Body.Close()
-		} else {
+			failure.Failures = append(failure.Failures, rr.Error.Error())
+			logEvent(LevelDebug, "mirror failed", append(fields, Field{"duration", time.Since(start)}, Field{"error", rr.Error})...)
+			continue
+		}
+		kind, err := classifyResponse(rr.Response)
+		fields = append(fields, Field{"status", rr.Response.StatusCode}, Field{"duration", time.Since(start)})
+		if err != nil {
+			failure.Failures = append(failure.Failures, fmt.Sprintf("%s: %s", rr.Host, err))
+			logEvent(LevelDebug, "mirror failed", append(fields, Field{"error", err})...)
+			continue
+		}
+		fields = append(fields, Field{"bytes", rr.Response.ContentLength})
+		switch kind {
+		case PageResult:
+			logEvent(LevelInfo, "request", fields...)
+			go discardResponses(result, started-i-1)
 			return rr.Response, nil
+		case PageNotFound:
+			// Mirror works, there is just nothing to show
+			logEvent(LevelInfo, "request", append(fields, Field{"page", kind})...)
+			go discardResponses(result, started-i-1)
+			return nil, ErrNotFound
+		default:
+			failure.Failures = append(failure.Failures, fmt.Sprintf("%s: %s page (status %d)", rr.Host, kind, rr.Response.StatusCode))
+			logEvent(LevelDebug, "mirror failed", append(fields, Field{"page", kind})...)
+		}
+	}
+	logEvent(LevelDebug, "all mirrors failed", Field{"path", requestPath}, Field{"duration", time.Since(start)})
+	return nil, failure
+}
+
+// discardResponses closes responses of mirrors which were not used, their bodies may be still streaming
+func discardResponses(result chan *ResponseResult, count int) {
+	for i := 0; i < count; i++ {
+		if rr := <-result; rr.Response != nil {
+			_ = rr.Response.Body.Close()
 		}
 	}
-	return nil, fmt.Errorf("All request attempts failed. Maybe you want to use some proxy? For example:\n\n\t%s", TorproxySuggest)
 }
 
 func (c *FlibustaClient) Search(searchQuery string, respProcessor func(stream io.Reader) (*[]ListItem, error)) (result *[]ListItem, err error) {
 	searchUrl := buildSearchUrl(searchQuery)
 	headers := getHeaders()
-	log.Printf("Search Flibusta for `%s`", searchUrl.String())
+	logEvent(LevelDebug, "search", Field{"query", searchQuery})
 
 	resp, err := executeRequest(c.httpClient, searchUrl, headers)
 	if err != nil {
@@ -138,10 +213,13 @@ func (c *FlibustaClient) Download(id string, bookFormat string) (result *Downloa
 	if err != nil {
 		return
 	}
-	bookUrl := buildDownloadUrl(id, bookFormat)
+	bookUrl, err := c.downloadUrl(id, bookFormat)
+	if err != nil {
+		return
+	}
 	headers := getHeaders()
 
-	log.Printf("Download file by id: `%s`", bookUrl.String())
+	logEvent(LevelDebug, "download book", Field{"id", id}, Field{"format", bookFormat})
 
 	resp, err := executeRequest(c.httpClient, bookUrl, headers)
 
@@ -158,11 +236,29 @@ func (c *FlibustaClient) Download(id string, bookFormat string) (result *Downloa
 	return &DownloadResult{Name: getFileNameFromHeader(&resp.Header), File: file}, nil
 }
 
+// downloadUrl returns link of the format from the book page, like `/b/123/txt`,
+// or the original file `/b/123/download` which may be djvu or pdf.
+func (c *FlibustaClient) downloadUrl(id string, bookFormat string) (*url.URL, error) {
+	if segment, ok := formatPath(bookFormat); ok {
+		return buildDownloadUrl(id, segment), nil
+	}
+	info, err := c.Info(id, ParseInfo)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range info.Links {
+		if link.Format == bookFormat {
+			return buildLinkUrl(link.Href), nil
+		}
+	}
+	return nil, fmt.Errorf("format %s of book %s: %w, book has: %v", bookFormat, id, ErrNotFound, info.Formats)
+}
+
 func (c *FlibustaClient) Info(id string, respProcessor func(stream io.Reader) (result *InfoResult, err error)) (result *InfoResult, err error) {
 	infoUrl := buildInfoUrl(id)
 	headers := getHeaders()
 
-	log.Printf("Download file by id: `%s`", infoUrl.String())
+	logEvent(LevelDebug, "book info", Field{"id", id})
 
 	resp, err := executeRequest(c.httpClient, infoUrl, headers)
 	if err != nil {
@@ -172,3 +268,104 @@ func (c *FlibustaClient) Info(id string, respProcessor func(stream io.Reader) (r
 	defer resp.Body.Close()
 	return respProcessor(resp.Body)
 }
+
+// Cover downloads cover image of the book found by Info.
+func (c *FlibustaClient) Cover(info *InfoResult) (*DownloadResult, error) {
+	if info.Cover == "" {
+		return nil, ErrNotFound
+	}
+	coverUrl, err := buildCoverUrl(info.Cover)
+	if err != nil {
+		return nil, err
+	}
+	logEvent(LevelDebug, "download cover", Field{"id", info.ID})
+	return c.fetchFile(coverUrl, path.Base(coverUrl.Path))
+}
+
+func (c *FlibustaClient) Read(id string, respProcessor func(stream io.Reader) (result *BookText, err error)) (result *BookText, err error) {
+	readUrl := buildReadUrl(id)
+	headers := getHeaders()
+
+	logEvent(LevelDebug, "read book", Field{"id", id})
+
+	resp, err := executeRequest(c.httpClient, readUrl, headers)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+	result, err = respProcessor(resp.Body)
+	if err != nil {
+		return
+	}
+	result.ID = id
+	return
+}
+
+// Catalog downloads zipped list of all books in the library.
+func (c *FlibustaClient) Catalog() (*DownloadResult, error) {
+	catalogUrl := buildCatalogUrl()
+	logEvent(LevelDebug, "download catalog")
+	return c.fetchFile(catalogUrl, path.Base(catalogPath))
+}
+
+// SQLDump downloads dump of a library table, e.g. `lib.libbook.sql.gz`.
+// Dumps are large, so respProcessor reads the response while it is downloaded.
+func (c *FlibustaClient) SQLDump(name string, respProcessor func(stream io.Reader) error) error {
+	if safeFileName(name) != name {
+		return fmt.Errorf("invalid dump name `%s`", name)
+	}
+	dumpUrl := buildSQLDumpUrl(name)
+	logEvent(LevelDebug, "download SQL dump", Field{"name", name})
+
+	resp, err := executeRequest(c.httpClient, dumpUrl, getHeaders())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return respProcessor(resp.Body)
+}
+
+// DailyArchives lists names of daily update archives, oldest first.
+func (c *FlibustaClient) DailyArchives() ([]string, error) {
+	dailyUrl := buildDailyUrl("")
+	logEvent(LevelDebug, "list daily archives")
+
+	resp, err := executeRequest(c.httpClient, dailyUrl, getHeaders())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ParseDaily(resp.Body)
+}
+
+// DailyArchive downloads daily update archive, e.g. `f.fb2.590001-590290.zip`.
+// Archives are large, so respProcessor reads the response while it is downloaded.
+func (c *FlibustaClient) DailyArchive(name string, respProcessor func(stream io.Reader) error) error {
+	if safeFileName(name) != name {
+		return fmt.Errorf("invalid archive name `%s`", name)
+	}
+	dailyUrl := buildDailyUrl(name)
+	logEvent(LevelDebug, "download daily archive", Field{"name", name})
+
+	resp, err := executeRequest(c.httpClient, dailyUrl, getHeaders())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return respProcessor(resp.Body)
+}
+
+func (c *FlibustaClient) fetchFile(fileUrl *url.URL, name string) (result *DownloadResult, err error) {
+	resp, err := executeRequest(c.httpClient, fileUrl, getHeaders())
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+
+	file, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	return &DownloadResult{Name: name, File: file}, nil
+}
@@ -84,6 +84,38 @@ func TestFlibustaClient_Download(t *testing.T) {
 			},
 			false,
 		},
+		{
+			"Original file",
+			env{
+				testUrl.Host,
+			},
+			args{
+				"123",
+				"download",
+			},
+
+			&DownloadResult{
+				Name: "",
+				File: []byte("http://test.host/b/123/download"),
+			},
+			false,
+		},
+		{
+			"Zipped fb2",
+			env{
+				testUrl.Host,
+			},
+			args{
+				"123",
+				"fb2.zip",
+			},
+
+			&DownloadResult{
+				Name: "",
+				File: []byte("http://test.host/b/123/fb2"),
+			},
+			false,
+		},
 		{
 			"Wrong format",
 			env{
@@ -91,7 +123,7 @@ func TestFlibustaClient_Download(t *testing.T) {
 			},
 			args{
 				"123",
-				"docx",
+				"../mobi",
 			},
 
 			nil,
@@ -312,16 +344,31 @@ func Test_validateBookFormat(t *testing.T) {
 		{
 			"pdf",
 			args{"pdf"},
-			true,
+			false,
 		},
 		{
 			"djvu",
 			args{"djvu"},
-			true,
+			false,
 		},
 		{
 			"txt",
 			args{"txt"},
+			false,
+		},
+		{
+			"fb2.zip",
+			args{"fb2.zip"},
+			false,
+		},
+		{
+			"Upper case",
+			args{"PDF"},
+			true,
+		},
+		{
+			"Path",
+			args{"mobi/../fb2"},
 			true,
 		},
 	}
@@ -350,3 +397,47 @@ func processorFuncFabric(wantUrl string) func(stream io.Reader) (*[]ListItem, er
 		}, nil
 	}
 }
+
+func TestFlibustaClient_DownloadFromBookPage(t *testing.T) {
+	oldMirrors := FlibustaMirrors
+	defer func() {
+		FlibustaMirrors = oldMirrors
+	}()
+	FlibustaMirrors = []string{"test.host"}
+	page, err := os.ReadFile("testdata/parser/item_original.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(req *http.Request) *http.Response {
+		header := make(http.Header)
+		if req.URL.Path == "/b/325729" {
+			header.Set("Content-Type", "text/html; charset=utf-8")
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(page)), Header: header}
+		}
+		return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewBufferString(req.URL.Path)), Header: header}
+	}
+	c := &FlibustaClient{httpClient: NewTestClient(serve)}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"txt", "/b/325729/txt"},
+		{"rtf", "/b/325729/rtf"},
+		{"djvu", "/b/325729/download"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			result, err := c.Download("325729", tt.format)
+			if err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if string(result.File) != tt.want {
+				t.Errorf("Download() requested %s, want %s", result.File, tt.want)
+			}
+		})
+	}
+	_, err = c.Download("325729", "pdf")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Download() of missing format error = %v, want ErrNotFound", err)
+	}
+}
@@ -5,6 +5,9 @@ const (
 	Fb2                = "fb2"
 	Epub               = "epub"
 	Mobi               = "mobi"
+	Fb2Zip             = "fb2.zip"
+	// Path used by the site for the original uploaded file, whatever its format is.
+	originalFilePath = "download"
 )
 
 var (
@@ -13,6 +16,7 @@ var (
 		"flibusta.site",
 		"flibustahezeous3.onion",
 	}
-	validFormats    = []string{Fb2, Epub, Mobi}
-	TorproxySuggest = `docker run -it -p 8118:8118 -p 9050:9050 -d dperson/torproxy`
+	// Formats the site converts books to. Everything else is served as original file.
+	convertedFormats = []string{Fb2, Epub, Mobi}
+	TorproxySuggest  = `docker run -it -p 8118:8118 -p 9050:9050 -d dperson/torproxy`
 )
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NetworkError is returned when no mirror gave a usable response.
+type NetworkError struct {
+	// Failure of every mirror, like `flibusta.is: captcha page (status 200)`
+	Failures []string
+}
+
+func (e *NetworkError) Error() string {
+	return "all request attempts failed, maybe you want to use some proxy? For example:\n\n\t" + TorproxySuggest
+}
+
+// Detail lists failures of mirrors one per line.
+func (e *NetworkError) Detail() string {
+	return strings.Join(e.Failures, "\n")
+}
+
+// ParseError is returned when response is not what the parser expects.
+// Changed page layout gives LayoutDriftError instead.
+type ParseError struct {
+	What string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err == nil {
+		return "cannot parse " + e.What
+	}
+	return fmt.Sprintf("cannot parse %s: %s", e.What, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func parseError(what string, err error) error {
+	return &ParseError{What: what, Err: err}
+}
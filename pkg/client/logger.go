@@ -0,0 +1,134 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of log message, messages below logger level are dropped.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "DEBUG",
+	LevelInfo:  "INFO",
+	LevelWarn:  "WARN",
+	LevelError: "ERROR",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Field is a named value of log message, like mirror or status of the request.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger receives messages of the client. Requests are logged with fields
+// mirror, path, status, duration and bytes.
+type Logger interface {
+	Log(level Level, message string, fields ...Field)
+}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = NewTextLogger(os.Stderr, LevelInfo)
+)
+
+// SetLogger replaces logger of the package and returns previous one, nil disables logging.
+func SetLogger(l Logger) Logger {
+	if l == nil {
+		l = nopLogger{}
+	}
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	previous := logger
+	logger = l
+	return previous
+}
+
+func logEvent(level Level, message string, fields ...Field) {
+	loggerMu.RLock()
+	l := logger
+	loggerMu.RUnlock()
+	l.Log(level, message, fields...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...Field) {}
+
+// TextLogger writes one line per message:
+// `2021/10/19 10:19:42 INFO request mirror=flibusta.is path=/b/1 status=200 duration=350ms bytes=2317`
+type TextLogger struct {
+	mu    sync.Mutex
+	out   io.Writer
+	level Level
+	now   func() time.Time
+}
+
+func NewTextLogger(out io.Writer, level Level) *TextLogger {
+	return &TextLogger{out: out, level: level, now: time.Now}
+}
+
+func (l *TextLogger) Log(level Level, message string, fields ...Field) {
+	if level < l.level {
+		return
+	}
+	line := &strings.Builder{}
+	line.WriteString(l.now().Format("2006/01/02 15:04:05"))
+	line.WriteString(" " + level.String() + " " + message)
+	for _, field := range fields {
+		line.WriteString(" " + field.Key + "=" + formatValue(field.Value))
+	}
+	line.WriteString("\n")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line.String())
+}
+
+func formatValue(value interface{}) string {
+	var text string
+	switch v := value.(type) {
+	case time.Duration:
+		text = v.Round(time.Millisecond).String()
+	case error:
+		text = v.Error()
+	default:
+		text = fmt.Sprint(v)
+	}
+	if text == "" || strings.ContainsAny(text, " \t\n\"=") {
+		return strconv.Quote(text)
+	}
+	return text
+}
+
+// Password of proxy URL which could not be parsed
+var credentialsRe = regexp.MustCompile(`(//[^/@:]*):[^/@]*@`)
+
+// RedactURL hides password of URL, e.g. of proxy, so it can be logged or shown in errors.
+func RedactURL(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return credentialsRe.ReplaceAllString(rawUrl, "$1:xxxxx@")
+	}
+	return u.Redacted()
+}
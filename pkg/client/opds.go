@@ -0,0 +1,185 @@
+package client
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	OPDSBookIdRe   = regexp.MustCompile(`/b/([0-9]+)`)
+	OPDSAuthorIdRe = regexp.MustCompile(`/a/([0-9]+)$`)
+	// Feed titles are like `Серия: Остап Бендер`
+	feedTitlePrefixRe = regexp.MustCompile(`^[^:]*:\s*`)
+)
+
+const acquisitionRel = "http://opds-spec.org/acquisition"
+
+// Formats of original files served as /b/<id>/download
+var opdsTypeFormats = map[string]string{
+	"application/fb2+zip":            Fb2,
+	"application/epub+zip":           Epub,
+	"application/x-mobipocket-ebook": Mobi,
+	"application/pdf":                "pdf",
+	"image/vnd.djvu":                 "djvu",
+	"image/x-djvu":                   "djvu",
+	"application/rtf":                "rtf",
+	"application/msword":             "doc",
+	"text/plain":                     "txt",
+	"text/html":                      "html",
+}
+
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID      string       `xml:"id"`
+	Title   string       `xml:"title"`
+	Authors []atomAuthor `xml:"author"`
+	Links   []atomLink   `xml:"link"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type opdsBackend struct {
+	client *FlibustaClient
+}
+
+func (b *opdsBackend) Name() string {
+	return OPDSBackend
+}
+
+func (b *opdsBackend) Supports(string) bool {
+	return true
+}
+
+func (b *opdsBackend) Search(query string) (*[]ListItem, error) {
+	feed, err := b.fetch(buildOPDSSearchUrl(query))
+	if err != nil {
+		return nil, err
+	}
+	return feed.books(), nil
+}
+
+func (b *opdsBackend) Author(id string) (*AuthorResult, error) {
+	feed, err := b.fetch(buildOPDSAuthorUrl(id))
+	if err != nil {
+		return nil, err
+	}
+	result := &AuthorResult{ID: id, Books: *feed.books()}
+	for _, entry := range feed.Entries {
+		for _, author := range entry.Authors {
+			if match := OPDSAuthorIdRe.FindStringSubmatch(author.URI); match != nil && match[1] == id {
+				result.Name = strings.TrimSpace(author.Name)
+			}
+		}
+	}
+	if result.Name == "" {
+		result.Name = feed.name()
+	}
+	return result, nil
+}
+
+func (b *opdsBackend) Series(id string) (*SeriesResult, error) {
+	feed, err := b.fetch(buildOPDSSeriesUrl(id))
+	if err != nil {
+		return nil, err
+	}
+	return &SeriesResult{ID: id, Name: feed.name(), Books: *feed.books()}, nil
+}
+
+func (b *opdsBackend) NewArrivals() (*[]ListItem, error) {
+	feed, err := b.fetch(buildOPDSNewUrl())
+	if err != nil {
+		return nil, err
+	}
+	return feed.books(), nil
+}
+
+func (b *opdsBackend) fetch(feedUrl *url.URL) (*atomFeed, error) {
+	logEvent(LevelDebug, "OPDS feed", Field{"path", feedUrl.RequestURI()})
+	resp, err := executeRequest(b.client.httpClient, feedUrl, getHeaders())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseFeed(resp.Body)
+}
+
+// parseFeed reads OPDS Atom feed.
+func parseFeed(stream io.Reader) (*atomFeed, error) {
+	feed := &atomFeed{}
+	decoder := xml.NewDecoder(stream)
+	err := decoder.Decode(feed)
+	if err != nil {
+		return nil, parseError("OPDS feed", err)
+	}
+	return feed, nil
+}
+
+// books returns entries with acquisition links, navigation entries are skipped.
+func (f *atomFeed) books() *[]ListItem {
+	result := &[]ListItem{}
+	for _, entry := range f.Entries {
+		links := entry.acquisitionLinks()
+		if len(links) == 0 {
+			continue
+		}
+		id := ""
+		if match := OPDSBookIdRe.FindStringSubmatch(links[0].Href); match != nil {
+			id = match[1]
+		}
+		var authors []string
+		for _, author := range entry.Authors {
+			authors = append(authors, strings.TrimSpace(author.Name))
+		}
+		*result = append(*result, ListItem{
+			ID:      id,
+			Title:   strings.TrimSpace(entry.Title),
+			Authors: authors,
+			Links:   links,
+		})
+	}
+	return result
+}
+
+func (e *atomEntry) acquisitionLinks() (links []Link) {
+	for _, link := range e.Links {
+		if !strings.HasPrefix(link.Rel, acquisitionRel) {
+			continue
+		}
+		links = append(links, Link{Format: linkFormat(link), Type: link.Type, Href: link.Href})
+	}
+	return links
+}
+
+// linkFormat is the last path element, like `/b/123/epub`, or the format of original file by its type.
+func linkFormat(link atomLink) string {
+	format := path.Base(link.Href)
+	if format != originalFilePath && BookFormatRe.MatchString(format) {
+		return format
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(link.Type, ";", 2)[0])
+	if known, ok := opdsTypeFormats[mediaType]; ok {
+		return known
+	}
+	return originalFilePath
+}
+
+func (f *atomFeed) name() string {
+	return strings.TrimSpace(feedTitlePrefixRe.ReplaceAllString(f.Title, ""))
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// opdsFixtures serves recorded feeds by request path, other paths get not found page of the site
+func opdsFixtures(t *testing.T) *FlibustaClient {
+	fixtures := map[string]string{
+		"/opds/search":            "testdata/opds/search.xml",
+		"/opds/author/67890/time": "testdata/opds/author.xml",
+		"/opds/sequencebooks/7":   "testdata/opds/series.xml",
+		"/opds/new/0/new":         "testdata/opds/new.xml",
+	}
+	return &FlibustaClient{
+		httpClient: NewTestClient(func(req *http.Request) *http.Response {
+			header := make(http.Header)
+			header.Set("Content-Type", "application/atom+xml; charset=utf-8")
+			fileName, ok := fixtures[req.URL.Path]
+			if !ok {
+				header.Set("Content-Type", "text/html; charset=utf-8")
+				data, err := os.ReadFile("testdata/pages/not_found.html")
+				if err != nil {
+					t.Fatal(err)
+				}
+				return &http.Response{StatusCode: 404, Body: ioutil.NopCloser(bytes.NewReader(data)), Header: header}
+			}
+			data, err := os.ReadFile(fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return &http.Response{StatusCode: 200, Body: ioutil.NopCloser(bytes.NewReader(data)), Header: header}
+		}),
+	}
+}
+
+func TestOPDSBackend_Search(t *testing.T) {
+	backend, _ := NewBackend(OPDSBackend, opdsFixtures(t))
+	got, err := backend.Search("Нежить")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	want := &[]ListItem{
+		{
+			ID:      "325729",
+			Title:   "Нежить",
+			Authors: []string{"Джон Джозеф Адамс", "Стивен Кинг"},
+			Links: []Link{
+				{Format: "fb2", Type: "application/fb2+zip", Href: "/b/325729/fb2"},
+				{Format: "epub", Type: "application/epub+zip", Href: "/b/325729/epub"},
+				{Format: "mobi", Type: "application/x-mobipocket-ebook", Href: "/b/325729/mobi"},
+			},
+		},
+		{
+			ID:      "400001",
+			Title:   "Нежить. Сборник",
+			Authors: []string{"Анна Петрова"},
+			Links:   []Link{{Format: "pdf", Type: "application/pdf", Href: "/b/400001/download"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() = %+v, want %+v", got, want)
+	}
+}
+
+func TestOPDSBackend_Author(t *testing.T) {
+	backend, _ := NewBackend(OPDSBackend, opdsFixtures(t))
+	got, err := backend.Author("67890")
+	if err != nil {
+		t.Fatalf("Author() error = %v", err)
+	}
+	if got.Name != "Джон Джозеф Адамс" || len(got.Books) != 2 || got.Books[1].ID != "310001" {
+		t.Errorf("Author() = %+v", got)
+	}
+
+	_, err = backend.Author("1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Author() of missing author error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestOPDSBackend_Series(t *testing.T) {
+	backend, _ := NewBackend(OPDSBackend, opdsFixtures(t))
+	got, err := backend.Series("7")
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	if got.Name != "Остап Бендер" || len(got.Books) != 2 {
+		t.Fatalf("Series() = %+v", got)
+	}
+	wantLinks := []Link{
+		{Format: "fb2", Type: "application/fb2+zip", Href: "/b/12346/fb2"},
+		{Format: "djvu", Type: "image/vnd.djvu", Href: "/b/12346/download"},
+	}
+	if !reflect.DeepEqual(got.Books[1].Links, wantLinks) {
+		t.Errorf("Series() links = %+v, want %+v", got.Books[1].Links, wantLinks)
+	}
+}
+
+func TestOPDSBackend_NewArrivals(t *testing.T) {
+	backend, _ := NewBackend(OPDSBackend, opdsFixtures(t))
+	got, err := backend.NewArrivals()
+	if err != nil {
+		t.Fatalf("NewArrivals() error = %v", err)
+	}
+	// Navigation entry is skipped
+	if len(*got) != 1 || (*got)[0].ID != "600001" || (*got)[0].Title != "Новая книга" {
+		t.Errorf("NewArrivals() = %+v", got)
+	}
+}
+
+func TestNewBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantName string
+		wantErr  bool
+	}{
+		{"", HTMLBackend, false},
+		{"html", HTMLBackend, false},
+		{"opds", OPDSBackend, false},
+		{"json", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewBackend(tt.name, &FlibustaClient{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewBackend() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != nil && got.Name() != tt.wantName {
+				t.Errorf("NewBackend() = %v, want %v", got.Name(), tt.wantName)
+			}
+		})
+	}
+
+	html, _ := NewBackend(HTMLBackend, &FlibustaClient{})
+	if _, err := html.Author("1"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("html Author() error = %v, want ErrUnsupported", err)
+	}
+	opds, _ := NewBackend(OPDSBackend, &FlibustaClient{})
+	for _, listing := range []string{AuthorListing, SeriesListing, NewListing} {
+		if html.Supports(listing) || !opds.Supports(listing) {
+			t.Errorf("Supports(%q) of html = %v, of opds = %v", listing, html.Supports(listing), opds.Supports(listing))
+		}
+	}
+}
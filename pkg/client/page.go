@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// PageKind describes what kind of page mirror has responded with.
+type PageKind int
+
+const (
+	// PageResult is a regular site page or a book file.
+	PageResult PageKind = iota
+	// PageNotFound is a site page telling that nothing was found.
+	PageNotFound
+	// PageBlocked is a provider or government block page.
+	PageBlocked
+	// PageMaintenance is a stub shown while site is down.
+	PageMaintenance
+	// PageCaptcha is a bot check page.
+	PageCaptcha
+	// PageBroken is an empty or unrecognized page, like bare "502 Bad Gateway".
+	PageBroken
+)
+
+var ErrNotFound = errors.New("nothing found")
+
+var pageKindNames = map[PageKind]string{
+	PageResult:      "result",
+	PageNotFound:    "not found",
+	PageBlocked:     "blocked",
+	PageMaintenance: "maintenance",
+	PageCaptcha:     "captcha",
+	PageBroken:      "broken",
+}
+
+// Markers are matched against lower cased page body.
+var (
+	siteMarkers = []string{
+		`id="main"`,
+		// Directory listing of /daily/ and other file folders
+		"<title>index of /",
+	}
+	notFoundMarkers = []string{
+		"не нашлось ни единой",
+		"страница не найдена",
+		"page not found",
+	}
+	captchaMarkers = []string{
+		"captcha",
+		"checking your browser",
+		"cf-challenge",
+	}
+	blockedMarkers = []string{
+		"доступ ограничен",
+		"доступ к ресурсу ограничен",
+		"заблокирован",
+		"роскомнадзор",
+		"eais.rkn.gov.ru",
+		"access denied",
+		"blocked",
+	}
+	maintenanceMarkers = []string{
+		"under maintenance",
+		"технических работ",
+		"временно недоступен",
+		"service unavailable",
+		"bad gateway",
+		"gateway timeout",
+	}
+)
+
+func (kind PageKind) String() string {
+	return pageKindNames[kind]
+}
+
+// ClassifyPage guesses page kind by HTTP status and body.
+// Site pages are recognized by layout first, so words from book annotations
+// or comments can not turn a real page into a block page.
+// 404 means nothing found only on a site page, bare 404 of a proxy or a dead mirror is a failure.
+func ClassifyPage(statusCode int, body []byte) PageKind {
+	text := strings.ToLower(string(body))
+	if statusCode == http.StatusNotFound && containsAny(text, siteMarkers) {
+		return PageNotFound
+	}
+	if statusCode == http.StatusOK && containsAny(text, siteMarkers) {
+		if containsAny(text, notFoundMarkers) {
+			return PageNotFound
+		}
+		return PageResult
+	}
+	switch {
+	case strings.TrimSpace(text) == "":
+		return PageBroken
+	case containsAny(text, captchaMarkers):
+		return PageCaptcha
+	case containsAny(text, blockedMarkers):
+		return PageBlocked
+	case containsAny(text, maintenanceMarkers) || statusCode == http.StatusServiceUnavailable:
+		return PageMaintenance
+	}
+	return PageBroken
+}
+
+// sniffLen is enough to detect content type of a response, see http.DetectContentType
+const sniffLen = 512
+
+// classifyResponse reads HTML responses and classifies them, keeping body readable.
+// Anything else, e.g. book file, is considered a result and its body is left streaming.
+func classifyResponse(resp *http.Response) (PageKind, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(resp.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		_ = resp.Body.Close()
+		return PageBroken, err
+	}
+	head = head[:n]
+	empty := n < sniffLen && len(bytes.TrimSpace(head)) == 0
+	if resp.StatusCode == http.StatusOK && !empty && !isHTML(resp.Header, head) {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+		return PageResult, nil
+	}
+
+	rest, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return PageBroken, err
+	}
+	body := append(head, rest...)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return ClassifyPage(resp.StatusCode, body), nil
+}
+
+func isHTML(h http.Header, body []byte) bool {
+	contentType := h.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return strings.HasPrefix(contentType, "text/html")
+}
+
+func containsAny(text string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestClassifyPage(t *testing.T) {
+	type args struct {
+		statusCode    int
+		inputFileName string
+	}
+	tests := []struct {
+		name string
+		args args
+		want PageKind
+	}{
+		{
+			"Index",
+			args{200, "parser/index.html"},
+			PageResult,
+		},
+		{
+			"Item",
+			args{200, "parser/item.html"},
+			PageResult,
+		},
+		{
+			"List",
+			args{200, "parser/list.html"},
+			PageResult,
+		},
+		{
+			"Daily archives listing",
+			args{200, "daily/index.html"},
+			PageResult,
+		},
+		{
+			"Nothing found",
+			args{200, "pages/not_found.html"},
+			PageNotFound,
+		},
+		{
+			"Site 404",
+			args{404, "pages/not_found.html"},
+			PageNotFound,
+		},
+		{
+			"Bare 404",
+			args{404, "pages/404.html"},
+			PageBroken,
+		},
+		{
+			"Block page with 404",
+			args{404, "pages/blocked.html"},
+			PageBlocked,
+		},
+		{
+			"Site page with error status",
+			args{500, "parser/index.html"},
+			PageBroken,
+		},
+		{
+			"Provider block",
+			args{200, "pages/blocked.html"},
+			PageBlocked,
+		},
+		{
+			"Captcha",
+			args{200, "pages/captcha.html"},
+			PageCaptcha,
+		},
+		{
+			"Maintenance",
+			args{200, "pages/maintenance.html"},
+			PageMaintenance,
+		},
+		{
+			"502 stub",
+			args{200, "parser/502.html"},
+			PageMaintenance,
+		},
+		{
+			"Json",
+			args{200, "parser/empty.json"},
+			PageBroken,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := os.ReadFile(path.Join("testdata", tt.args.inputFileName))
+			if err != nil {
+				t.Errorf("Cannot open test data file: %v", tt.args.inputFileName)
+				return
+			}
+			if got := ClassifyPage(tt.args.statusCode, body); got != tt.want {
+				t.Errorf("ClassifyPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := ClassifyPage(200, []byte(" \n")); got != PageBroken {
+		t.Errorf("ClassifyPage() for empty body = %v, want %v", got, PageBroken)
+	}
+}
+
+func Test_executeRequest(t *testing.T) {
+	oldMirrors := FlibustaMirrors
+	defer func() {
+		FlibustaMirrors = oldMirrors
+	}()
+	FlibustaMirrors = []string{"blocked.host", "captcha.host", "good.host"}
+
+	pages := map[string]string{
+		"blocked.host": "pages/blocked.html",
+		"captcha.host": "pages/captcha.html",
+		"good.host":    "parser/item.html",
+	}
+	serve := func(req *http.Request) *http.Response {
+		body, _ := os.ReadFile(path.Join("testdata", pages[req.URL.Host]))
+		header := make(http.Header)
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		return &http.Response{
+			StatusCode: 200,
+			Body:       ioutil.NopCloser(bytes.NewReader(body)),
+			Header:     header,
+		}
+	}
+
+	resp, err := executeRequest(NewTestClient(serve), buildInfoUrl("325729"), getHeaders())
+	if err != nil {
+		t.Fatalf("executeRequest() error = %v", err)
+	}
+	info, err := ParseInfo(resp.Body)
+	if err != nil {
+		t.Fatalf("ParseInfo() error = %v", err)
+	}
+	if info.ID != "325729" {
+		t.Errorf("executeRequest() returned page of %v, want 325729", info.ID)
+	}
+
+	FlibustaMirrors = []string{"blocked.host", "captcha.host"}
+	_, err = executeRequest(NewTestClient(serve), buildInfoUrl("325729"), getHeaders())
+	var networkErr *NetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("executeRequest() with blocked mirrors only error = %v, want NetworkError", err)
+	}
+	sort.Strings(networkErr.Failures)
+	want := []string{"blocked.host: blocked page (status 200)", "captcha.host: captcha page (status 200)"}
+	if !reflect.DeepEqual(networkErr.Failures, want) {
+		t.Errorf("NetworkError.Failures = %v, want %v", networkErr.Failures, want)
+	}
+}
+
+func Test_executeRequestBare404(t *testing.T) {
+	oldMirrors := FlibustaMirrors
+	defer func() {
+		FlibustaMirrors = oldMirrors
+	}()
+	FlibustaMirrors = []string{"dead.host", "good.host"}
+
+	pages := map[string]string{
+		"dead.host": "pages/404.html",
+		"good.host": "parser/item.html",
+	}
+	serve := func(req *http.Request) *http.Response {
+		body, _ := os.ReadFile(path.Join("testdata", pages[req.URL.Host]))
+		status := 200
+		if req.URL.Host == "dead.host" {
+			status = 404
+		}
+		header := make(http.Header)
+		header.Set("Content-Type", "text/html; charset=utf-8")
+		return &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(bytes.NewReader(body)),
+			Header:     header,
+		}
+	}
+	// Whichever mirror answers first, bare 404 does not stop the fan-out
+	for i := 0; i < 10; i++ {
+		resp, err := executeRequest(NewTestClient(serve), buildInfoUrl("325729"), getHeaders())
+		if err != nil {
+			t.Fatalf("executeRequest() error = %v", err)
+		}
+		info, err := ParseInfo(resp.Body)
+		if err != nil || info.ID != "325729" {
+			t.Fatalf("executeRequest() returned page of %v, %v", info, err)
+		}
+	}
+}
+
+// countingReader counts bytes read from it
+type countingReader struct {
+	reader *bytes.Reader
+	read   int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += n
+	return n, err
+}
+
+func Test_classifyResponseStreams(t *testing.T) {
+	file := bytes.Repeat([]byte{0x1f, 0x8b}, 10*sniffLen)
+	body := &countingReader{reader: bytes.NewReader(file)}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/gzip")
+	resp := &http.Response{StatusCode: 200, Body: ioutil.NopCloser(body), Header: header}
+
+	kind, err := classifyResponse(resp)
+	if err != nil || kind != PageResult {
+		t.Fatalf("classifyResponse() = %v, %v", kind, err)
+	}
+	if body.read > sniffLen {
+		t.Errorf("classifyResponse() read %d bytes of file, want at most %d", body.read, sniffLen)
+	}
+	got, _ := ioutil.ReadAll(resp.Body)
+	if !bytes.Equal(got, file) {
+		t.Errorf("body after classifyResponse() has %d bytes, want %d", len(got), len(file))
+	}
+}
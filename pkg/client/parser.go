@@ -7,61 +7,91 @@ import (
 	"github.com/antchfx/htmlquery"
 	"golang.org/x/net/html"
 	"io"
+	"net/url"
 	"regexp"
 	"strings"
 	"text/template"
 )
 
-const (
-	listItemsSelector = "//div[@id='main']/ul/li"
-	itemBodySelector  = "//div[@id='main']"
-)
-
 var (
 	ItemInListIdRe        = regexp.MustCompile(`[0-9]+$`)
 	ItemInDescriptionIdRe = regexp.MustCompile(`b/([0-9]+)/read$`)
+	DownloadLinkRe        = regexp.MustCompile(`b/[0-9]+/([a-z0-9.]+)$`)
+	SeriesNumberRe        = regexp.MustCompile(`^\s*-\s*([0-9]+)`)
+	EditionYearRe         = regexp.MustCompile(`издание\s+([0-9]{4})`)
 	stripSpacesRe         = regexp.MustCompile(`\s+`)
+	DailyArchiveRe        = regexp.MustCompile(`^[A-Za-z0-9._-]+\.zip$`)
 )
 
 type ListItem struct {
-	Title   string
-	Authors []string
-	ID      string
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Authors []string `json:"authors"`
+	// Acquisition links, only OPDS backend knows them
+	Links []Link `json:"links,omitempty"`
+}
+
+// TextBlock is a paragraph or a heading of a book text.
+type TextBlock struct {
+	Heading bool
+	Text    string
+}
+
+// BookText is a book converted from online reader page.
+type BookText struct {
+	ID     string
+	Title  string
+	Blocks []TextBlock
 }
 
 func (item *ListItem) String() string {
 	return fmt.Sprintf("%s: %s <%s>", item.ID, item.Title, strings.Join(item.Authors, ", "))
 }
 
-func (info *InfoResult) String() string {
-	const tpl = `
+var infoTemplate = template.Must(template.New("bookInfo").Parse(`
 	{{.Title}}
 	ID: {{.ID}}
 	Size: {{.Size}}
 	Formats: {{range .Formats}} {{.}} {{end}}
 
 	{{.Annotation}}
-	`
-	t, err := template.New("bookInfo").Parse(tpl)
-	check(err)
-	buf := &bytes.Buffer{}
-	err = t.Execute(buf, info)
-	check(err)
+	`))
 
+func (info *InfoResult) String() string {
+	buf := &bytes.Buffer{}
+	err := infoTemplate.Execute(buf, info)
+	if err != nil {
+		// Not expected for the fixed template
+		return fmt.Sprintf("%s: %s", info.ID, info.Title)
+	}
 	return buf.String()
 }
 
+// TitleWithoutFormat strips format the site adds to the book title: `Нежить (fb2)`
+func TitleWithoutFormat(title string) string {
+	if i := strings.LastIndex(title, " ("); i > 0 && strings.HasSuffix(title, ")") {
+		return title[:i]
+	}
+	return title
+}
+
 func ParseSearch(stream io.Reader) (result *[]ListItem, err error) {
-	doc, _ := htmlquery.Parse(stream)
+	doc, err := htmlquery.Parse(stream)
+	if err != nil {
+		return nil, err
+	}
 
-	list := htmlquery.Find(doc, listItemsSelector)
+	list := htmlquery.Find(doc, selectors.ListItems)
 	if list == nil {
-		return nil, errors.New("list with items not found")
+		return nil, driftError("list items", selectors.ListItems)
 	}
 	result = &[]ListItem{}
 	for _, listItem := range list {
-		titleNode := htmlquery.FindOne(listItem, "//a[1]")
-		authorNodes := htmlquery.Find(listItem, "//a[position()>1]")
+		titleNode := htmlquery.FindOne(listItem, selectors.ListItemTitle)
+		if titleNode == nil {
+			return nil, driftError("list item title", selectors.ListItemTitle)
+		}
+		authorNodes := htmlquery.Find(listItem, selectors.ListItemAuthors)
 		itemHref := htmlquery.SelectAttr(titleNode, "href")
 
 		title := &bytes.Buffer{}
@@ -77,11 +107,14 @@ func ParseSearch(stream io.Reader) (result *[]ListItem, err error) {
 }
 
 func ParseInfo(stream io.Reader) (result *InfoResult, err error) {
-	doc, _ := htmlquery.Parse(stream)
+	doc, err := htmlquery.Parse(stream)
+	if err != nil {
+		return nil, err
+	}
 
-	list := htmlquery.Find(doc, itemBodySelector)
+	list := htmlquery.Find(doc, selectors.ItemBody)
 	if list == nil {
-		return nil, errors.New("item body not found")
+		return nil, driftError("item body", selectors.ItemBody)
 	}
 
 	getText := func(node *html.Node) (data string) {
@@ -95,20 +128,102 @@ func ParseInfo(stream io.Reader) (result *InfoResult, err error) {
 	id := getID(doc)
 	if id == "" {
 		// It is not item page
-		return nil, errors.New("item not found")
+		return nil, parseError("book page", errors.New("book ID not found"))
 	}
 
 	result = &InfoResult{
 		ID:         id,
-		Title:      getText(htmlquery.FindOne(doc, "//div[@id='main']/h1/text()")),
-		Genre:      getText(htmlquery.FindOne(doc, "//p[@class=\"genre\"]")),
-		Annotation: getText(htmlquery.FindOne(doc, "//div[@id='main']/p/text()")),
-		Size:       getText(htmlquery.FindOne(doc, "//span[@style=\"size\"]/text()")),
-		Formats:    getFormats(doc),
+		Title:      getText(htmlquery.FindOne(doc, selectors.ItemTitle)),
+		Genre:      getText(htmlquery.FindOne(doc, selectors.ItemGenre)),
+		Annotation: getText(htmlquery.FindOne(doc, selectors.ItemAnnotation)),
+		Size:       getText(htmlquery.FindOne(doc, selectors.ItemSize)),
+		Links:      getFormats(doc),
+		Authors:    getBookAuthors(doc),
+		Year:       getYear(doc),
+		Cover:      getCover(doc),
+		Rating:     getRating(doc),
+	}
+	for _, link := range result.Links {
+		result.Formats = append(result.Formats, link.Format)
+	}
+	result.Series, result.SeriesNumber = getSeries(doc)
+	if result.Title == "" {
+		return nil, driftError("title", selectors.ItemTitle)
 	}
 	return
 }
 
+// ParseRead converts online reader page to headings and paragraphs.
+func ParseRead(stream io.Reader) (result *BookText, err error) {
+	doc, err := htmlquery.Parse(stream)
+	if err != nil {
+		return nil, err
+	}
+
+	body := htmlquery.FindOne(doc, selectors.ItemBody)
+	if body == nil {
+		return nil, driftError("item body", selectors.ItemBody)
+	}
+	result = &BookText{}
+	if title := htmlquery.FindOne(doc, selectors.ItemTitle); title != nil {
+		buf := &bytes.Buffer{}
+		collectText(title, buf)
+		result.Title = strings.TrimSpace(buf.String())
+	}
+	collectBlocks(body, &result.Blocks)
+	if len(result.Blocks) == 0 {
+		return nil, parseError("book text", errors.New("no paragraphs found"))
+	}
+	return result, nil
+}
+
+func collectBlocks(n *html.Node, blocks *[]TextBlock) {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "script", "style", "form", "h1":
+			return
+		case "h2", "h3", "h4", "h5", "h6":
+			appendBlock(n, true, blocks)
+			return
+		case "p":
+			appendBlock(n, false, blocks)
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectBlocks(c, blocks)
+	}
+}
+
+func appendBlock(n *html.Node, heading bool, blocks *[]TextBlock) {
+	buf := &bytes.Buffer{}
+	collectLines(n, buf)
+	var lines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		line = strings.TrimSpace(strings.ReplaceAll(line, "\u00a0", " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	*blocks = append(*blocks, TextBlock{Heading: heading, Text: strings.Join(lines, "\n")})
+}
+
+// collectLines works like collectText, but keeps line breaks from <br>
+func collectLines(n *html.Node, buf *bytes.Buffer) {
+	if n.Type == html.ElementNode && n.Data == "br" {
+		buf.WriteString("\n")
+	}
+	if n.Type == html.TextNode {
+		buf.WriteString(stripSpacesRe.ReplaceAllString(n.Data, ` `))
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		collectLines(c, buf)
+	}
+}
+
 func getAuthors(nodes []*html.Node) (authors []string) {
 	for _, node := range nodes {
 		authors = append(authors, htmlquery.InnerText(node))
@@ -116,23 +231,122 @@ func getAuthors(nodes []*html.Node) (authors []string) {
 	return authors
 }
 
-func getFormats(doc *html.Node) (formats []string) {
-	for _, format := range validFormats {
-		available := htmlquery.FindOne(doc, "//a[contains(@href, \""+format+"\")]")
-		if available != nil {
-			formats = append(formats, format)
+// getBookAuthors returns authors of the book page, translators are listed after "(перевод:" and skipped.
+func getBookAuthors(doc *html.Node) (authors []string) {
+	for _, node := range htmlquery.Find(doc, selectors.ItemAuthors) {
+		if isTranslator(node) {
+			continue
+		}
+		authors = append(authors, strings.TrimSpace(htmlquery.InnerText(node)))
+	}
+	return authors
+}
+
+func isTranslator(node *html.Node) bool {
+	for sibling := node.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.TextNode && strings.Contains(sibling.Data, "перевод") {
+			return true
+		}
+	}
+	return false
+}
+
+// getSeries returns series name and number, number follows the link: `Series - 2`
+func getSeries(doc *html.Node) (series string, number string) {
+	link := htmlquery.FindOne(doc, selectors.ItemSeries)
+	if link == nil {
+		return
+	}
+	series = strings.TrimSpace(htmlquery.InnerText(link))
+	if next := link.NextSibling; next != nil && next.Type == html.TextNode {
+		if match := SeriesNumberRe.FindStringSubmatch(next.Data); match != nil {
+			number = match[1]
 		}
 	}
-	return formats
+	return
+}
+
+func getCover(doc *html.Node) string {
+	cover := htmlquery.FindOne(doc, selectors.ItemCover)
+	if cover == nil {
+		return ""
+	}
+	return htmlquery.SelectAttr(cover, "src")
+}
+
+func getRating(doc *html.Node) string {
+	rating := htmlquery.FindOne(doc, selectors.ItemRating)
+	if rating == nil {
+		return ""
+	}
+	return strings.TrimSpace(htmlquery.SelectAttr(rating, "title"))
+}
+
+func getYear(doc *html.Node) string {
+	edition := htmlquery.FindOne(doc, selectors.ItemEdition)
+	if edition == nil {
+		return ""
+	}
+	if match := EditionYearRe.FindStringSubmatch(htmlquery.InnerText(edition)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// getFormats collects formats from the "скачать:" links of the book page.
+// Converted formats are linked as `(fb2)`, while original files use the
+// `/download` path with the format in the text: `(скачать pdf)`.
+func getFormats(doc *html.Node) (links []Link) {
+	marker := htmlquery.FindOne(doc, selectors.ItemDownloads)
+	if marker == nil {
+		return links
+	}
+	for sibling := marker.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type == html.TextNode {
+			if strings.Trim(sibling.Data, " -\n\t\u00a0") != "" {
+				break
+			}
+			continue
+		}
+		if sibling.Type != html.ElementNode || sibling.Data != "a" {
+			break
+		}
+		href := htmlquery.SelectAttr(sibling, "href")
+		match := DownloadLinkRe.FindStringSubmatch(href)
+		if match == nil {
+			continue
+		}
+		format := formatFromLinkText(htmlquery.InnerText(sibling))
+		if validateBookFormat(format) != nil {
+			format = match[1]
+		}
+		if format == originalFilePath {
+			continue
+		}
+		// Only path is kept, host is one of mirrors
+		linkPath := href
+		if u, err := url.Parse(href); err == nil {
+			linkPath = u.Path
+		}
+		links = append(links, Link{Format: format, Href: linkPath})
+	}
+	return links
+}
+
+func formatFromLinkText(text string) string {
+	text = strings.Trim(text, " ()\n\t")
+	text = strings.TrimSpace(strings.TrimPrefix(text, "скачать"))
+	return strings.ToLower(text)
 }
 
 func getID(doc *html.Node) (ID string) {
-	readUrl := htmlquery.SelectAttr(
-		htmlquery.FindOne(doc, "//a[contains(@href, \"read\")]"),
-		"href",
-	)
-	if readUrl != "" {
-		return ItemInDescriptionIdRe.FindStringSubmatch(readUrl)[1]
+	readLink := htmlquery.FindOne(doc, selectors.ItemReadLink)
+	if readLink == nil {
+		return
+	}
+	match := ItemInDescriptionIdRe.FindStringSubmatch(htmlquery.SelectAttr(readLink, "href"))
+	if match != nil {
+		return match[1]
 	}
 	return
 }
@@ -146,3 +360,22 @@ func collectText(n *html.Node, buf *bytes.Buffer) {
 		collectText(c, buf)
 	}
 }
+
+// ParseDaily returns archive names linked from /daily/ listing in order of the page.
+func ParseDaily(stream io.Reader) ([]string, error) {
+	doc, err := htmlquery.Parse(stream)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	seen := map[string]bool{}
+	for _, link := range htmlquery.Find(doc, "//a[@href]") {
+		name := htmlquery.SelectAttr(link, "href")
+		if !DailyArchiveRe.MatchString(name) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
@@ -1,12 +1,15 @@
 package client
 
 import (
+	"errors"
 	"os"
 	"path"
 	"reflect"
 	"testing"
 )
 
+var itemAuthors = []string{"Харлан Эллисон", "Поппи З. Брайт", "Лорел Гамильтон", "Нил Гейман", "Майкл Суэнвик", "Джордж Мартин", "Дарелл Швайцер", "Роберт Силверберг", "Дэн Симмонс", "Джеффри Форд", "Джон Джозеф Адамс", "Джо Хилл", "Дейл Бейли", "Уилл Макинтош", "Нэнси Холдер", "Келли Линк", "Нэнси Килпатрик", "Нина Кирики Хоффман", "Адам-Трой Кастро", "Сьюзан Палвик", "Дэвид Таллерман", "Норман Партридж", "Брайан Эвенсон", "Ханна Вольф Боуэн", "Лиза Мортон", "Дэвид Барр Кертли", "Кэтрин Чик", "Энди Дункан", "Скотт Эдельман", "Джон Лэнган", "Джо Р. Лансдейл", "Дэвид Джеймс Шоу"}
+
 func TestParseSearch(t *testing.T) {
 	type args struct {
 		inputFileName string
@@ -182,6 +185,41 @@ func TestParseInfo(t *testing.T) {
 				Size:       "2263K, 595 с.",
 				Genre:      "Ужасы",
 				Formats:    []string{"fb2", "epub", "mobi"},
+				Links: []Link{
+					{Format: "fb2", Href: "/b/325729/fb2"},
+					{Format: "epub", Href: "/b/325729/epub"},
+					{Format: "mobi", Href: "/b/325729/mobi"},
+				},
+				Authors:      itemAuthors,
+				Series:       "Антология ужасов",
+				SeriesNumber: "2009",
+				Year:         "2009",
+				Cover:        "item_files/cover.jpg",
+				Rating:       "файл не оценен",
+			},
+			false,
+		},
+		{
+			"Item - original file and other formats",
+			args{"item_original.html"},
+			&InfoResult{
+				ID:         "325729",
+				Title:      "Нежить (fb2)",
+				Annotation: "На страницах новой антологии собраны лучшие рассказы о нежити! Красочные картины дефилирующих по городам и весям чудовищ, некогда бывших людьми, способны защекотать самые крепкие нервы. Для вас, дорогой читатель, напрягали фантазию такие мастера макабрических сюжетов, как Майкл Суэнвик, Джеффри Форд, Лорел Гамильтон, Нил Гейман, Джордж Мартин, Харлан Эллисон с Робертом Сильвербергом и многие другие.",
+				Size:       "2263K, 595 с.",
+				Genre:      "Ужасы",
+				Formats:    []string{"djvu", "txt", "rtf"},
+				Links: []Link{
+					{Format: "djvu", Href: "/b/325729/download"},
+					{Format: "txt", Href: "/b/325729/txt"},
+					{Format: "rtf", Href: "/b/325729/rtf"},
+				},
+				Authors:      itemAuthors,
+				Series:       "Антология ужасов",
+				SeriesNumber: "2009",
+				Year:         "2009",
+				Cover:        "item_files/cover.jpg",
+				Rating:       "файл не оценен",
 			},
 			false,
 		},
@@ -219,3 +257,134 @@ func TestParseInfo(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRead(t *testing.T) {
+	type args struct {
+		inputFileName string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantResult *BookText
+		wantErr    bool
+	}{
+		{
+			"Reader page",
+			args{"read.html"},
+			&BookText{
+				Title: "Нежить (fb2)",
+				Blocks: []TextBlock{
+					{Heading: true, Text: "Джон Джозеф Адамс\nПредисловие"},
+					{Text: "Зомби — это метафора. Зомби могут символизировать что угодно."},
+					{Text: "Джон Джозеф Адамс"},
+					{Heading: true, Text: "Дэн Симмонс\nЭти глаза, что не закрываются никогда"},
+					{Text: "Каждый раз, когда я начинаю думать, что Карл Свенсон и вправду мертв, он возвращается."},
+					{Text: "Конец."},
+				},
+			},
+			false,
+		},
+		{
+			"502",
+			args{"502.html"},
+			nil,
+			true,
+		},
+		{
+			"json",
+			args{"empty.json"},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fullPath := path.Join("testdata/parser", tt.args.inputFileName)
+			stream, err := os.Open(fullPath)
+			if err != nil {
+				t.Errorf("Cannot open test data file: %v", fullPath)
+				return
+			}
+			gotResult, err := ParseRead(stream)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRead() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotResult, tt.wantResult) {
+				t.Errorf("ParseRead() gotResult = %v, want %v", gotResult, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestParseDaily(t *testing.T) {
+	tests := []struct {
+		name          string
+		inputFileName string
+		want          []string
+	}{
+		{
+			"Daily listing",
+			"testdata/daily/index.html",
+			[]string{"f.fb2.590001-590290.zip", "f.fb2.590291-590511.zip", "f.n.590001-590290.zip", "f.fb2.590512-590800.zip"},
+		},
+		{
+			"Site page",
+			"testdata/parser/item.html",
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := os.Open(tt.inputFileName)
+			if err != nil {
+				t.Errorf("Cannot open test data file: %v", tt.inputFileName)
+				return
+			}
+			defer stream.Close()
+			got, err := ParseDaily(stream)
+			if err != nil {
+				t.Errorf("ParseDaily() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDaily() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseInfo_errorType(t *testing.T) {
+	for _, name := range []string{"index.html", "list.html", "502.html"} {
+		t.Run(name, func(t *testing.T) {
+			stream, err := os.Open(path.Join("testdata/parser", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+			_, err = ParseInfo(stream)
+			var parseErr *ParseError
+			var driftErr *LayoutDriftError
+			if !errors.As(err, &parseErr) && !errors.As(err, &driftErr) {
+				t.Errorf("ParseInfo() error = %#v, want ParseError or LayoutDriftError", err)
+			}
+		})
+	}
+}
+
+func TestTitleWithoutFormat(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Нежить (fb2)", "Нежить"},
+		{"Нежить (сборник) (epub)", "Нежить (сборник)"},
+		{"Нежить", "Нежить"},
+		{"(fb2)", "(fb2)"},
+	}
+	for _, tt := range tests {
+		if got := TitleWithoutFormat(tt.title); got != tt.want {
+			t.Errorf("TitleWithoutFormat(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"golang.org/x/net/html"
+	"os"
+	"reflect"
+)
+
+const SelectorsFileEnvKey = "FLIBUSTA_SELECTORS_FILE"
+
+// SelectorProfile holds XPath expressions used by parsers.
+// When site layout changes, profile can be overridden from JSON file
+// without waiting for a new release.
+type SelectorProfile struct {
+	Version string `json:"version"`
+
+	// Search results page
+	ListItems       string `json:"list_items"`
+	ListItemTitle   string `json:"list_item_title"`
+	ListItemAuthors string `json:"list_item_authors"`
+
+	// Book page
+	ItemBody       string `json:"item_body"`
+	ItemTitle      string `json:"item_title"`
+	ItemGenre      string `json:"item_genre"`
+	ItemAnnotation string `json:"item_annotation"`
+	ItemSize       string `json:"item_size"`
+	ItemReadLink   string `json:"item_read_link"`
+	ItemDownloads  string `json:"item_downloads"`
+	ItemAuthors    string `json:"item_authors"`
+	ItemSeries     string `json:"item_series"`
+	ItemEdition    string `json:"item_edition"`
+	ItemCover      string `json:"item_cover"`
+	ItemRating     string `json:"item_rating"`
+}
+
+// LayoutDriftError tells which required field was not matched,
+// usually it means that site layout has changed.
+type LayoutDriftError struct {
+	Field    string
+	Selector string
+	Version  string
+}
+
+var DefaultSelectors = SelectorProfile{
+	Version:         "2021.07",
+	ListItems:       "//div[@id='main']/ul/li",
+	ListItemTitle:   "//a[1]",
+	ListItemAuthors: "//a[position()>1]",
+	ItemBody:        "//div[@id='main']",
+	ItemTitle:       "//div[@id='main']/h1/text()",
+	ItemGenre:       "//p[@class=\"genre\"]",
+	ItemAnnotation:  "//div[@id='main']/p/text()",
+	ItemSize:        "//span[@style=\"size\"]/text()",
+	ItemReadLink:    "//a[contains(@href, \"read\")]",
+	ItemDownloads:   "//text()[contains(., \"скачать:\")]",
+	ItemAuthors:     "//div[@id='main']/a[contains(@href, '/a/')]",
+	ItemSeries:      "//a[contains(@href, '/s/')][span[@class='h8']]",
+	ItemEdition:     "//div[@id='main']/text()[contains(., 'издание')]",
+	ItemCover:       "//div[@id='main']//img[@title='Cover image']",
+	ItemRating:      "//div[@id='main']//img[contains(@src, 'znak')]",
+}
+
+var selectors = DefaultSelectors
+
+func (e *LayoutDriftError) Error() string {
+	return fmt.Sprintf("layout drift: `%s` is not matched by `%s` (selectors %s)", e.Field, e.Selector, e.Version)
+}
+
+// ActiveSelectors returns profile currently used by parsers.
+func ActiveSelectors() SelectorProfile {
+	return selectors
+}
+
+// UseSelectors validates profile and makes parsers use it.
+func UseSelectors(profile SelectorProfile) error {
+	err := profile.Validate()
+	if err != nil {
+		return err
+	}
+	selectors = profile
+	return nil
+}
+
+// LoadSelectors reads profile from JSON file.
+// Fields missing in file are taken from DefaultSelectors.
+func LoadSelectors(fileName string) (*SelectorProfile, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	profile := DefaultSelectors
+	profile.Version = ""
+	err = json.Unmarshal(data, &profile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse selectors file %s: %w", fileName, err)
+	}
+	if profile.Version == "" {
+		profile.Version = DefaultSelectors.Version + "+" + fileName
+	}
+	return &profile, profile.Validate()
+}
+
+// Validate checks that every expression is a valid XPath, parsers would panic otherwise.
+func (p SelectorProfile) Validate() error {
+	empty := &html.Node{Type: html.DocumentNode}
+	value := reflect.ValueOf(p)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Name == "Version" {
+			continue
+		}
+		expr := value.Field(i).String()
+		if expr == "" {
+			return fmt.Errorf("selector %s is empty", field.Tag.Get("json"))
+		}
+		if _, err := htmlquery.QueryAll(empty, expr); err != nil {
+			return fmt.Errorf("selector %s is invalid: %w", field.Tag.Get("json"), err)
+		}
+	}
+	return nil
+}
+
+func loadEnvSelectors() error {
+	fileName := os.Getenv(SelectorsFileEnvKey)
+	if fileName == "" {
+		return nil
+	}
+	profile, err := LoadSelectors(fileName)
+	if err != nil {
+		return err
+	}
+	return UseSelectors(*profile)
+}
+
+func driftError(field string, selector string) error {
+	return &LayoutDriftError{Field: field, Selector: selector, Version: selectors.Version}
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestLoadSelectors(t *testing.T) {
+	tests := []struct {
+		name          string
+		fileName      string
+		wantVersion   string
+		wantItemTitle string
+		wantErr       bool
+	}{
+		{
+			"Partial override",
+			"partial.json",
+			"test.1",
+			"//h1[@class='title']/text()",
+			false,
+		},
+		{
+			"Invalid xpath",
+			"invalid_xpath.json",
+			"",
+			"",
+			true,
+		},
+		{
+			"Broken json",
+			"broken.json",
+			"",
+			"",
+			true,
+		},
+		{
+			"Missing file",
+			"missing.json",
+			"",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadSelectors(path.Join("testdata/selectors", tt.fileName))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadSelectors() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Version != tt.wantVersion {
+				t.Errorf("LoadSelectors() version = %v, want %v", got.Version, tt.wantVersion)
+			}
+			if got.ItemTitle != tt.wantItemTitle {
+				t.Errorf("LoadSelectors() item title = %v, want %v", got.ItemTitle, tt.wantItemTitle)
+			}
+			if got.ListItems != DefaultSelectors.ListItems {
+				t.Errorf("LoadSelectors() list items = %v, want default %v", got.ListItems, DefaultSelectors.ListItems)
+			}
+		})
+	}
+}
+
+func TestParseInfo_LayoutDrift(t *testing.T) {
+	defer func() {
+		_ = UseSelectors(DefaultSelectors)
+	}()
+	profile, err := LoadSelectors("testdata/selectors/drifted.json")
+	if err != nil {
+		t.Fatalf("LoadSelectors() error = %v", err)
+	}
+	err = UseSelectors(*profile)
+	if err != nil {
+		t.Fatalf("UseSelectors() error = %v", err)
+	}
+
+	stream, err := os.Open("testdata/parser/item.html")
+	if err != nil {
+		t.Fatalf("Cannot open test data file: %v", err)
+	}
+	defer stream.Close()
+	_, err = ParseInfo(stream)
+	var drift *LayoutDriftError
+	if !errors.As(err, &drift) {
+		t.Fatalf("ParseInfo() error = %v, want LayoutDriftError", err)
+	}
+	if drift.Field != "title" || drift.Version != "drifted" {
+		t.Errorf("ParseInfo() drift = %+v, want title field of drifted profile", drift)
+	}
+
+	for _, result := range SelfTest(nil) {
+		if result.Name == "bundled book page" && result.Error == nil {
+			t.Errorf("SelfTest() did not notice drifted book page")
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	results := SelfTest(nil)
+	if len(results) != 2 {
+		t.Fatalf("SelfTest() returned %d results, want 2", len(results))
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Errorf("SelfTest() %s", result.String())
+		}
+	}
+}
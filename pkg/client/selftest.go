@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+)
+
+//go:embed testdata/parser/list.html testdata/parser/item.html
+var bundledPages embed.FS
+
+// Book used for live check, it is shown in README too.
+const SelfTestBookID = "175105"
+
+// SelfTestResult is an outcome of one parser check.
+type SelfTestResult struct {
+	Name  string
+	Error error
+}
+
+func (r *SelfTestResult) String() string {
+	if r.Error != nil {
+		return fmt.Sprintf("FAIL %s: %v", r.Name, r.Error)
+	}
+	return fmt.Sprintf("ok   %s", r.Name)
+}
+
+// SelfTest runs parsers with active selectors against bundled pages.
+// When client is given, live search and book pages are checked as well.
+func SelfTest(live *FlibustaClient) (results []SelfTestResult) {
+	results = append(results,
+		SelfTestResult{"bundled search page", checkSearch("list.html")},
+		SelfTestResult{"bundled book page", checkInfo("item.html")},
+	)
+	if live == nil {
+		return results
+	}
+	searchResult, err := live.Search("Война и мир", ParseSearch)
+	if err == nil {
+		err = validateSearch(searchResult)
+	}
+	results = append(results, SelfTestResult{"live search page", err})
+
+	infoResult, err := live.Info(SelfTestBookID, ParseInfo)
+	if err == nil {
+		err = validateInfo(infoResult)
+	}
+	results = append(results, SelfTestResult{"live book page", err})
+	return results
+}
+
+func bundledPage(name string) ([]byte, error) {
+	return bundledPages.ReadFile(path.Join("testdata/parser", name))
+}
+
+func checkSearch(name string) error {
+	page, err := bundledPage(name)
+	if err != nil {
+		return err
+	}
+	result, err := ParseSearch(bytes.NewReader(page))
+	if err != nil {
+		return err
+	}
+	return validateSearch(result)
+}
+
+func checkInfo(name string) error {
+	page, err := bundledPage(name)
+	if err != nil {
+		return err
+	}
+	result, err := ParseInfo(bytes.NewReader(page))
+	if err != nil {
+		return err
+	}
+	return validateInfo(result)
+}
+
+func validateSearch(result *[]ListItem) error {
+	if result == nil || len(*result) == 0 {
+		return driftError("list items", selectors.ListItems)
+	}
+	for _, item := range *result {
+		if item.ID == "" {
+			return driftError("list item id", selectors.ListItemTitle)
+		}
+		if item.Title == "" {
+			return driftError("list item title", selectors.ListItemTitle)
+		}
+	}
+	return nil
+}
+
+func validateInfo(result *InfoResult) error {
+	if len(result.Formats) == 0 {
+		return driftError("formats", selectors.ItemDownloads)
+	}
+	if result.Size == "" {
+		return driftError("size", selectors.ItemSize)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// epubMimetype is the first entry of epub, such archive is a book itself
+const epubMimetype = "mimetype"
+
+// maxUnpackedSize limits the book extracted from archive, the archive comes from a mirror
+var maxUnpackedSize int64 = 512 << 20
+
+// ArchiveContentError tells that archive has no book or more than one file.
+type ArchiveContentError struct {
+	Files []string
+}
+
+func (e *ArchiveContentError) Error() string {
+	if len(e.Files) == 0 {
+		return "archive has no book file"
+	}
+	return fmt.Sprintf("archive must contain exactly one book, got %d files: %s", len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// IsZipped tells if downloaded file is a zip archive with a book inside.
+// Epub and other zip based formats are not archives of a book.
+func (r *DownloadResult) IsZipped() bool {
+	if !bytes.HasPrefix(r.File, zipMagic) {
+		return false
+	}
+	archive, err := zip.NewReader(bytes.NewReader(r.File), int64(len(r.File)))
+	if err != nil {
+		return false
+	}
+	return len(archive.File) == 0 || archive.File[0].Name != epubMimetype
+}
+
+// Unpack extracts the only book from zipped download.
+// Name of the book is taken from archive entry, or from download name without `.zip`.
+func (r *DownloadResult) Unpack() (*DownloadResult, error) {
+	archive, err := zip.NewReader(bytes.NewReader(r.File), int64(len(r.File)))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open archive: %w", err)
+	}
+	var files []*zip.File
+	var names []string
+	for _, entry := range archive.File {
+		if isJunkEntry(entry) {
+			continue
+		}
+		files = append(files, entry)
+		names = append(names, entry.Name)
+	}
+	if len(files) != 1 {
+		return nil, &ArchiveContentError{Files: names}
+	}
+
+	if files[0].UncompressedSize64 > uint64(maxUnpackedSize) {
+		return nil, unpackSizeError(files[0].Name)
+	}
+	content, err := files[0].Open()
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close()
+	// Size in archive header may lie, reading stops right after the limit
+	data, err := io.ReadAll(io.LimitReader(content, maxUnpackedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot unpack %s: %w", files[0].Name, err)
+	}
+	if int64(len(data)) > maxUnpackedSize {
+		return nil, unpackSizeError(files[0].Name)
+	}
+
+	name := safeFileName(files[0].Name)
+	if name == "" {
+		name = strings.TrimSuffix(r.Name, path.Ext(r.Name))
+	}
+	return &DownloadResult{Name: name, File: data}, nil
+}
+
+func unpackSizeError(name string) error {
+	return fmt.Errorf("cannot unpack %s: book is larger than %d MB", name, maxUnpackedSize>>20)
+}
+
+// isJunkEntry skips directories and metadata added by archivers
+func isJunkEntry(entry *zip.File) bool {
+	if entry.FileInfo().IsDir() {
+		return true
+	}
+	name := entry.Name
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), ".")
+}
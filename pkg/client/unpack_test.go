@@ -0,0 +1,122 @@
+package client
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func zipped(t *testing.T, files ...string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	for _, name := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte("content of " + name))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadResult_IsZipped(t *testing.T) {
+	tests := []struct {
+		name string
+		file []byte
+		want bool
+	}{
+		{"Fb2 zip", zipped(t, "book.fb2"), true},
+		{"Epub", zipped(t, "mimetype", "META-INF/container.xml"), false},
+		{"Fb2", []byte("<?xml version=\"1.0\"?>"), false},
+		{"Broken zip", []byte("PK\x03\x04broken"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &DownloadResult{Name: "book.zip", File: tt.file}
+			if got := result.IsZipped(); got != tt.want {
+				t.Errorf("IsZipped() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadResult_Unpack(t *testing.T) {
+	tests := []struct {
+		name      string
+		download  DownloadResult
+		wantName  string
+		wantFiles []string
+	}{
+		{
+			"Single book",
+			DownloadResult{Name: "Tolstoy_Voyna.fb2.zip", File: zipped(t, "Tolstoy_Voyna-i-mir.175105.fb2")},
+			"Tolstoy_Voyna-i-mir.175105.fb2",
+			nil,
+		},
+		{
+			"Junk entries",
+			DownloadResult{Name: "book.fb2.zip", File: zipped(t, "dir/", "__MACOSX/._book.fb2", ".DS_Store", "dir/book.fb2")},
+			"book.fb2",
+			nil,
+		},
+		{
+			"Unsafe entry name",
+			DownloadResult{Name: "book.fb2.zip", File: zipped(t, "../../etc/book.fb2")},
+			"book.fb2",
+			nil,
+		},
+		{
+			"Several books",
+			DownloadResult{Name: "book.zip", File: zipped(t, "book.fb2", "book.txt")},
+			"",
+			[]string{"book.fb2", "book.txt"},
+		},
+		{
+			"Empty archive",
+			DownloadResult{Name: "book.zip", File: zipped(t)},
+			"",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.download.Unpack()
+			if tt.wantName == "" {
+				var contentErr *ArchiveContentError
+				if !errors.As(err, &contentErr) {
+					t.Fatalf("Unpack() error = %v, want ArchiveContentError", err)
+				}
+				if !reflect.DeepEqual(contentErr.Files, tt.wantFiles) {
+					t.Errorf("Unpack() files = %v, want %v", contentErr.Files, tt.wantFiles)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+			if got.Name != tt.wantName {
+				t.Errorf("Unpack() name = %v, want %v", got.Name, tt.wantName)
+			}
+			if !bytes.HasPrefix(got.File, []byte("content of ")) {
+				t.Errorf("Unpack() file = %q", got.File)
+			}
+		})
+	}
+}
+
+func TestDownloadResult_UnpackTooLarge(t *testing.T) {
+	defer func(size int64) { maxUnpackedSize = size }(maxUnpackedSize)
+	maxUnpackedSize = 10
+
+	download := DownloadResult{Name: "book.fb2.zip", File: zipped(t, "book.fb2")}
+	if _, err := download.Unpack(); err == nil || !strings.Contains(err.Error(), "book is larger than") {
+		t.Errorf("Unpack() error = %v, want size error", err)
+	}
+}
@@ -2,16 +2,26 @@ package client
 
 import (
 	"errors"
-	"log"
+	"fmt"
+	"golang.org/x/net/html/charset"
+	"mime"
 	"net/http"
 	"net/url"
 	"os"
 	"path"
 	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var HostRe = regexp.MustCompile(`(?P<Scheme>https?)?(://)?(?P<Host>[0-8a-z.]+):?(?P<Port>[0-9]+)?/?`)
+var (
+	HostRe          = regexp.MustCompile(`(?P<Scheme>https?)?(://)?(?P<Host>[0-8a-z.]+):?(?P<Port>[0-9]+)?/?`)
+	BookFormatRe    = regexp.MustCompile(`^[a-z0-9]+(\.[a-z0-9]+)?$`)
+	ExtFileNameRe   = regexp.MustCompile(`(?i)filename\*\s*=\s*("?[^;"]*'[^;"]*'[^;"]+"?)`)
+	PlainFileNameRe = regexp.MustCompile(`(?i)filename\s*=\s*"?([^";]+)"?`)
+	wordDecoder     = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+)
 
 type Headers map[string]string
 
@@ -49,12 +59,83 @@ func buildDownloadUrl(bookId string, bookFormat string) *url.URL {
 	return u
 }
 
+// buildLinkUrl points path of the site link to the base URL, mirrors are substituted later.
+func buildLinkUrl(linkPath string) *url.URL {
+	u := getBaseUrl()
+	u.Path = linkPath
+	return u
+}
+
 func buildInfoUrl(bookId string) *url.URL {
 	u := getBaseUrl()
 	u.Path = path.Join(downloadPath, bookId)
 	return u
 }
 
+func buildReadUrl(bookId string) *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(downloadPath, bookId, readPath)
+	return u
+}
+
+func buildCatalogUrl() *url.URL {
+	u := getBaseUrl()
+	u.Path = catalogPath
+	return u
+}
+
+func buildSQLDumpUrl(name string) *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(sqlDumpPath, name)
+	return u
+}
+
+func buildDailyUrl(name string) *url.URL {
+	u := getBaseUrl()
+	u.Path = dailyPath + name
+	return u
+}
+
+// buildCoverUrl accepts only site paths like `/i/29/325729/cover.jpg`,
+// image links of other hosts are not fetched through the proxy.
+func buildCoverUrl(coverPath string) (*url.URL, error) {
+	cover, err := url.Parse(coverPath)
+	if err != nil || cover.IsAbs() || cover.Host != "" || !strings.HasPrefix(cover.Path, "/") {
+		return nil, fmt.Errorf("invalid cover path `%s`", coverPath)
+	}
+	u := getBaseUrl()
+	u.Path = path.Clean(cover.Path)
+	return u, nil
+}
+
+func buildOPDSSearchUrl(searchQuery string) *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(opdsPath, "search")
+	q := u.Query()
+	q.Set("searchType", "books")
+	q.Set("searchTerm", searchQuery)
+	u.RawQuery = q.Encode()
+	return u
+}
+
+func buildOPDSAuthorUrl(authorId string) *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(opdsPath, "author", authorId, "time")
+	return u
+}
+
+func buildOPDSSeriesUrl(seriesId string) *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(opdsPath, "sequencebooks", seriesId)
+	return u
+}
+
+func buildOPDSNewUrl() *url.URL {
+	u := getBaseUrl()
+	u.Path = path.Join(opdsPath, "new", "0", "new")
+	return u
+}
+
 func buildRequest(host string, url *url.URL, headers Headers) (*http.Request, error) {
 	match := HostRe.FindStringSubmatch(host)
 	if match == nil {
@@ -82,21 +163,106 @@ func getHeaders() Headers {
 	return headers
 }
 
+// getFileNameFromHeader returns file name from Content-Disposition header.
+// RFC 5987 `filename*` is preferred over plain `filename`. Names with path
+// traversal or control characters are rejected, directories are dropped.
 func getFileNameFromHeader(h *http.Header) string {
 	disposition := h.Get("Content-Disposition")
 	if disposition == "" {
 		return ""
 	}
-	splitted := strings.Split(disposition, "filename=")
-	if len(splitted) > 1 {
-		return strings.ReplaceAll(splitted[1], "\"", "")
-	} else {
-		return ""
+	name := ""
+	_, params, err := mime.ParseMediaType(disposition)
+	if err == nil {
+		// ParseMediaType puts decoded `filename*` to `filename`, but knows only UTF-8
+		name = params["filename"]
+	}
+	if match := ExtFileNameRe.FindStringSubmatch(disposition); match != nil {
+		if decoded, err := decodeExtValue(match[1]); err == nil {
+			name = decoded
+		}
 	}
+	if name == "" {
+		// Servers often send unquoted names with spaces, which are not valid parameter values
+		if match := PlainFileNameRe.FindStringSubmatch(disposition); match != nil {
+			name = strings.TrimSpace(match[1])
+		}
+	}
+	return safeFileName(decodeFileName(name))
 }
 
-func check(err error) {
+// decodeExtValue decodes RFC 5987 value: charset'language'percent-encoded
+func decodeExtValue(value string) (string, error) {
+	parts := strings.SplitN(strings.Trim(value, "\""), "'", 3)
+	if len(parts) != 3 {
+		return "", errors.New("invalid extended value")
+	}
+	raw, err := url.PathUnescape(parts[2])
 	if err != nil {
-		log.Fatal(err)
+		return "", err
+	}
+	return decodeCharset(parts[0], raw)
+}
+
+// decodeFileName handles names which are encoded, but not in standard way:
+// MIME encoded words and percent encoding in plain `filename`.
+func decodeFileName(name string) string {
+	if strings.HasPrefix(name, "=?") {
+		if decoded, err := wordDecoder.DecodeHeader(name); err == nil {
+			return decoded
+		}
+	}
+	if strings.Contains(name, "%") {
+		if decoded, err := url.PathUnescape(name); err == nil && utf8.ValidString(decoded) {
+			return decoded
+		}
+	}
+	return name
+}
+
+func decodeCharset(label string, raw string) (string, error) {
+	if label == "" || strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "us-ascii") {
+		if !utf8.ValidString(raw) {
+			return "", errors.New("invalid utf-8")
+		}
+		return raw, nil
+	}
+	encoding, _ := charset.Lookup(label)
+	if encoding == nil {
+		return "", fmt.Errorf("unknown charset %s", label)
+	}
+	return encoding.NewDecoder().String(raw)
+}
+
+func safeFileName(name string) string {
+	parts := strings.FieldsFunc(name, isPathSeparator)
+	for _, part := range parts {
+		if part == ".." {
+			return ""
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	name = parts[len(parts)-1]
+	if name == "." || strings.HasPrefix(name, ".") || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return ""
+	}
+	return name
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+// ChooseFormat returns first preferred format that is available.
+func ChooseFormat(available []string, preferred []string) (string, error) {
+	for _, format := range preferred {
+		for _, candidate := range available {
+			if format == candidate {
+				return format, nil
+			}
+		}
 	}
+	return "", fmt.Errorf("none of formats %v is available, book has: %v", preferred, available)
 }
@@ -142,6 +142,111 @@ func Test_getFileNameFromHeader(t *testing.T) {
 			},
 			"filename.jpg",
 		},
+		{
+			"Site attachment",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"Ellison_Nezhit.325729.fb2.zip\""}},
+			},
+			"Ellison_Nezhit.325729.fb2.zip",
+		},
+		{
+			"Trailing parameters",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"book.epub\"; size=12345"}},
+			},
+			"book.epub",
+		},
+		{
+			"Trailing parameters, unquoted",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=book.epub; size=12345"}},
+			},
+			"book.epub",
+		},
+		{
+			"RFC 5987 UTF-8",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename*=UTF-8''%D0%9D%D0%B5%D0%B6%D0%B8%D1%82%D1%8C.fb2"}},
+			},
+			"Нежить.fb2",
+		},
+		{
+			"RFC 5987 is preferred",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"Nezhit.fb2\"; filename*=utf-8'ru'%D0%9D%D0%B5%D0%B6%D0%B8%D1%82%D1%8C.fb2"}},
+			},
+			"Нежить.fb2",
+		},
+		{
+			"RFC 5987 windows-1251",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename*=windows-1251''%CD%E5%E6%E8%F2%FC.fb2"}},
+			},
+			"Нежить.fb2",
+		},
+		{
+			"RFC 5987 unknown charset falls back to filename",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"Nezhit.fb2\"; filename*=x-unknown''%CD%E5.fb2"}},
+			},
+			"Nezhit.fb2",
+		},
+		{
+			"Percent encoded plain filename",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"%D0%9D%D0%B5%D0%B6%D0%B8%D1%82%D1%8C.fb2\""}},
+			},
+			"Нежить.fb2",
+		},
+		{
+			"MIME encoded word",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"=?UTF-8?B?0J3QtdC20LjRgtGMLmZiMg==?=\""}},
+			},
+			"Нежить.fb2",
+		},
+		{
+			"Unquoted with spaces",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=Харлан Эллисон - Нежить.fb2"}},
+			},
+			"Харлан Эллисон - Нежить.fb2",
+		},
+		{
+			"Path traversal",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"../../.bashrc\""}},
+			},
+			"",
+		},
+		{
+			"Encoded path traversal",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename*=UTF-8''..%2F..%2Fbook.fb2"}},
+			},
+			"",
+		},
+		{
+			"Windows path components",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"C:\\\\Books\\\\book.fb2\""}},
+			},
+			"book.fb2",
+		},
+		{
+			"Directory is dropped",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\"books/book.fb2\""}},
+			},
+			"book.fb2",
+		},
+		{
+			"Hidden file",
+			args{
+				http.Header{"Content-Disposition": {"attachment; filename=\".hidden\""}},
+			},
+			"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -347,3 +452,162 @@ func Test_buildInfoUrl(t *testing.T) {
 		})
 	}
 }
+
+func TestChooseFormat(t *testing.T) {
+	type args struct {
+		available []string
+		preferred []string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"Requested is available",
+			args{
+				[]string{"fb2", "epub", "mobi"},
+				[]string{"mobi", "epub"},
+			},
+			"mobi",
+			false,
+		},
+		{
+			"Fallback",
+			args{
+				[]string{"pdf", "djvu"},
+				[]string{"mobi", "epub", "djvu", "pdf"},
+			},
+			"djvu",
+			false,
+		},
+		{
+			"Nothing matches",
+			args{
+				[]string{"pdf"},
+				[]string{"mobi"},
+			},
+			"",
+			true,
+		},
+		{
+			"No formats",
+			args{
+				nil,
+				[]string{"mobi"},
+			},
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ChooseFormat(tt.args.available, tt.args.preferred)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ChooseFormat() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ChooseFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildReadUrl(t *testing.T) {
+	tests := []struct {
+		name   string
+		bookId string
+		want   string
+	}{
+		{
+			"most common",
+			"123",
+			"http://flibusta/b/123/read",
+		},
+		{
+			"empty",
+			"",
+			"http://flibusta/b/read",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildReadUrl(tt.bookId).String(); got != tt.want {
+				t.Errorf("buildReadUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_buildCatalogUrl(t *testing.T) {
+	want := "http://flibusta/catalog/catalog.zip"
+	if got := buildCatalogUrl().String(); got != want {
+		t.Errorf("buildCatalogUrl() = %v, want %v", got, want)
+	}
+}
+
+func Test_buildSQLDumpUrl(t *testing.T) {
+	want := "http://flibusta/sql/lib.libbook.sql.gz"
+	if got := buildSQLDumpUrl("lib.libbook.sql.gz").String(); got != want {
+		t.Errorf("buildSQLDumpUrl() = %v, want %v", got, want)
+	}
+}
+
+func Test_buildDailyUrl(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "http://flibusta/daily/"},
+		{"f.fb2.590001-590290.zip", "http://flibusta/daily/f.fb2.590001-590290.zip"},
+	}
+	for _, tt := range tests {
+		if got := buildDailyUrl(tt.name).String(); got != tt.want {
+			t.Errorf("buildDailyUrl() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func Test_buildCoverUrl(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{"/i/29/325729/cover.jpg", "http://flibusta/i/29/325729/cover.jpg", false},
+		{"/i/../b/1", "http://flibusta/b/1", false},
+		{"item_files/cover.jpg", "", true},
+		{"http://example.com/cover.jpg", "", true},
+		{"//example.com/cover.jpg", "", true},
+	}
+	for _, tt := range tests {
+		got, err := buildCoverUrl(tt.path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("buildCoverUrl(%v) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got.String() != tt.want {
+			t.Errorf("buildCoverUrl(%v) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func Test_buildOPDSUrls(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"search", buildOPDSSearchUrl("Война и мир").String(), "http://flibusta/opds/search?searchTerm=%D0%92%D0%BE%D0%B9%D0%BD%D0%B0+%D0%B8+%D0%BC%D0%B8%D1%80&searchType=books"},
+		{"author", buildOPDSAuthorUrl("123").String(), "http://flibusta/opds/author/123/time"},
+		{"series", buildOPDSSeriesUrl("7").String(), "http://flibusta/opds/sequencebooks/7"},
+		{"new", buildOPDSNewUrl().String(), "http://flibusta/opds/new/0/new"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s url = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const mobiHeaderOffset = 60
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	mobiMagic = []byte("BOOKMOBI")
+	xmlMagic  = []byte("<?xml")
+	fb2Magic  = []byte("<FictionBook")
+	utf8BOM   = []byte("\xef\xbb\xbf")
+)
+
+// WrongPayloadError tells that downloaded file does not look like requested format,
+// usually mirror returned an error page instead of the book.
+type WrongPayloadError struct {
+	Format string
+	Reason string
+}
+
+func (e *WrongPayloadError) Error() string {
+	return fmt.Sprintf("downloaded file is not %s: %s", e.Format, e.Reason)
+}
+
+// VerifyFormat checks file magic bytes against format.
+// Formats without known signature are accepted as is.
+func VerifyFormat(format string, data []byte) error {
+	if len(data) == 0 {
+		return &WrongPayloadError{Format: format, Reason: "file is empty"}
+	}
+	switch format {
+	case Epub, Fb2Zip:
+		if !bytes.HasPrefix(data, zipMagic) {
+			return wrongPayload(format, data, "no ZIP signature")
+		}
+	case Fb2:
+		// Site serves fb2 zipped
+		if bytes.HasPrefix(data, zipMagic) || isFb2XML(data) {
+			return nil
+		}
+		return wrongPayload(format, data, "neither XML nor ZIP")
+	case Mobi:
+		if len(data) < mobiHeaderOffset+len(mobiMagic) ||
+			!bytes.Equal(data[mobiHeaderOffset:mobiHeaderOffset+len(mobiMagic)], mobiMagic) {
+			return wrongPayload(format, data, "no MOBI header")
+		}
+	}
+	return nil
+}
+
+func isFb2XML(data []byte) bool {
+	head := bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	return bytes.HasPrefix(head, xmlMagic) || bytes.HasPrefix(head, fb2Magic)
+}
+
+func wrongPayload(format string, data []byte, reason string) error {
+	if strings.HasPrefix(http.DetectContentType(data), "text/html") {
+		reason = "got HTML page"
+	}
+	return &WrongPayloadError{Format: format, Reason: reason}
+}
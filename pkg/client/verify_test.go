@@ -0,0 +1,46 @@
+package client
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFormat(t *testing.T) {
+	mobi := strings.Repeat("\x00", mobiHeaderOffset) + "BOOKMOBI" + "rest"
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		wantErr string
+	}{
+		{"Epub", Epub, "PK\x03\x04mimetype", ""},
+		{"Epub html", Epub, "<!DOCTYPE html><html><body>503</body></html>", "downloaded file is not epub: got HTML page"},
+		{"Epub empty", Epub, "", "downloaded file is not epub: file is empty"},
+		{"Fb2 zip", Fb2Zip, "PK\x03\x04book.fb2", ""},
+		{"Fb2 zip xml", Fb2Zip, "<?xml version=\"1.0\"?><FictionBook/>", "downloaded file is not fb2.zip: no ZIP signature"},
+		{"Fb2 xml", Fb2, "<?xml version=\"1.0\" encoding=\"utf-8\"?><FictionBook/>", ""},
+		{"Fb2 xml with BOM", Fb2, "\xef\xbb\xbf\n<FictionBook/>", ""},
+		{"Fb2 zipped", Fb2, "PK\x03\x04book.fb2", ""},
+		{"Fb2 text", Fb2, "plain text", "downloaded file is not fb2: neither XML nor ZIP"},
+		{"Mobi", Mobi, mobi, ""},
+		{"Mobi short", Mobi, "BOOKMOBI", "downloaded file is not mobi: no MOBI header"},
+		{"Mobi zip", Mobi, "PK\x03\x04" + mobi[4:mobiHeaderOffset] + "EPUBxxxx", "downloaded file is not mobi: no MOBI header"},
+		{"Unknown format", "djvu", "AT&TFORM", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyFormat(tt.format, []byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("VerifyFormat() error = %v, want nil", err)
+				}
+				return
+			}
+			var payloadErr *WrongPayloadError
+			if !errors.As(err, &payloadErr) || err.Error() != tt.wantErr {
+				t.Errorf("VerifyFormat() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
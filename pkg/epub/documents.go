@@ -0,0 +1,191 @@
+package epub
+
+import (
+	"fmt"
+	"strings"
+)
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+const styleCSS = `body { margin: 0 1em; }
+h1, h2, h3, h4, h5, h6 { text-align: center; page-break-after: avoid; }
+p { margin: 0; text-indent: 1.5em; text-align: justify; }
+p.subtitle { text-align: center; text-indent: 0; font-weight: bold; margin: 1em 0; }
+p.empty-line { height: 1em; }
+p.text-author { text-align: right; font-style: italic; }
+blockquote.epigraph { margin: 1em 0 1em 30%; font-style: italic; }
+blockquote.cite { margin: 1em 2em; }
+div.poem { margin: 1em 2em; }
+div.stanza { margin-bottom: 1em; }
+p.v { text-indent: 0; text-align: left; }
+div.image, div.cover { text-align: center; }
+div.image img, div.cover img { max-width: 100%; }
+a.note { vertical-align: super; font-size: 0.75em; text-decoration: none; }
+`
+
+func xhtmlDocument(lang string, title string, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="%[1]s" lang="%[1]s">
+<head>
+<title>%[2]s</title>
+<link rel="stylesheet" type="text/css" href="%[3]s"/>
+</head>
+<body>
+%[4]s</body>
+</html>
+`, esc(lang), esc(title), styleFile, body)
+}
+
+func (c *converter) packageDocument() string {
+	info := c.doc.TitleInfo
+	meta := &strings.Builder{}
+	fmt.Fprintf(meta, "    <dc:identifier id=\"bookid\">%s</dc:identifier>\n", esc(c.identifier()))
+	fmt.Fprintf(meta, "    <dc:title>%s</dc:title>\n", esc(info.Title))
+	fmt.Fprintf(meta, "    <dc:language>%s</dc:language>\n", esc(c.lang()))
+	for i, author := range info.Authors {
+		fmt.Fprintf(meta, "    <dc:creator id=\"creator%d\">%s</dc:creator>\n", i, esc(author.String()))
+		fmt.Fprintf(meta, "    <meta refines=\"#creator%d\" property=\"role\" scheme=\"marc:relators\">aut</meta>\n", i)
+	}
+	for i, translator := range info.Translators {
+		fmt.Fprintf(meta, "    <dc:contributor id=\"translator%d\">%s</dc:contributor>\n", i, esc(translator.String()))
+		fmt.Fprintf(meta, "    <meta refines=\"#translator%d\" property=\"role\" scheme=\"marc:relators\">trl</meta>\n", i)
+	}
+	for _, genre := range info.Genres {
+		fmt.Fprintf(meta, "    <dc:subject>%s</dc:subject>\n", esc(strings.TrimSpace(genre)))
+	}
+	if annotation := info.Annotation.String(); annotation != "" {
+		fmt.Fprintf(meta, "    <dc:description>%s</dc:description>\n", esc(annotation))
+	}
+	if date := strings.TrimSpace(info.Date); date != "" {
+		fmt.Fprintf(meta, "    <dc:date>%s</dc:date>\n", esc(date))
+	}
+	for i, sequence := range info.Sequences {
+		fmt.Fprintf(meta, "    <meta property=\"belongs-to-collection\" id=\"series%d\">%s</meta>\n", i, esc(sequence.Name))
+		fmt.Fprintf(meta, "    <meta refines=\"#series%d\" property=\"collection-type\">series</meta>\n", i)
+		if sequence.Number != "" {
+			fmt.Fprintf(meta, "    <meta refines=\"#series%d\" property=\"group-position\">%s</meta>\n", i, esc(sequence.Number))
+		}
+		if i == 0 {
+			// Older readers know only calibre series
+			fmt.Fprintf(meta, "    <meta name=\"calibre:series\" content=\"%s\"/>\n", esc(sequence.Name))
+			if sequence.Number != "" {
+				fmt.Fprintf(meta, "    <meta name=\"calibre:series_index\" content=\"%s\"/>\n", esc(sequence.Number))
+			}
+		}
+	}
+	fmt.Fprintf(meta, "    <meta property=\"dcterms:modified\">%s</meta>\n", c.modified.Format("2006-01-02T15:04:05Z"))
+
+	manifest := &strings.Builder{}
+	spine := &strings.Builder{}
+	fmt.Fprintf(manifest, "    <item id=\"nav\" href=\"%s\" media-type=\"application/xhtml+xml\" properties=\"nav\"/>\n", navFile)
+	fmt.Fprintf(manifest, "    <item id=\"ncx\" href=\"%s\" media-type=\"application/x-dtbncx+xml\"/>\n", ncxFile)
+	fmt.Fprintf(manifest, "    <item id=\"style\" href=\"%s\" media-type=\"text/css\"/>\n", styleFile)
+	if cover := c.cover(); cover != nil {
+		fmt.Fprintf(meta, "    <meta name=\"cover\" content=\"%s\"/>\n", cover.id)
+		fmt.Fprintf(manifest, "    <item id=\"cover\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", coverFile)
+		fmt.Fprintf(spine, "    <itemref idref=\"cover\"/>\n")
+	}
+	for i, chapter := range c.chapters {
+		fmt.Fprintf(manifest, "    <item id=\"chapter%d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i, chapter.file)
+		fmt.Fprintf(spine, "    <itemref idref=\"chapter%d\"/>\n", i)
+	}
+	for _, img := range c.order {
+		properties := ""
+		if img == c.cover() {
+			properties = " properties=\"cover-image\""
+		}
+		fmt.Fprintf(manifest, "    <item id=\"%s\" href=\"%s\" media-type=\"%s\"%s/>\n", img.id, esc(img.file), esc(img.data.ContentType), properties)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid" xml:lang="%s">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+%s  </metadata>
+  <manifest>
+%s  </manifest>
+  <spine toc="ncx">
+%s  </spine>
+</package>
+`, esc(c.lang()), meta, manifest, spine)
+}
+
+func (c *converter) navDocument() string {
+	body := &strings.Builder{}
+	body.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n")
+	fmt.Fprintf(body, "<h1>%s</h1>\n", esc(c.doc.TitleInfo.Title))
+	writeNavList(body, c.tocEntries())
+	body.WriteString("</nav>\n")
+	return xhtmlDocument(c.lang(), c.doc.TitleInfo.Title, body.String())
+}
+
+func writeNavList(body *strings.Builder, entries []*tocEntry) {
+	body.WriteString("<ol>\n")
+	for _, entry := range entries {
+		fmt.Fprintf(body, "<li><a href=\"%s\">%s</a>", esc(entry.href), esc(entry.title))
+		if len(entry.children) > 0 {
+			body.WriteString("\n")
+			writeNavList(body, entry.children)
+		}
+		body.WriteString("</li>\n")
+	}
+	body.WriteString("</ol>\n")
+}
+
+func (c *converter) ncxDocument() string {
+	points := &strings.Builder{}
+	order := 0
+	var writePoints func(entries []*tocEntry, indent string)
+	writePoints = func(entries []*tocEntry, indent string) {
+		for _, entry := range entries {
+			order++
+			fmt.Fprintf(points, "%s<navPoint id=\"navpoint%d\" playOrder=\"%d\">\n", indent, order, order)
+			fmt.Fprintf(points, "%s  <navLabel><text>%s</text></navLabel>\n", indent, esc(entry.title))
+			fmt.Fprintf(points, "%s  <content src=\"%s\"/>\n", indent, esc(entry.href))
+			writePoints(entry.children, indent+"  ")
+			fmt.Fprintf(points, "%s</navPoint>\n", indent)
+		}
+	}
+	writePoints(c.tocEntries(), "    ")
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="%s"/>
+  </head>
+  <docTitle><text>%s</text></docTitle>
+  <navMap>
+%s  </navMap>
+</ncx>
+`, esc(c.identifier()), esc(c.doc.TitleInfo.Title), points)
+}
+
+func (c *converter) coverDocument() string {
+	body := fmt.Sprintf("<div class=\"cover\"><img src=\"%s\" alt=\"%s\"/></div>\n", esc(c.cover().file), esc(c.doc.TitleInfo.Title))
+	return xhtmlDocument(c.lang(), c.doc.TitleInfo.Title, body)
+}
+
+func (c *converter) tocEntries() (entries []*tocEntry) {
+	for _, chapter := range c.chapters {
+		entries = append(entries, chapter.toc)
+	}
+	return entries
+}
+
+// href returns link to element by its id in any chapter.
+func (c *converter) href(link string) string {
+	if !strings.HasPrefix(link, "#") {
+		return link
+	}
+	id := strings.TrimPrefix(link, "#")
+	if file, ok := c.files[id]; ok {
+		return file + link
+	}
+	return link
+}
@@ -0,0 +1,293 @@
+// Package epub converts FictionBook documents to EPUB 3 books.
+package epub
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/fb2"
+)
+
+const (
+	contentDir     = "OEBPS"
+	coverFile      = "cover.xhtml"
+	navFile        = "nav.xhtml"
+	ncxFile        = "toc.ncx"
+	styleFile      = "style.css"
+	packageFile    = "content.opf"
+	defaultLang    = "ru"
+	defaultNotes   = "Примечания"
+	maxTocDepth    = 3
+	maxHeadingSize = 6
+)
+
+var unsafeFileNameRe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+type chapter struct {
+	file  string
+	title string
+	nodes []*fb2.Node
+	toc   *tocEntry
+}
+
+type tocEntry struct {
+	title    string
+	href     string
+	children []*tocEntry
+}
+
+type image struct {
+	id   string
+	file string
+	data *fb2.Binary
+}
+
+type converter struct {
+	doc      *fb2.Document
+	chapters []*chapter
+	// Element id to chapter file, to resolve links to notes and between chapters
+	files    map[string]string
+	images   map[string]*image
+	order    []*image
+	sections int
+	modified time.Time
+}
+
+// ConvertFB2 reads FictionBook and writes EPUB to out.
+func ConvertFB2(stream io.Reader, out io.Writer) error {
+	doc, err := fb2.ParseDocument(stream)
+	if err != nil {
+		return err
+	}
+	return Convert(doc, out)
+}
+
+// Convert writes EPUB 3 book with text split by top level sections,
+// notes, images, cover and table of contents.
+func Convert(doc *fb2.Document, out io.Writer) error {
+	if len(doc.Bodies) == 0 {
+		return fmt.Errorf("book has no body")
+	}
+	c := &converter{
+		doc:      doc,
+		files:    map[string]string{},
+		images:   map[string]*image{},
+		modified: time.Now().UTC(),
+	}
+	c.collectImages()
+	c.planChapters()
+	return c.write(out)
+}
+
+func (c *converter) collectImages() {
+	used := map[string]bool{}
+	for i, binary := range c.doc.Binaries {
+		if !strings.HasPrefix(binary.ContentType, "image/") {
+			continue
+		}
+		name := unsafeFileNameRe.ReplaceAllString(binary.ID, "_")
+		if name == "" || used[name] {
+			name = fmt.Sprintf("%d_%s", i, name)
+		}
+		used[name] = true
+		img := &image{id: fmt.Sprintf("img%d", i), file: path.Join("images", name), data: binary}
+		c.images[binary.ID] = img
+		c.order = append(c.order, img)
+	}
+}
+
+// planChapters splits main body by top level sections, other bodies are notes.
+func (c *converter) planChapters() {
+	main := c.doc.Bodies[0]
+	var preamble []*fb2.Node
+	var sections []*fb2.Node
+	for _, child := range main.Children {
+		switch child.Name {
+		case "section":
+			sections = append(sections, child)
+		case "":
+		default:
+			preamble = append(preamble, child)
+		}
+	}
+	if len(preamble) > 0 {
+		title := titleText(main)
+		if title == "" {
+			title = c.doc.TitleInfo.Title
+		}
+		c.addChapter(title, preamble)
+	}
+	for i, section := range sections {
+		title := titleText(section)
+		if title == "" {
+			title = fmt.Sprintf("%d", i+1)
+		}
+		chapter := c.addChapter(title, []*fb2.Node{section})
+		chapter.toc.children = c.sectionsToc(section, chapter.file, 2)
+	}
+	for _, notes := range c.doc.Bodies[1:] {
+		title := titleText(notes)
+		if title == "" {
+			title = defaultNotes
+		}
+		c.addChapter(title, notes.Children)
+	}
+	for _, chapter := range c.chapters {
+		for _, node := range chapter.nodes {
+			c.indexIDs(node, chapter.file)
+		}
+	}
+}
+
+func (c *converter) addChapter(title string, nodes []*fb2.Node) *chapter {
+	file := fmt.Sprintf("text%03d.xhtml", len(c.chapters))
+	chapter := &chapter{
+		file:  file,
+		title: title,
+		nodes: nodes,
+		toc:   &tocEntry{title: title, href: file},
+	}
+	c.chapters = append(c.chapters, chapter)
+	return chapter
+}
+
+// sectionsToc lists nested sections with titles, sections without id get generated one.
+func (c *converter) sectionsToc(section *fb2.Node, file string, depth int) (entries []*tocEntry) {
+	if depth > maxTocDepth {
+		return nil
+	}
+	for _, child := range section.Children {
+		if child.Name != "section" {
+			continue
+		}
+		title := titleText(child)
+		if title == "" {
+			continue
+		}
+		id := child.Get("id")
+		if id == "" {
+			c.sections++
+			id = fmt.Sprintf("section%d", c.sections)
+			child.Attr = append(child.Attr, attr("id", id))
+		}
+		entries = append(entries, &tocEntry{
+			title:    title,
+			href:     file + "#" + id,
+			children: c.sectionsToc(child, file, depth+1),
+		})
+	}
+	return entries
+}
+
+func (c *converter) indexIDs(node *fb2.Node, file string) {
+	if id := node.Get("id"); id != "" {
+		c.files[id] = file
+	}
+	for _, child := range node.Children {
+		c.indexIDs(child, file)
+	}
+}
+
+func (c *converter) write(out io.Writer) error {
+	archive := zip.NewWriter(out)
+	// mimetype must be the first file and must not be compressed
+	mimetype, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(mimetype, "application/epub+zip")
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"META-INF/container.xml", containerXML},
+		{path.Join(contentDir, styleFile), styleCSS},
+		{path.Join(contentDir, packageFile), c.packageDocument()},
+		{path.Join(contentDir, navFile), c.navDocument()},
+		{path.Join(contentDir, ncxFile), c.ncxDocument()},
+	}
+	if c.cover() != nil {
+		files = append(files, struct {
+			name    string
+			content string
+		}{path.Join(contentDir, coverFile), c.coverDocument()})
+	}
+	for _, file := range files {
+		err = writeFile(archive, file.name, []byte(file.content))
+		if err != nil {
+			return err
+		}
+	}
+	for _, chapter := range c.chapters {
+		err = writeFile(archive, path.Join(contentDir, chapter.file), []byte(c.chapterDocument(chapter)))
+		if err != nil {
+			return err
+		}
+	}
+	for _, img := range c.order {
+		err = writeFile(archive, path.Join(contentDir, img.file), img.data.Data)
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func writeFile(archive *zip.Writer, name string, content []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func (c *converter) cover() *image {
+	return c.images[c.doc.TitleInfo.CoverImage.ID()]
+}
+
+func (c *converter) lang() string {
+	if lang := strings.TrimSpace(c.doc.TitleInfo.Lang); lang != "" {
+		return lang
+	}
+	return defaultLang
+}
+
+// identifier is taken from document info, or derived from title and authors
+// so converting the same book twice gives the same identifier.
+func (c *converter) identifier() string {
+	id := strings.TrimSpace(c.doc.DocumentInfo.ID)
+	if id == "" {
+		hash := sha1.New()
+		_, _ = io.WriteString(hash, c.doc.TitleInfo.Title)
+		for _, author := range c.doc.TitleInfo.Authors {
+			_, _ = io.WriteString(hash, author.String())
+		}
+		sum := hash.Sum(nil)
+		return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+	}
+	return "urn:fb2:" + id
+}
+
+func titleText(node *fb2.Node) string {
+	title := node.Child("title")
+	if title == nil {
+		return ""
+	}
+	return title.PlainText()
+}
+
+func esc(text string) string {
+	return html.EscapeString(text)
+}
@@ -0,0 +1,121 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func convertTestBook(t *testing.T) map[string]string {
+	stream, err := os.Open("testdata/book.fb2")
+	if err != nil {
+		t.Fatalf("Cannot open test data file: %v", err)
+	}
+	defer stream.Close()
+	out := &bytes.Buffer{}
+	err = ConvertFB2(stream, out)
+	if err != nil {
+		t.Fatalf("ConvertFB2() error = %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Result is not zip: %v", err)
+	}
+	if first := archive.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("First file is %v with method %v, want stored mimetype", first.Name, first.Method)
+	}
+	files := map[string]string{}
+	for _, file := range archive.File {
+		content, err := file.Open()
+		if err != nil {
+			t.Fatalf("Cannot open %v: %v", file.Name, err)
+		}
+		data, _ := io.ReadAll(content)
+		files[file.Name] = string(data)
+	}
+	return files
+}
+
+func TestConvertFB2(t *testing.T) {
+	files := convertTestBook(t)
+
+	if files["mimetype"] != "application/epub+zip" {
+		t.Errorf("mimetype = %q", files["mimetype"])
+	}
+	for _, name := range []string{
+		"META-INF/container.xml",
+		"OEBPS/content.opf",
+		"OEBPS/nav.xhtml",
+		"OEBPS/toc.ncx",
+		"OEBPS/cover.xhtml",
+		"OEBPS/text000.xhtml",
+		"OEBPS/text003.xhtml",
+		"OEBPS/images/cover.png",
+		"OEBPS/images/pic_1.png",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%v is missing", name)
+		}
+	}
+	if _, ok := files["OEBPS/images/data.bin"]; ok {
+		t.Errorf("Binary which is not an image should be skipped")
+	}
+
+	// Every document should be well formed XML
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".xhtml") && !strings.HasSuffix(name, ".opf") && !strings.HasSuffix(name, ".ncx") {
+			continue
+		}
+		decoder := xml.NewDecoder(strings.NewReader(content))
+		for {
+			_, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%v is not well formed: %v", name, err)
+				break
+			}
+		}
+	}
+}
+
+func TestConvertFB2_Content(t *testing.T) {
+	files := convertTestBook(t)
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"Title", "OEBPS/content.opf", "<dc:title>Нежить &amp; Co</dc:title>"},
+		{"Author", "OEBPS/content.opf", "<dc:creator id=\"creator0\">Джон Адамс</dc:creator>"},
+		{"Identifier", "OEBPS/content.opf", "urn:fb2:test-book-1"},
+		{"Series", "OEBPS/content.opf", "<meta refines=\"#series0\" property=\"group-position\">2</meta>"},
+		{"Cover image", "OEBPS/content.opf", "properties=\"cover-image\""},
+		{"Nested toc", "OEBPS/nav.xhtml", "<a href=\"text002.xhtml#section1\">Глава 1</a>"},
+		{"Notes in toc", "OEBPS/nav.xhtml", "<a href=\"text003.xhtml\">Примечания</a>"},
+		{"Ncx", "OEBPS/toc.ncx", "<content src=\"text002.xhtml#section1\"/>"},
+		{"Body title", "OEBPS/text000.xhtml", "<h1>Джон Адамс<br/>Нежить</h1>"},
+		{"Epigraph", "OEBPS/text000.xhtml", "<blockquote class=\"epigraph\">"},
+		{"Note link", "OEBPS/text001.xhtml", "<a href=\"text003.xhtml#n1\" class=\"note\" epub:type=\"noteref\">[1]</a>"},
+		{"Emphasis", "OEBPS/text001.xhtml", "<em>метафора</em>"},
+		{"Image", "OEBPS/text001.xhtml", "<img src=\"images/pic_1.png\" alt=\"\"/>"},
+		{"Link between chapters", "OEBPS/text002.xhtml", "<a href=\"text001.xhtml#intro\">предисловие</a>"},
+		{"Nested heading", "OEBPS/text002.xhtml", "<h2>Глава 1</h2>"},
+		{"Poem", "OEBPS/text002.xhtml", "<p class=\"v\">Строка один</p>"},
+		{"Escaped text", "OEBPS/text002.xhtml", "&lt;без заголовка&gt;"},
+		{"Note", "OEBPS/text003.xhtml", "<div class=\"section\" id=\"n1\">"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(files[tt.file], tt.want) {
+				t.Errorf("%v does not contain %q:\n%v", tt.file, tt.want, files[tt.file])
+			}
+		})
+	}
+}
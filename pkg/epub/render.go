@@ -0,0 +1,158 @@
+package epub
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/fb2"
+)
+
+// Inline FictionBook elements and their XHTML counterparts
+var inlineTags = map[string]string{
+	"emphasis":      "em",
+	"strong":        "strong",
+	"strikethrough": "del",
+	"sub":           "sub",
+	"sup":           "sup",
+	"code":          "code",
+	"style":         "span",
+}
+
+// Block FictionBook elements rendered as elements with class
+var blockTags = map[string][2]string{
+	"p":           {"p", ""},
+	"subtitle":    {"p", "subtitle"},
+	"text-author": {"p", "text-author"},
+	"v":           {"p", "v"},
+	"date":        {"p", "date"},
+	"epigraph":    {"blockquote", "epigraph"},
+	"cite":        {"blockquote", "cite"},
+	"poem":        {"div", "poem"},
+	"stanza":      {"div", "stanza"},
+	"annotation":  {"div", "annotation"},
+	"table":       {"table", ""},
+	"tr":          {"tr", ""},
+	"th":          {"th", ""},
+	"td":          {"td", ""},
+}
+
+func (c *converter) chapterDocument(chapter *chapter) string {
+	body := &strings.Builder{}
+	for _, node := range chapter.nodes {
+		c.render(body, node, 0, false)
+	}
+	return xhtmlDocument(c.lang(), chapter.title, body.String())
+}
+
+// render writes node as XHTML, depth is a section nesting level used for headings.
+func (c *converter) render(out *strings.Builder, node *fb2.Node, depth int, inline bool) {
+	if node.Name == "" {
+		if inline || strings.TrimSpace(node.Text) != "" {
+			out.WriteString(esc(node.Text))
+		}
+		return
+	}
+	id := idAttr(node)
+	switch node.Name {
+	case "section":
+		fmt.Fprintf(out, "<div class=\"section\"%s>\n", id)
+		c.renderChildren(out, node, depth+1, false)
+		out.WriteString("</div>\n")
+	case "title":
+		level := depth
+		if level < 1 {
+			level = 1
+		}
+		if level > maxHeadingSize {
+			level = maxHeadingSize
+		}
+		fmt.Fprintf(out, "<h%d%s>", level, id)
+		first := true
+		for _, child := range node.Children {
+			if child.Name == "" {
+				continue
+			}
+			if !first {
+				out.WriteString("<br/>")
+			}
+			first = false
+			c.renderChildren(out, child, depth, true)
+		}
+		fmt.Fprintf(out, "</h%d>\n", level)
+	case "empty-line":
+		out.WriteString("<p class=\"empty-line\">&#160;</p>\n")
+	case "image":
+		c.renderImage(out, node, inline)
+	case "a":
+		class := ""
+		if node.Get("type") == "note" {
+			class = " class=\"note\" epub:type=\"noteref\""
+		}
+		fmt.Fprintf(out, "<a href=\"%s\"%s%s>", esc(c.href(node.Get("href"))), class, id)
+		c.renderChildren(out, node, depth, true)
+		out.WriteString("</a>")
+	default:
+		if tag, ok := inlineTags[node.Name]; ok {
+			fmt.Fprintf(out, "<%s%s>", tag, id)
+			c.renderChildren(out, node, depth, true)
+			fmt.Fprintf(out, "</%s>", tag)
+			return
+		}
+		if tag, ok := blockTags[node.Name]; ok {
+			fmt.Fprintf(out, "<%s%s%s%s>", tag[0], classAttr(tag[1]), id, spanAttrs(node))
+			c.renderChildren(out, node, depth, tag[0] == "p" || tag[0] == "th" || tag[0] == "td")
+			fmt.Fprintf(out, "</%s>\n", tag[0])
+			return
+		}
+		// Unknown element, keep its content
+		c.renderChildren(out, node, depth, inline)
+	}
+}
+
+func (c *converter) renderChildren(out *strings.Builder, node *fb2.Node, depth int, inline bool) {
+	for _, child := range node.Children {
+		c.render(out, child, depth, inline)
+	}
+}
+
+func (c *converter) renderImage(out *strings.Builder, node *fb2.Node, inline bool) {
+	img, ok := c.images[strings.TrimPrefix(node.Get("href"), "#")]
+	if !ok {
+		return
+	}
+	tag := fmt.Sprintf("<img src=\"%s\" alt=\"%s\"/>", esc(img.file), esc(node.Get("alt")))
+	if inline {
+		out.WriteString(tag)
+		return
+	}
+	fmt.Fprintf(out, "<div class=\"image\"%s>%s</div>\n", idAttr(node), tag)
+}
+
+func idAttr(node *fb2.Node) string {
+	if id := node.Get("id"); id != "" {
+		return fmt.Sprintf(" id=\"%s\"", esc(id))
+	}
+	return ""
+}
+
+func classAttr(class string) string {
+	if class == "" {
+		return ""
+	}
+	return fmt.Sprintf(" class=\"%s\"", class)
+}
+
+// spanAttrs keeps table cell spans
+func spanAttrs(node *fb2.Node) (attrs string) {
+	for _, name := range []string{"colspan", "rowspan"} {
+		if value := node.Get(name); value != "" {
+			attrs += fmt.Sprintf(" %s=\"%s\"", name, esc(value))
+		}
+	}
+	return attrs
+}
+
+func attr(name string, value string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+}
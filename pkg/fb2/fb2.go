@@ -0,0 +1,332 @@
+// Package fb2 reads metadata of FictionBook 2 files.
+package fb2
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+type Author struct {
+	FirstName  string `xml:"first-name"`
+	MiddleName string `xml:"middle-name"`
+	LastName   string `xml:"last-name"`
+	Nickname   string `xml:"nickname"`
+}
+
+type Sequence struct {
+	Name   string `xml:"name,attr"`
+	Number string `xml:"number,attr"`
+}
+
+type TitleInfo struct {
+	Genres      []string   `xml:"genre"`
+	Authors     []Author   `xml:"author"`
+	Title       string     `xml:"book-title"`
+	Annotation  Text       `xml:"annotation"`
+	Date        string     `xml:"date"`
+	CoverImage  Image      `xml:"coverpage>image"`
+	Lang        string     `xml:"lang"`
+	SrcLang     string     `xml:"src-lang"`
+	Translators []Author   `xml:"translator"`
+	Sequences   []Sequence `xml:"sequence"`
+}
+
+type DocumentInfo struct {
+	Authors     []Author `xml:"author"`
+	ProgramUsed string   `xml:"program-used"`
+	Date        string   `xml:"date"`
+	SrcURLs     []string `xml:"src-url"`
+	ID          string   `xml:"id"`
+	Version     string   `xml:"version"`
+}
+
+// Image is a reference to binary, like `<image l:href="#cover.jpg"/>`
+type Image struct {
+	Href string `xml:"href,attr"`
+}
+
+// Text keeps inner XML of formatted element, like annotation.
+type Text struct {
+	InnerXML string `xml:",innerxml"`
+}
+
+type Binary struct {
+	ID          string
+	ContentType string
+	Data        []byte
+}
+
+// Book is a metadata of FictionBook file, body is skipped.
+type Book struct {
+	TitleInfo    TitleInfo
+	DocumentInfo DocumentInfo
+	Cover        *Binary
+}
+
+// Document is a whole FictionBook: metadata, bodies and binaries.
+// First body is the book text, the others are usually notes.
+type Document struct {
+	Book
+	Bodies   []*Node
+	Binaries []*Binary
+}
+
+type rawBinary struct {
+	ID          string `xml:"id,attr"`
+	ContentType string `xml:"content-type,attr"`
+	Data        string `xml:",chardata"`
+}
+
+var (
+	ErrNoBook = errors.New("no fb2 file in archive")
+	zipMagic  = []byte("PK\x03\x04")
+)
+
+// Open reads book from .fb2 or zipped .fb2 file.
+func Open(fileName string) (*Book, error) {
+	stream, err := OpenStream(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+	return Parse(stream)
+}
+
+// OpenBytes returns reader of .fb2 content, unpacking it when data is zip archive.
+func OpenBytes(data []byte) (io.Reader, error) {
+	if !bytes.HasPrefix(data, zipMagic) {
+		return bytes.NewReader(data), nil
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	entry := findBook(archive.File)
+	if entry == nil {
+		return nil, ErrNoBook
+	}
+	return entry.Open()
+}
+
+// OpenStream opens .fb2 file, or .fb2 file inside zip archive.
+func OpenStream(fileName string) (io.ReadCloser, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(file, magic)
+	_ = file.Close()
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, zipMagic) {
+		return os.Open(fileName)
+	}
+
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return nil, err
+	}
+	entry := findBook(archive.File)
+	if entry == nil {
+		_ = archive.Close()
+		return nil, ErrNoBook
+	}
+	content, err := entry.Open()
+	if err != nil {
+		_ = archive.Close()
+		return nil, err
+	}
+	return &zipEntry{content, archive}, nil
+}
+
+func findBook(files []*zip.File) *zip.File {
+	for _, entry := range files {
+		if strings.EqualFold(path.Ext(entry.Name), ".fb2") {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Parse streams FictionBook XML, body is skipped and only cover binary is kept.
+func Parse(stream io.Reader) (*Book, error) {
+	doc, err := parse(stream, false)
+	if err != nil {
+		return nil, err
+	}
+	return &doc.Book, nil
+}
+
+// ParseDocument reads whole FictionBook with bodies and binaries.
+func ParseDocument(stream io.Reader) (*Document, error) {
+	return parse(stream, true)
+}
+
+func parse(stream io.Reader, full bool) (*Document, error) {
+	decoder := xml.NewDecoder(stream)
+	decoder.CharsetReader = charset.NewReaderLabel
+	doc := &Document{}
+	foundRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case element.Name.Local == "FictionBook":
+			foundRoot = true
+		case element.Name.Local == "title-info":
+			err = decoder.DecodeElement(&doc.TitleInfo, &element)
+		case element.Name.Local == "document-info":
+			err = decoder.DecodeElement(&doc.DocumentInfo, &element)
+		case element.Name.Local == "body" && full:
+			var body *Node
+			body, err = readNode(decoder, element)
+			doc.Bodies = append(doc.Bodies, body)
+		case element.Name.Local == "body":
+			err = decoder.Skip()
+		case element.Name.Local == "binary" && full:
+			err = doc.decodeBinary(decoder, &element)
+		case element.Name.Local == "binary":
+			err = doc.decodeCover(decoder, &element)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !foundRoot {
+		return nil, errors.New("not a FictionBook file")
+	}
+	return doc, nil
+}
+
+func (b *Book) decodeCover(decoder *xml.Decoder, element *xml.StartElement) error {
+	coverID := b.TitleInfo.CoverImage.ID()
+	if coverID == "" || b.Cover != nil || attr(element, "id") != coverID {
+		return decoder.Skip()
+	}
+	binary, err := readBinary(decoder, element)
+	if err != nil {
+		return err
+	}
+	b.Cover = binary
+	return nil
+}
+
+func (d *Document) decodeBinary(decoder *xml.Decoder, element *xml.StartElement) error {
+	binary, err := readBinary(decoder, element)
+	if err != nil {
+		return err
+	}
+	d.Binaries = append(d.Binaries, binary)
+	if binary.ID == d.TitleInfo.CoverImage.ID() {
+		d.Cover = binary
+	}
+	return nil
+}
+
+func readBinary(decoder *xml.Decoder, element *xml.StartElement) (*Binary, error) {
+	raw := rawBinary{}
+	err := decoder.DecodeElement(&raw, element)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeBase64(raw.Data)
+	if err != nil {
+		return nil, fmt.Errorf("binary %s: %w", raw.ID, err)
+	}
+	return &Binary{ID: raw.ID, ContentType: raw.ContentType, Data: data}, nil
+}
+
+// ID is a binary id the image refers to.
+func (i Image) ID() string {
+	return strings.TrimPrefix(i.Href, "#")
+}
+
+func (a Author) String() string {
+	var parts []string
+	for _, part := range []string{a.FirstName, a.MiddleName, a.LastName} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return strings.TrimSpace(a.Nickname)
+	}
+	return strings.Join(parts, " ")
+}
+
+// String returns plain text, paragraphs are separated with new lines.
+func (t Text) String() string {
+	decoder := xml.NewDecoder(strings.NewReader("<text>" + t.InnerXML + "</text>"))
+	decoder.Strict = false
+	buf := &strings.Builder{}
+	var paragraphs []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch token := token.(type) {
+		case xml.CharData:
+			buf.Write(token)
+		case xml.EndElement:
+			if token.Name.Local == "p" || token.Name.Local == "subtitle" {
+				paragraphs = appendParagraph(paragraphs, buf.String())
+				buf.Reset()
+			}
+		}
+	}
+	paragraphs = appendParagraph(paragraphs, buf.String())
+	return strings.Join(paragraphs, "\n")
+}
+
+func appendParagraph(paragraphs []string, text string) []string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return paragraphs
+	}
+	return append(paragraphs, text)
+}
+
+func attr(element *xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func decodeBase64(data string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(data), ""))
+}
+
+type zipEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+func (z *zipEntry) Close() error {
+	_ = z.ReadCloser.Close()
+	return z.archive.Close()
+}
@@ -0,0 +1,154 @@
+package fb2
+
+import (
+	"path"
+	"reflect"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name           string
+		fileName       string
+		wantAnnotation string
+		wantErr        bool
+	}{
+		{
+			"Plain fb2",
+			"book.fb2",
+			"На страницах новой антологии собраны лучшие рассказы о нежити!\nТолько мертвые. И очень голодные…",
+			false,
+		},
+		{
+			"Windows-1251",
+			"book_1251.fb2",
+			"На страницах новой антологии собраны лучшие рассказы о нежити!\nТолько мертвые. И очень голодные...",
+			false,
+		},
+		{
+			"Zipped",
+			"book.fb2.zip",
+			"На страницах новой антологии собраны лучшие рассказы о нежити!\nТолько мертвые. И очень голодные…",
+			false,
+		},
+		{
+			"Zip without book",
+			"empty.zip",
+			"",
+			true,
+		},
+		{
+			"Not a book",
+			"not_a_book.xml",
+			"",
+			true,
+		},
+		{
+			"Missing",
+			"missing.fb2",
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := Open(path.Join("testdata", tt.fileName))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Open() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			info := book.TitleInfo
+			if info.Title != "Нежить" {
+				t.Errorf("Title = %v", info.Title)
+			}
+			if got := info.Annotation.String(); got != tt.wantAnnotation {
+				t.Errorf("Annotation = %q, want %q", got, tt.wantAnnotation)
+			}
+			if !reflect.DeepEqual(info.Genres, []string{"sf_horror", "antology"}) {
+				t.Errorf("Genres = %v", info.Genres)
+			}
+			wantAuthors := []Author{
+				{FirstName: "Джон", MiddleName: "Джозеф", LastName: "Адамс"},
+				{Nickname: "Аноним"},
+			}
+			if !reflect.DeepEqual(info.Authors, wantAuthors) {
+				t.Errorf("Authors = %v, want %v", info.Authors, wantAuthors)
+			}
+			if !reflect.DeepEqual(info.Sequences, []Sequence{{"Антология ужасов", "1"}}) {
+				t.Errorf("Sequences = %v", info.Sequences)
+			}
+			if info.Lang != "ru" || info.SrcLang != "en" || info.Date != "2009" {
+				t.Errorf("Lang = %v, SrcLang = %v, Date = %v", info.Lang, info.SrcLang, info.Date)
+			}
+			if len(info.Translators) != 1 || info.Translators[0].String() != "Елена Королева" {
+				t.Errorf("Translators = %v", info.Translators)
+			}
+			doc := book.DocumentInfo
+			if doc.ProgramUsed != "FictionBook Editor Release 2.6" || doc.Version != "1.0" || doc.ID == "" {
+				t.Errorf("DocumentInfo = %+v", doc)
+			}
+			if book.Cover == nil {
+				t.Fatalf("Cover not found")
+			}
+			if book.Cover.ID != "cover.png" || book.Cover.ContentType != "image/png" || string(book.Cover.Data[1:4]) != "PNG" {
+				t.Errorf("Cover = %v %v %q", book.Cover.ID, book.Cover.ContentType, book.Cover.Data[:4])
+			}
+		})
+	}
+}
+
+func TestAuthor_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		author Author
+		want   string
+	}{
+		{"Full", Author{FirstName: "Лев", MiddleName: "Николаевич", LastName: "Толстой"}, "Лев Николаевич Толстой"},
+		{"Last name", Author{LastName: " Толстой "}, "Толстой"},
+		{"Nickname", Author{Nickname: "Аноним"}, "Аноним"},
+		{"Empty", Author{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.author.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	stream, err := OpenStream("testdata/book.fb2.zip")
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	defer stream.Close()
+	doc, err := ParseDocument(stream)
+	if err != nil {
+		t.Fatalf("ParseDocument() error = %v", err)
+	}
+	if doc.TitleInfo.Title != "Нежить" {
+		t.Errorf("Title = %v", doc.TitleInfo.Title)
+	}
+	if len(doc.Bodies) != 1 {
+		t.Fatalf("Bodies = %v, want 1", len(doc.Bodies))
+	}
+	section := doc.Bodies[0].Child("section")
+	if section == nil {
+		t.Fatalf("Section not found")
+	}
+	if got := section.Child("title").PlainText(); got != "Предисловие" {
+		t.Errorf("Section title = %q", got)
+	}
+	if got := section.PlainText(); got != "Предисловие Зомби — это метафора." {
+		t.Errorf("Section text = %q", got)
+	}
+	if len(doc.Binaries) != 2 {
+		t.Errorf("Binaries = %v, want 2", len(doc.Binaries))
+	}
+	if doc.Cover == nil || doc.Cover.ID != "cover.png" {
+		t.Errorf("Cover = %v", doc.Cover)
+	}
+}
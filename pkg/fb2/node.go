@@ -0,0 +1,77 @@
+package fb2
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Node is an element of book body. Text nodes have empty Name.
+type Node struct {
+	Name     string
+	Attr     []xml.Attr
+	Text     string
+	Children []*Node
+}
+
+// readNode reads element with all children, decoder should be right after start element.
+func readNode(decoder *xml.Decoder, start xml.StartElement) (*Node, error) {
+	node := &Node{Name: start.Name.Local, Attr: start.Attr}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			child, err := readNode(decoder, token)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+		case xml.CharData:
+			node.Children = append(node.Children, &Node{Text: string(token)})
+		case xml.EndElement:
+			return node, nil
+		}
+	}
+}
+
+// Get returns attribute value by local name, so `l:href` is `href`.
+func (n *Node) Get(name string) string {
+	for _, a := range n.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// Child returns first child element with given name.
+func (n *Node) Child(name string) *Node {
+	for _, child := range n.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// PlainText returns text of node and its children with spaces collapsed.
+func (n *Node) PlainText() string {
+	buf := &strings.Builder{}
+	n.collectText(buf)
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+func (n *Node) collectText(buf *strings.Builder) {
+	if n.Name == "" {
+		buf.WriteString(n.Text)
+		return
+	}
+	for _, child := range n.Children {
+		child.collectText(buf)
+		if child.Name == "p" {
+			buf.WriteString(" ")
+		}
+	}
+}
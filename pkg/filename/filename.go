@@ -0,0 +1,123 @@
+// Package filename builds file system safe book file names from templates.
+package filename
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Longest name most file systems accept is 255 bytes,
+// some room is left for extension and collision counter.
+const maxBaseBytes = 255 - 32
+
+var (
+	unsafeCharsRe = regexp.MustCompile(`[/\\:*?"<>|]+`)
+	spacesRe      = regexp.MustCompile(`\s+`)
+	// Book ID in `Ellison_Nezhit.325729` is not an extension
+	numberExtRe = regexp.MustCompile(`^\.[0-9]+$`)
+)
+
+// Fields are available in name templates, like `{{.Author}} - {{.Title}}`
+type Fields struct {
+	ID           string
+	Title        string
+	Author       string
+	Authors      []string
+	Series       string
+	SeriesNumber string
+	Year         string
+	Format       string
+}
+
+// Parse checks template up front, so mistakes are reported before download.
+func Parse(text string) (*template.Template, error) {
+	return template.New("filename").Option("missingkey=error").Parse(text)
+}
+
+// Render executes template and makes result safe to use as file name without extension.
+func Render(tpl *template.Template, fields Fields, translit bool) (string, error) {
+	buf := &bytes.Buffer{}
+	err := tpl.Execute(buf, fields)
+	if err != nil {
+		return "", err
+	}
+	name := Sanitize(buf.String(), translit)
+	if name == "" {
+		return "", fmt.Errorf("template %q gives empty file name", tpl.Root.String())
+	}
+	return truncate(name, maxBaseBytes), nil
+}
+
+// Sanitize replaces characters not allowed in file names, optionally transliterating Cyrillic.
+func Sanitize(name string, translit bool) string {
+	if translit {
+		name = Transliterate(name)
+	}
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = unsafeCharsRe.ReplaceAllString(name, "_")
+	name = spacesRe.ReplaceAllString(name, " ")
+	// Leading dots make hidden files, trailing dots and spaces are dropped by Windows
+	return strings.Trim(name, " .")
+}
+
+// Unique returns path in dir which does not exist yet, adding ` (2)`, ` (3)`... before extension.
+func Unique(dir string, name string, extension string) string {
+	candidate := filepath.Join(dir, name+extension)
+	for i := 2; exists(candidate); i++ {
+		candidate = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", name, i, extension))
+	}
+	return candidate
+}
+
+// Extension returns book file extension, keeping double ones like `.fb2.zip`.
+func Extension(fileName string, format string) string {
+	lower := strings.ToLower(fileName)
+	if strings.HasSuffix(lower, ".zip") {
+		inner := strings.TrimSuffix(lower, ".zip")
+		if ext := filepath.Ext(inner); ext != "" {
+			return ext + ".zip"
+		}
+		return ".zip"
+	}
+	if ext := filepath.Ext(lower); ext != "" && !numberExtRe.MatchString(ext) {
+		return ext
+	}
+	return "." + format
+}
+
+// TrimExtension returns file name without extension returned by Extension, case of the name is ignored.
+func TrimExtension(fileName string, extension string) string {
+	base := len(fileName) - len(extension)
+	if base >= 0 && strings.EqualFold(fileName[base:], extension) {
+		return fileName[:base]
+	}
+	return fileName
+}
+
+func truncate(name string, limit int) string {
+	if len(name) <= limit {
+		return name
+	}
+	name = name[:limit]
+	for !utf8.ValidString(name) {
+		name = name[:len(name)-1]
+	}
+	return strings.Trim(name, " .")
+}
+
+func exists(fileName string) bool {
+	_, err := os.Lstat(fileName)
+	return err == nil
+}
@@ -0,0 +1,187 @@
+package filename
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFields = Fields{
+	ID:           "325729",
+	Title:        "Нежить: лучшее",
+	Author:       "Харлан Эллисон",
+	Authors:      []string{"Харлан Эллисон", "Нил Гейман"},
+	Series:       "Антология ужасов",
+	SeriesNumber: "2",
+	Year:         "2009",
+	Format:       "fb2",
+}
+
+func TestRender(t *testing.T) {
+	type args struct {
+		template string
+		translit bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			"Author and title",
+			args{"{{.Author}} - {{.Title}}", false},
+			"Харлан Эллисон - Нежить_ лучшее",
+			false,
+		},
+		{
+			"Series",
+			args{"{{.Series}} {{.SeriesNumber}} ({{.Year}}) {{.ID}}", false},
+			"Антология ужасов 2 (2009) 325729",
+			false,
+		},
+		{
+			"Transliterated",
+			args{"{{.Author}}_{{.Title}}", true},
+			"Harlan Ellison_Nezhit_ luchshee",
+			false,
+		},
+		{
+			"Path separators are not allowed",
+			args{"{{.Author}}/../{{.Title}}", false},
+			"Харлан Эллисон_.._Нежить_ лучшее",
+			false,
+		},
+		{
+			"Authors",
+			args{"{{range $i, $a := .Authors}}{{if $i}}, {{end}}{{$a}}{{end}}", false},
+			"Харлан Эллисон, Нил Гейман",
+			false,
+		},
+		{
+			"Empty",
+			args{"{{.Series}}...", false},
+			"Антология ужасов",
+			false,
+		},
+		{
+			"Unknown field",
+			args{"{{.Publisher}}", false},
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := Parse(tt.args.template)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got, err := Render(tpl, testFields, tt.args.translit)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	tpl, _ := Parse("{{.Title}}{{.Title}}")
+	long := Fields{Title: strings.Repeat("Очень длинное название ", 10)}
+	got, err := Render(tpl, long, false)
+	if err != nil || len(got) > maxBaseBytes {
+		t.Errorf("Render() of long title = %d bytes, %v", len(got), err)
+	}
+
+	tpl, _ = Parse("{{.Series}}")
+	if _, err = Render(tpl, Fields{}, false); err == nil {
+		t.Errorf("Render() of empty name error = nil, want error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("{{.Title"); err == nil {
+		t.Errorf("Parse() of broken template error = nil, want error")
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Эллисон", "Ellison"},
+		{"Жизнь и Щука", "Zhizn i Schuka"},
+		{"ЁЖИК", "EZhIK"},
+		{"Latin stays", "Latin stays"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Transliterate(tt.text); got != tt.want {
+				t.Errorf("Transliterate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := []struct {
+		fileName string
+		format   string
+		want     string
+	}{
+		{"Ellison_Nezhit.325729.fb2.zip", "fb2", ".fb2.zip"},
+		{"Ellison_Nezhit.325729.mobi", "mobi", ".mobi"},
+		{"Ellison_Nezhit.325729", "epub", ".epub"},
+		{"book.ZIP", "pdf", ".zip"},
+		{"", "djvu", ".djvu"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if got := Extension(tt.fileName, tt.format); got != tt.want {
+				t.Errorf("Extension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimExtension(t *testing.T) {
+	tests := []struct {
+		fileName  string
+		extension string
+		want      string
+	}{
+		{"Ellison_Nezhit.325729.fb2.zip", ".fb2.zip", "Ellison_Nezhit.325729"},
+		{"Book.FB2.ZIP", ".fb2.zip", "Book"},
+		{"Book.Epub", ".epub", "Book"},
+		{"Ellison_Nezhit.325729", ".epub", "Ellison_Nezhit.325729"},
+		{"zip", ".fb2.zip", "zip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			if got := TrimExtension(tt.fileName, tt.extension); got != tt.want {
+				t.Errorf("TrimExtension() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	dir := t.TempDir()
+	first := Unique(dir, "book", ".epub")
+	if first != filepath.Join(dir, "book.epub") {
+		t.Errorf("Unique() = %v", first)
+	}
+	_ = os.WriteFile(first, nil, 0644)
+	second := Unique(dir, "book", ".epub")
+	if second != filepath.Join(dir, "book (2).epub") {
+		t.Errorf("Unique() = %v", second)
+	}
+	_ = os.WriteFile(second, nil, 0644)
+	if third := Unique(dir, "book", ".epub"); third != filepath.Join(dir, "book (3).epub") {
+		t.Errorf("Unique() = %v", third)
+	}
+}
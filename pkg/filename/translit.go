@@ -0,0 +1,31 @@
+package filename
+
+import "strings"
+
+// Close to what the site uses for file names: `Эллисон` becomes `Ellison`
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "h", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "sch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "e", 'ґ': "g", 'ў': "u",
+}
+
+// Transliterate replaces Cyrillic letters with Latin ones, keeping letter case.
+func Transliterate(text string) string {
+	buf := &strings.Builder{}
+	for _, r := range text {
+		lower := []rune(strings.ToLower(string(r)))[0]
+		latin, ok := cyrillicToLatin[lower]
+		switch {
+		case !ok:
+			buf.WriteRune(r)
+		case lower != r && latin != "":
+			// Upper case letter, only the first latin letter is capital: Ж -> Zh
+			buf.WriteString(strings.ToUpper(latin[:1]) + latin[1:])
+		default:
+			buf.WriteString(latin)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,93 @@
+// Package fsutil writes files so that readers never see partial content.
+package fsutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrExists is returned when file exists and overwrite is not allowed.
+var ErrExists = errors.New("file already exists")
+
+// WriteFileAtomic writes data to temporary file in the same directory, syncs it
+// and moves it to fileName. If process dies, only temporary file is left.
+func WriteFileAtomic(fileName string, data []byte, perm fs.FileMode, overwrite bool) error {
+	return WriteAtomic(fileName, perm, overwrite, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// WriteAtomic is like WriteFileAtomic, but content is streamed by write function.
+func WriteAtomic(fileName string, perm fs.FileMode, overwrite bool, write func(w io.Writer) error) (err error) {
+	if !overwrite && exists(fileName) {
+		return fmt.Errorf("%s: %w", fileName, ErrExists)
+	}
+	dir := filepath.Dir(fileName)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(fileName)+".*.part")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err = move(tmp.Name(), fileName, overwrite); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// move renames file, without overwrite it uses hard link which fails when target exists.
+func move(from string, to string, overwrite bool) error {
+	if overwrite {
+		return os.Rename(from, to)
+	}
+	err := os.Link(from, to)
+	if err == nil {
+		return os.Remove(from)
+	}
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("%s: %w", to, ErrExists)
+	}
+	// File system without hard links
+	if exists(to) {
+		return fmt.Errorf("%s: %w", to, ErrExists)
+	}
+	return os.Rename(from, to)
+}
+
+// syncDir makes rename durable, errors are ignored as not every platform supports it.
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	_ = d.Sync()
+	_ = d.Close()
+}
+
+func exists(fileName string) bool {
+	_, err := os.Lstat(fileName)
+	return err == nil
+}
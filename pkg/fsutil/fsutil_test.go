@@ -0,0 +1,58 @@
+package fsutil
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "book.epub")
+
+	err := WriteFileAtomic(fileName, []byte("first"), 0644, false)
+	if err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	err = WriteFileAtomic(fileName, []byte("second"), 0644, false)
+	if !errors.Is(err, ErrExists) {
+		t.Errorf("WriteFileAtomic() without overwrite error = %v, want ErrExists", err)
+	}
+	if data, _ := os.ReadFile(fileName); string(data) != "first" {
+		t.Errorf("File content = %q, want first", data)
+	}
+
+	err = WriteFileAtomic(fileName, []byte("second"), 0600, true)
+	if err != nil {
+		t.Fatalf("WriteFileAtomic() with overwrite error = %v", err)
+	}
+	if data, _ := os.ReadFile(fileName); string(data) != "second" {
+		t.Errorf("File content = %q, want second", data)
+	}
+	if stat, _ := os.Stat(fileName); stat.Mode().Perm() != 0600 {
+		t.Errorf("File mode = %v, want 0600", stat.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Temporary files are left: %v", entries)
+	}
+}
+
+func TestWriteAtomic_Failure(t *testing.T) {
+	dir := t.TempDir()
+	fileName := filepath.Join(dir, "book.epub")
+	err := WriteAtomic(fileName, 0644, false, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errors.New("connection reset")
+	})
+	if err == nil {
+		t.Fatalf("WriteAtomic() error = nil, want error")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Files are left after failure: %v", entries)
+	}
+}
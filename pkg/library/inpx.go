@@ -0,0 +1,158 @@
+package library
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/fb2"
+	"github.com/slivtime/flibusta-cli/pkg/filename"
+)
+
+const (
+	inpFieldSep = "\x04"
+	inpLineEnd  = "\r\n"
+	inpFile     = "library.inp"
+	// Collection types of MyHomeLib
+	inpxFB2Collection   = 0
+	inpxOtherCollection = 1
+)
+
+// INPX fields in order of lines, FOLDER is a directory of the book relative to collection root
+var inpStructure = []string{"AUTHOR", "GENRE", "TITLE", "SERIES", "SERNO", "FILE", "SIZE", "LIBID", "DEL", "EXT", "DATE", "INSNO", "FOLDER", "LANG", "LIBRATE", "KEYWORDS"}
+
+// INPXOptions describe collection for catalog software.
+type INPXOptions struct {
+	Name string
+	// FileName is the name of .inpx file without extension
+	FileName    string
+	Description string
+	// Root is a collection directory, book paths are relative to it
+	Root    string
+	Version time.Time
+}
+
+// ExportINPX writes INPX index of library books whose files exist under root.
+// Authors, genres and language are read from fb2 files when possible,
+// the site metadata is used otherwise.
+func (l *Library) ExportINPX(out io.Writer, options INPXOptions) (books int, err error) {
+	lines := &strings.Builder{}
+	collectionType := inpxFB2Collection
+	for _, entry := range l.List() {
+		if !entry.Exists() {
+			continue
+		}
+		folder, err := filepath.Rel(options.Root, filepath.Dir(entry.Path))
+		if err != nil || strings.HasPrefix(folder, "..") {
+			continue
+		}
+		if !strings.HasPrefix(entry.Format, "fb2") {
+			collectionType = inpxOtherCollection
+		}
+		lines.WriteString(entry.inpLine(filepath.ToSlash(folder)))
+		books++
+	}
+
+	archive := zip.NewWriter(out)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"collection.info", strings.Join([]string{options.Name, options.FileName, fmt.Sprint(collectionType), options.Description}, inpLineEnd) + inpLineEnd},
+		{"version.info", options.Version.Format("20060102") + inpLineEnd},
+		{"structure.info", strings.Join(inpStructure, ";") + ";" + inpLineEnd},
+		{inpFile, lines.String()},
+	}
+	for _, file := range files {
+		w, err := archive.Create(file.name)
+		if err != nil {
+			return books, err
+		}
+		_, err = io.WriteString(w, file.content)
+		if err != nil {
+			return books, err
+		}
+	}
+	return books, archive.Close()
+}
+
+func (e *Entry) inpLine(folder string) string {
+	extension := filename.Extension(e.Path, e.Format)
+	name := strings.TrimSuffix(filepath.Base(e.Path), extension)
+	authors, genres, lang := e.bookMetadata()
+	fields := map[string]string{
+		"AUTHOR": authors,
+		"GENRE":  genres,
+		"TITLE":  cleanField(client.TitleWithoutFormat(e.Info.Title)),
+		"SERIES": cleanField(e.Info.Series),
+		"SERNO":  cleanField(e.Info.SeriesNumber),
+		"FILE":   cleanField(name),
+		"SIZE":   fmt.Sprint(e.Size),
+		"LIBID":  e.ID,
+		"DEL":    "0",
+		"EXT":    strings.TrimPrefix(extension, "."),
+		"DATE":   e.Added.Format("2006-01-02"),
+		"FOLDER": cleanField(folder),
+		"LANG":   lang,
+	}
+	values := make([]string, len(inpStructure))
+	for i, field := range inpStructure {
+		values[i] = fields[field]
+	}
+	return strings.Join(values, inpFieldSep) + inpFieldSep + inpLineEnd
+}
+
+// bookMetadata returns authors as `Last,First,Middle:` list, genre codes as `code:` list and language.
+func (e *Entry) bookMetadata() (authors string, genres string, lang string) {
+	if strings.HasPrefix(e.Format, "fb2") {
+		if book, err := fb2.Open(e.Path); err == nil {
+			for _, author := range book.TitleInfo.Authors {
+				authors += inpAuthor(author.LastName, author.FirstName, author.MiddleName, author.Nickname)
+			}
+			for _, genre := range book.TitleInfo.Genres {
+				if genre = cleanListField(genre); genre != "" {
+					genres += genre + ":"
+				}
+			}
+			lang = cleanField(book.TitleInfo.Lang)
+			if authors != "" {
+				return authors, genres, lang
+			}
+		}
+	}
+	// Site shows full names: first name, middle name, last name
+	for _, name := range e.Info.Authors {
+		words := strings.Fields(name)
+		switch len(words) {
+		case 0:
+		case 1:
+			authors += inpAuthor(words[0], "", "", "")
+		default:
+			authors += inpAuthor(words[len(words)-1], words[0], strings.Join(words[1:len(words)-1], " "), "")
+		}
+	}
+	return authors, genres, lang
+}
+
+func inpAuthor(last string, first string, middle string, nickname string) string {
+	if last == "" && first == "" {
+		last = nickname
+	}
+	return cleanListField(last) + "," + cleanListField(first) + "," + cleanListField(middle) + ":"
+}
+
+// cleanField removes characters used as separators by INPX
+func cleanField(value string) string {
+	value = strings.NewReplacer(inpFieldSep, " ", "\r", " ", "\n", " ").Replace(value)
+	return strings.TrimSpace(value)
+}
+
+// cleanListField also removes separators of author and genre lists, like comma in `Кинг, мл.`
+func cleanListField(value string) string {
+	value = strings.NewReplacer(",", " ", ":", " ").Replace(cleanField(value))
+	return strings.Join(strings.Fields(value), " ")
+}
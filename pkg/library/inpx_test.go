@@ -0,0 +1,110 @@
+package library
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+func TestLibrary_ExportINPX(t *testing.T) {
+	root := t.TempDir()
+	library := &Library{}
+	added := time.Date(2021, 7, 14, 10, 0, 0, 0, time.UTC)
+
+	fb2Data, err := os.ReadFile("../fb2/testdata/book.fb2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []struct {
+		path   string
+		format string
+		data   []byte
+		info   client.InfoResult
+	}{
+		{"horror/Нежить.fb2", "fb2", fb2Data, client.InfoResult{ID: "325729", Title: "Нежить (fb2)", Series: "Антология ужасов", SeriesNumber: "3"}},
+		{"classic/Война и мир.epub", "epub", []byte("PK"), client.InfoResult{ID: "175105", Title: "Война и мир", Authors: []string{"Лев Николаевич Толстой", "Аноним"}}},
+	}
+	for i, file := range files {
+		path := filepath.Join(root, file.path)
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, file.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		entry, _ := NewEntry(file.info, file.format, path, file.data)
+		entry.Added = added.Add(time.Duration(i) * time.Hour)
+		library.Add(entry)
+	}
+	// Missing file and file outside of collection are skipped
+	library.Add(&Entry{ID: "1", Format: "fb2", Path: filepath.Join(root, "missing.fb2"), Added: added})
+	outside, _ := NewEntry(client.InfoResult{ID: "2"}, "mobi", filepath.Join(t.TempDir(), "2.mobi"), nil)
+	_ = os.WriteFile(outside.Path, nil, 0644)
+	library.Add(outside)
+
+	out := &bytes.Buffer{}
+	books, err := library.ExportINPX(out, INPXOptions{Name: "Flibusta", FileName: "flibusta", Description: "Downloaded books", Root: root, Version: added})
+	if err != nil {
+		t.Fatalf("ExportINPX() error = %v", err)
+	}
+	if books != 2 {
+		t.Errorf("ExportINPX() books = %d, want 2", books)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("INPX is not a zip: %v", err)
+	}
+	content := map[string]string{}
+	for _, file := range archive.File {
+		r, _ := file.Open()
+		data, _ := io.ReadAll(r)
+		content[file.Name] = string(data)
+	}
+
+	want := map[string]string{
+		"collection.info": "Flibusta\r\nflibusta\r\n1\r\nDownloaded books\r\n",
+		"version.info":    "20210714\r\n",
+		"structure.info":  "AUTHOR;GENRE;TITLE;SERIES;SERNO;FILE;SIZE;LIBID;DEL;EXT;DATE;INSNO;FOLDER;LANG;LIBRATE;KEYWORDS;\r\n",
+		"library.inp": strings.Join([]string{
+			"Адамс,Джон,Джозеф:Аноним,,:", "sf_horror:antology:", "Нежить", "Антология ужасов", "3", "Нежить",
+			strconv.Itoa(len(fb2Data)), "325729", "0", "fb2", "2021-07-14", "", "horror", "ru", "", "", "\r\n",
+		}, "\x04") + strings.Join([]string{
+			"Толстой,Лев,Николаевич:Аноним,,:", "", "Война и мир", "", "", "Война и мир",
+			"2", "175105", "0", "epub", "2021-07-14", "", "classic", "", "", "", "\r\n",
+		}, "\x04"),
+	}
+	for name, wantContent := range want {
+		if content[name] != wantContent {
+			t.Errorf("%s = %q, want %q", name, content[name], wantContent)
+		}
+	}
+}
+
+func Test_inpAuthor(t *testing.T) {
+	tests := []struct {
+		name   string
+		last   string
+		first  string
+		middle string
+		want   string
+	}{
+		{"full name", "Толстой", "Лев", "Николаевич", "Толстой,Лев,Николаевич:"},
+		{"comma in name", "Кинг, мл.", "Мартин", "Лютер", "Кинг мл.,Мартин,Лютер:"},
+		{"colon in name", "Дюма:отец", "Александр", "", "Дюма отец,Александр,:"},
+		{"field separator", "Адамс\x04", "Джон", "", "Адамс,Джон,:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inpAuthor(tt.last, tt.first, tt.middle, ""); got != tt.want {
+				t.Errorf("inpAuthor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
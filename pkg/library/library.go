@@ -0,0 +1,187 @@
+// Package library keeps the list of downloaded books to query them offline.
+package library
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+)
+
+const (
+	appConfigDir = "flibusta-cli"
+	FileEnvKey   = "FLIBUSTA_LIBRARY_FILE"
+)
+
+// Entry is a book file saved by `get`.
+type Entry struct {
+	ID       string            `json:"id"`
+	Format   string            `json:"format"`
+	Path     string            `json:"path"`
+	Checksum string            `json:"sha256"`
+	Size     int64             `json:"size"`
+	Info     client.InfoResult `json:"info"`
+	Added    time.Time         `json:"added"`
+}
+
+// Library is a collection of downloaded books stored in JSON file.
+type Library struct {
+	fileName string
+	Books    []*Entry
+}
+
+// DefaultFile is located in user config directory.
+func DefaultFile() (string, error) {
+	if fileName := os.Getenv(FileEnvKey); fileName != "" {
+		return fileName, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appConfigDir, "library.json"), nil
+}
+
+// Load reads library file, missing file means empty library.
+func Load(fileName string) (*Library, error) {
+	library := &Library{fileName: fileName}
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return library, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &library.Books)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse library file %s: %w", fileName, err)
+	}
+	return library, nil
+}
+
+// NewEntry describes saved file, path is made absolute so library works from any directory.
+func NewEntry(info client.InfoResult, format string, path string, data []byte) (*Entry, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{
+		ID:       info.ID,
+		Format:   format,
+		Path:     path,
+		Checksum: Checksum(data),
+		Size:     int64(len(data)),
+		Info:     info,
+		Added:    time.Now(),
+	}, nil
+}
+
+// Checksum is hex encoded SHA-256 of file content.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s [%s] %s <%s>", e.ID, e.Format, e.Info.Title, strings.Join(e.Info.Authors, ", "))
+}
+
+// Exists tells if the book file is still on disk.
+func (e *Entry) Exists() bool {
+	_, err := os.Stat(e.Path)
+	return err == nil
+}
+
+// Add records the book, previous entry of the same book and format is replaced.
+func (l *Library) Add(entry *Entry) {
+	l.remove(func(e *Entry) bool { return e.ID == entry.ID && e.Format == entry.Format })
+	l.Books = append(l.Books, entry)
+}
+
+// Get returns all formats of the book.
+func (l *Library) Get(id string) (entries []*Entry) {
+	for _, entry := range l.Books {
+		if entry.ID == id {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Owned returns entry of the book in one of formats whose file still exists.
+func (l *Library) Owned(id string, formats ...string) *Entry {
+	for _, entry := range l.Get(id) {
+		for _, format := range formats {
+			if entry.Format == format && entry.Exists() {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// Remove forgets the book in given format, or in all formats when format is empty.
+func (l *Library) Remove(id string, format string) []*Entry {
+	return l.remove(func(e *Entry) bool { return e.ID == id && (format == "" || e.Format == format) })
+}
+
+func (l *Library) remove(match func(e *Entry) bool) (removed []*Entry) {
+	kept := l.Books[:0]
+	for _, entry := range l.Books {
+		if match(entry) {
+			removed = append(removed, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	l.Books = kept
+	return removed
+}
+
+// List returns books ordered by time they were added.
+func (l *Library) List() []*Entry {
+	entries := append([]*Entry{}, l.Books...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Added.Before(entries[j].Added) })
+	return entries
+}
+
+// Search returns books whose ID, title, authors or series contain every word of query.
+func (l *Library) Search(query string) (entries []*Entry) {
+	words := strings.Fields(strings.ToLower(query))
+	for _, entry := range l.List() {
+		text := strings.ToLower(strings.Join(append([]string{entry.ID, entry.Info.Title, entry.Info.Series}, entry.Info.Authors...), " "))
+		found := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				found = false
+				break
+			}
+		}
+		if found {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (l *Library) Save() error {
+	data, err := json.MarshalIndent(l.Books, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(l.fileName), 0755)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(l.fileName, data, 0644, true)
+}
@@ -0,0 +1,119 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+var testInfo = client.InfoResult{
+	ID:      "175105",
+	Title:   "Война и мир",
+	Authors: []string{"Лев Николаевич Толстой"},
+	Series:  "Роман-эпопея",
+	Formats: []string{"fb2", "epub", "mobi"},
+}
+
+func addBook(t *testing.T, library *Library, info client.InfoResult, format string) *Entry {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), info.ID+"."+format)
+	data := []byte("book " + info.ID)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := NewEntry(info, format, path, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	library.Add(entry)
+	return entry
+}
+
+func TestLibrary_SaveLoad(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config", "library.json")
+	library, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Load() missing file error = %v", err)
+	}
+	entry := addBook(t, library, testInfo, "epub")
+	if err = library.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(loaded.Books) != 1 {
+		t.Fatalf("Load() books = %v, want 1", len(loaded.Books))
+	}
+	got := loaded.Books[0]
+	if got.Checksum != Checksum([]byte("book 175105")) || got.Size != 11 || got.Path != entry.Path {
+		t.Errorf("Load() entry = %+v", got)
+	}
+	if !reflect.DeepEqual(got.Info, testInfo) {
+		t.Errorf("Load() info = %+v, want %+v", got.Info, testInfo)
+	}
+}
+
+func TestLibrary_Owned(t *testing.T) {
+	library := &Library{}
+	entry := addBook(t, library, testInfo, "epub")
+	addBook(t, library, testInfo, "epub")
+
+	if len(library.Books) != 1 {
+		t.Errorf("Add() of the same book and format must replace entry, got %d", len(library.Books))
+	}
+	if library.Owned("175105", "mobi") != nil {
+		t.Errorf("Owned() in mobi, want nil")
+	}
+	if library.Owned("175105", "mobi", "epub") == nil {
+		t.Errorf("Owned() in epub = nil")
+	}
+	_ = os.Remove(library.Books[0].Path)
+	if library.Owned("175105", "epub") != nil {
+		t.Errorf("Owned() with deleted file %s, want nil", entry.Path)
+	}
+}
+
+func TestLibrary_SearchRemove(t *testing.T) {
+	library := &Library{}
+	addBook(t, library, testInfo, "epub")
+	addBook(t, library, testInfo, "fb2")
+	addBook(t, library, client.InfoResult{ID: "325729", Title: "Нежить", Authors: []string{"Елена Блашкович"}}, "mobi")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"война", []string{"175105", "175105"}},
+		{"толстой мир", []string{"175105", "175105"}},
+		{"эпопея", []string{"175105", "175105"}},
+		{"325729", []string{"325729"}},
+		{"толстой нежить", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			var got []string
+			for _, entry := range library.Search(tt.query) {
+				got = append(got, entry.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if removed := library.Remove("175105", "fb2"); len(removed) != 1 {
+		t.Errorf("Remove() with format = %v, want 1 entry", removed)
+	}
+	if removed := library.Remove("175105", ""); len(removed) != 1 {
+		t.Errorf("Remove() all formats = %v, want 1 entry", removed)
+	}
+	if len(library.List()) != 1 {
+		t.Errorf("List() = %v, want 1 entry", library.List())
+	}
+}
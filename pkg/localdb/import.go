@@ -0,0 +1,232 @@
+package localdb
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/slivtime/flibusta-cli/pkg/sqldump"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Rows written in one transaction, dumps have millions of rows
+const importBatchSize = 20000
+
+// DumpFiles are dumps published by the site which are imported.
+var DumpFiles = []string{
+	"lib.libbook.sql.gz",
+	"lib.libavtorname.sql.gz",
+	"lib.libavtor.sql.gz",
+	"lib.libtranslator.sql.gz",
+	"lib.libseqname.sql.gz",
+	"lib.libseq.sql.gz",
+	"lib.libgenrelist.sql.gz",
+	"lib.libgenre.sql.gz",
+}
+
+// bucketFunc returns bucket the import writes to instead of the named one
+type bucketFunc func(name []byte) *bolt.Bucket
+
+// table knows buckets filled from dump table and how to store its row
+type table struct {
+	buckets [][]byte
+	put     func(bucket bucketFunc, row *sqldump.Row) error
+}
+
+var tables = map[string]table{
+	"libbook": {[][]byte{booksBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		size, _ := strconv.ParseInt(row.Get("FileSize"), 10, 64)
+		year := row.Get("Year")
+		if year == "0" {
+			year = ""
+		}
+		return putJSON(bucket, booksBucket, row.Get("BookId"), &Book{
+			ID:       row.Get("BookId"),
+			Title:    row.Get("Title"),
+			Lang:     row.Get("Lang"),
+			FileType: row.Get("FileType"),
+			Year:     year,
+			Size:     size,
+			Deleted:  row.Get("Deleted") == "1",
+			Added:    row.Get("Time"),
+		})
+	}},
+	"libavtorname": {[][]byte{authorsBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return putJSON(bucket, authorsBucket, row.Get("AvtorId"), &Author{
+			ID:         row.Get("AvtorId"),
+			FirstName:  row.Get("FirstName"),
+			MiddleName: row.Get("MiddleName"),
+			LastName:   row.Get("LastName"),
+			NickName:   row.Get("NickName"),
+		})
+	}},
+	"libavtor": {[][]byte{bookAuthorsBucket, authorBooksBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return putRelation(bucket, bookAuthorsBucket, authorBooksBucket, row.Get("BookId"), row.Get("AvtorId"), row.Get("Pos"))
+	}},
+	"libtranslator": {[][]byte{bookTranslatorsBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return put(bucket, bookTranslatorsBucket, relationKey(row.Get("BookId"), row.Get("TranslatorId")), row.Get("Pos"))
+	}},
+	"libseqname": {[][]byte{seriesBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return putJSON(bucket, seriesBucket, row.Get("SeqId"), &Series{ID: row.Get("SeqId"), Name: row.Get("SeqName")})
+	}},
+	"libseq": {[][]byte{bookSeriesBucket, seriesBooksBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		numb := row.Get("SeqNumb")
+		if numb == "0" {
+			numb = ""
+		}
+		return putRelation(bucket, bookSeriesBucket, seriesBooksBucket, row.Get("BookId"), row.Get("SeqId"), numb)
+	}},
+	"libgenrelist": {[][]byte{genresBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return putJSON(bucket, genresBucket, row.Get("GenreId"), &Genre{
+			ID:          row.Get("GenreId"),
+			Code:        row.Get("GenreCode"),
+			Description: row.Get("GenreDesc"),
+		})
+	}},
+	"libgenre": {[][]byte{bookGenresBucket}, func(bucket bucketFunc, row *sqldump.Row) error {
+		return put(bucket, bookGenresBucket, relationKey(row.Get("BookId"), row.Get("GenreId")), "")
+	}},
+}
+
+// ImportStats counts imported rows per table, rows of unknown tables are skipped.
+type ImportStats struct {
+	Rows    map[string]int
+	Skipped map[string]int
+}
+
+// Import loads plain or gzipped dump. Table is replaced completely,
+// so a dump can be imported again when the site publishes a new one.
+// Rows are written to temporary buckets which replace the current ones when
+// the whole dump is read, a failed import keeps the previous data.
+func (db *DB) Import(stream io.Reader) (*ImportStats, error) {
+	reader, err := sqldump.NewReader(stream)
+	if err != nil {
+		return nil, err
+	}
+	stats := &ImportStats{Rows: map[string]int{}, Skipped: map[string]int{}}
+	staged := map[string][]byte{}
+	tx, err := db.bolt.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	bucket := func(name []byte) *bolt.Bucket {
+		return tx.Bucket(staged[string(name)])
+	}
+
+	batch := 0
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stats, db.dropStaged(tx, staged, err)
+		}
+		t, ok := tables[row.Table]
+		if !ok {
+			stats.Skipped[row.Table]++
+			continue
+		}
+		if _, seen := stats.Rows[row.Table]; !seen {
+			if err = stageBuckets(tx, t.buckets, staged); err != nil {
+				return stats, db.dropStaged(tx, staged, err)
+			}
+		}
+		if err = t.put(bucket, row); err != nil {
+			return stats, db.dropStaged(tx, staged, err)
+		}
+		stats.Rows[row.Table]++
+
+		batch++
+		if batch == importBatchSize {
+			if err = tx.Commit(); err != nil {
+				return stats, db.dropStaged(tx, staged, err)
+			}
+			next, err := db.bolt.Begin(true)
+			if err != nil {
+				return stats, db.dropStaged(tx, staged, err)
+			}
+			tx, batch = next, 0
+		}
+	}
+	if err = swapBuckets(tx, staged); err != nil {
+		return stats, db.dropStaged(tx, staged, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return stats, db.dropStaged(tx, staged, err)
+	}
+	return stats, nil
+}
+
+// stageBuckets creates temporary buckets named after the bucket and a sequence number
+func stageBuckets(tx *bolt.Tx, buckets [][]byte, staged map[string][]byte) error {
+	meta, err := tx.CreateBucketIfNotExists(metaBucket)
+	if err != nil {
+		return err
+	}
+	for _, name := range buckets {
+		seq, err := meta.NextSequence()
+		if err != nil {
+			return err
+		}
+		physical := []byte(string(name) + "." + strconv.FormatUint(seq, 10))
+		if _, err = tx.CreateBucket(physical); err != nil {
+			return err
+		}
+		staged[string(name)] = physical
+	}
+	return nil
+}
+
+// swapBuckets points buckets to the staged ones and deletes previous data
+func swapBuckets(tx *bolt.Tx, staged map[string][]byte) error {
+	meta := tx.Bucket(metaBucket)
+	for name, physical := range staged {
+		if previous := physicalName(tx, []byte(name)); tx.Bucket(previous) != nil {
+			if err := tx.DeleteBucket(previous); err != nil {
+				return err
+			}
+		}
+		if err := meta.Put([]byte(name), physical); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropStaged deletes temporary buckets left by failed import, batches of them could be committed already
+func (db *DB) dropStaged(tx *bolt.Tx, staged map[string][]byte, err error) error {
+	_ = tx.Rollback()
+	_ = db.bolt.Update(func(tx *bolt.Tx) error {
+		for _, physical := range staged {
+			if tx.Bucket(physical) != nil {
+				_ = tx.DeleteBucket(physical)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func putJSON(bucket bucketFunc, name []byte, id string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket(name).Put([]byte(key(id)), data)
+}
+
+// putRelation stores relation in both directions
+func putRelation(bucket bucketFunc, forward []byte, backward []byte, from string, to string, value string) error {
+	if err := put(bucket, forward, relationKey(from, to), value); err != nil {
+		return err
+	}
+	return put(bucket, backward, relationKey(to, from), value)
+}
+
+func put(bucket bucketFunc, name []byte, key []byte, value string) error {
+	return bucket(name).Put(key, []byte(value))
+}
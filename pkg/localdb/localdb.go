@@ -0,0 +1,393 @@
+// Package localdb keeps library metadata imported from the site's SQL dumps
+// in an embedded database, so relations between books, authors and series can be queried offline.
+package localdb
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	appCacheDir = "flibusta-cli"
+	FileEnvKey  = "FLIBUSTA_DB_FILE"
+	keySep      = "/"
+)
+
+// Buckets, relation buckets are keyed by `<id>/<id>` to scan them by prefix
+var (
+	booksBucket           = []byte("books")
+	authorsBucket         = []byte("authors")
+	seriesBucket          = []byte("series")
+	genresBucket          = []byte("genres")
+	bookAuthorsBucket     = []byte("book_authors")
+	authorBooksBucket     = []byte("author_books")
+	bookTranslatorsBucket = []byte("book_translators")
+	bookSeriesBucket      = []byte("book_series")
+	seriesBooksBucket     = []byte("series_books")
+	bookGenresBucket      = []byte("book_genres")
+
+	// metaBucket maps bucket names to buckets holding the data, so import can replace them at once
+	metaBucket = []byte("meta")
+)
+
+var ErrNotFound = errors.New("not found in local database")
+
+type Book struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Lang     string `json:"lang,omitempty"`
+	FileType string `json:"file_type,omitempty"`
+	Year     string `json:"year,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Deleted  bool   `json:"deleted,omitempty"`
+	Added    string `json:"added,omitempty"`
+}
+
+type Author struct {
+	ID         string `json:"id"`
+	FirstName  string `json:"first_name,omitempty"`
+	MiddleName string `json:"middle_name,omitempty"`
+	LastName   string `json:"last_name,omitempty"`
+	NickName   string `json:"nick_name,omitempty"`
+}
+
+type Series struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Genre struct {
+	ID          string `json:"id"`
+	Code        string `json:"code"`
+	Description string `json:"description,omitempty"`
+}
+
+// SeriesBook is a book with its number in series.
+type SeriesBook struct {
+	Book
+	Number string `json:"number,omitempty"`
+}
+
+// BookSeries is a series with number of the book in it.
+type BookSeries struct {
+	Series
+	Number string `json:"number,omitempty"`
+}
+
+// BookDetails is a book with all its relations.
+type BookDetails struct {
+	Book
+	Authors     []*Author     `json:"authors,omitempty"`
+	Translators []*Author     `json:"translators,omitempty"`
+	Series      []*BookSeries `json:"series,omitempty"`
+	Genres      []*Genre      `json:"genres,omitempty"`
+}
+
+type DB struct {
+	bolt *bolt.DB
+}
+
+// DefaultFile is located in user cache directory, database can be imported again anytime.
+func DefaultFile() (string, error) {
+	if fileName := os.Getenv(FileEnvKey); fileName != "" {
+		return fileName, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appCacheDir, "flibusta.db"), nil
+}
+
+func Open(fileName string) (*DB, error) {
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(fileName, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &DB{bolt: db}, nil
+}
+
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+func (a *Author) String() string {
+	name := strings.Join(strings.Fields(strings.Join([]string{a.FirstName, a.MiddleName, a.LastName}, " ")), " ")
+	if name == "" {
+		return a.NickName
+	}
+	return name
+}
+
+// Book returns book with authors, translators, series and genres.
+func (db *DB) Book(id string) (details *BookDetails, err error) {
+	err = db.bolt.View(func(tx *bolt.Tx) error {
+		book := &Book{}
+		if err := get(tx, booksBucket, id, book); err != nil {
+			return err
+		}
+		details = &BookDetails{Book: *book}
+		for _, rel := range related(tx, bookAuthorsBucket, id) {
+			details.Authors = append(details.Authors, getAuthor(tx, rel.id))
+		}
+		for _, rel := range related(tx, bookTranslatorsBucket, id) {
+			details.Translators = append(details.Translators, getAuthor(tx, rel.id))
+		}
+		for _, rel := range related(tx, bookSeriesBucket, id) {
+			series := Series{ID: rel.id}
+			_ = get(tx, seriesBucket, rel.id, &series)
+			details.Series = append(details.Series, &BookSeries{Series: series, Number: rel.value})
+		}
+		for _, rel := range related(tx, bookGenresBucket, id) {
+			genre := &Genre{ID: rel.id}
+			_ = get(tx, genresBucket, rel.id, genre)
+			details.Genres = append(details.Genres, genre)
+		}
+		sortByPosition(details.Authors, related(tx, bookAuthorsBucket, id))
+		return nil
+	})
+	return details, err
+}
+
+// Author returns author with books, deleted books are skipped.
+func (db *DB) Author(id string) (author *Author, books []*Book, err error) {
+	err = db.bolt.View(func(tx *bolt.Tx) error {
+		author = &Author{}
+		if err := get(tx, authorsBucket, id, author); err != nil {
+			return err
+		}
+		for _, rel := range related(tx, authorBooksBucket, id) {
+			book := &Book{}
+			if get(tx, booksBucket, rel.id, book) == nil && !book.Deleted {
+				books = append(books, book)
+			}
+		}
+		return nil
+	})
+	return author, books, err
+}
+
+// Series returns series with books ordered by number in series.
+func (db *DB) Series(id string) (series *Series, books []*SeriesBook, err error) {
+	err = db.bolt.View(func(tx *bolt.Tx) error {
+		series = &Series{}
+		if err := get(tx, seriesBucket, id, series); err != nil {
+			return err
+		}
+		for _, rel := range related(tx, seriesBooksBucket, id) {
+			book := &Book{}
+			if get(tx, booksBucket, rel.id, book) == nil && !book.Deleted {
+				books = append(books, &SeriesBook{Book: *book, Number: rel.value})
+			}
+		}
+		return nil
+	})
+	sort.SliceStable(books, func(i, j int) bool { return number(books[i].Number) < number(books[j].Number) })
+	return series, books, err
+}
+
+// SearchBooks returns not deleted books whose title contains every word of query.
+func (db *DB) SearchBooks(query string, limit int) (books []*Book, err error) {
+	words := strings.Fields(normalize(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	err = db.search(booksBucket, limit, func(data []byte) bool {
+		book := &Book{}
+		if json.Unmarshal(data, book) != nil || book.Deleted || !matches(book.Title, words) {
+			return false
+		}
+		books = append(books, book)
+		return true
+	})
+	return books, err
+}
+
+// SearchAuthors returns authors whose name contains every word of query.
+func (db *DB) SearchAuthors(query string, limit int) (authors []*Author, err error) {
+	words := strings.Fields(normalize(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	err = db.search(authorsBucket, limit, func(data []byte) bool {
+		author := &Author{}
+		if json.Unmarshal(data, author) != nil || !matches(author.String()+" "+author.NickName, words) {
+			return false
+		}
+		authors = append(authors, author)
+		return true
+	})
+	return authors, err
+}
+
+// SearchSeries returns series whose name contains every word of query.
+func (db *DB) SearchSeries(query string, limit int) (series []*Series, err error) {
+	words := strings.Fields(normalize(query))
+	if len(words) == 0 {
+		return nil, nil
+	}
+	err = db.search(seriesBucket, limit, func(data []byte) bool {
+		item := &Series{}
+		if json.Unmarshal(data, item) != nil || !matches(item.Name, words) {
+			return false
+		}
+		series = append(series, item)
+		return true
+	})
+	return series, err
+}
+
+// Stats returns number of records in every imported bucket.
+func (db *DB) Stats() (stats map[string]int, err error) {
+	stats = map[string]int{}
+	err = db.bolt.View(func(tx *bolt.Tx) error {
+		for _, t := range tables {
+			for _, name := range t.buckets {
+				if b := bucket(tx, name); b != nil {
+					stats[string(name)] = b.Stats().KeyN
+				}
+			}
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// search scans bucket, collect returns true when record matched and was added to result.
+func (db *DB) search(name []byte, limit int, collect func(data []byte) bool) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		b := bucket(tx, name)
+		if b == nil {
+			return nil
+		}
+		found := 0
+		cursor := b.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if !collect(v) {
+				continue
+			}
+			found++
+			if limit > 0 && found == limit {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+type relation struct {
+	id    string
+	value string
+}
+
+// related lists ids linked to id in relation bucket, ordered by id.
+func related(tx *bolt.Tx, name []byte, id string) (relations []relation) {
+	b := bucket(tx, name)
+	if b == nil {
+		return nil
+	}
+	prefix := []byte(key(id) + keySep)
+	cursor := b.Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, v = cursor.Next() {
+		relations = append(relations, relation{id: unkey(string(k[len(prefix):])), value: string(v)})
+	}
+	return relations
+}
+
+// sortByPosition orders authors by position in book, it is stored as relation value.
+func sortByPosition(authors []*Author, relations []relation) {
+	position := map[string]int{}
+	for _, rel := range relations {
+		position[rel.id] = number(rel.value)
+	}
+	sort.SliceStable(authors, func(i, j int) bool { return position[authors[i].ID] < position[authors[j].ID] })
+}
+
+func getAuthor(tx *bolt.Tx, id string) *Author {
+	author := &Author{ID: id}
+	_ = get(tx, authorsBucket, id, author)
+	return author
+}
+
+func get(tx *bolt.Tx, name []byte, id string, value interface{}) error {
+	b := bucket(tx, name)
+	if b == nil {
+		return ErrNotFound
+	}
+	data := b.Get([]byte(key(id)))
+	if data == nil {
+		return ErrNotFound
+	}
+	return json.Unmarshal(data, value)
+}
+
+// bucket returns bucket holding the data of the named table, it is nil when nothing has been imported yet
+func bucket(tx *bolt.Tx, name []byte) *bolt.Bucket {
+	return tx.Bucket(physicalName(tx, name))
+}
+
+// physicalName is the bucket meta bucket points to, before the first import there is none and the name itself is used
+func physicalName(tx *bolt.Tx, name []byte) []byte {
+	if meta := tx.Bucket(metaBucket); meta != nil {
+		if physical := meta.Get(name); physical != nil {
+			return physical
+		}
+	}
+	return name
+}
+
+// key pads numeric ids, so records are ordered by id
+func key(id string) string {
+	if _, err := strconv.ParseUint(id, 10, 64); err == nil && len(id) < 10 {
+		return strings.Repeat("0", 10-len(id)) + id
+	}
+	return id
+}
+
+func unkey(k string) string {
+	if _, err := strconv.ParseUint(k, 10, 64); err == nil {
+		if id := strings.TrimLeft(k, "0"); id != "" {
+			return id
+		}
+		return "0"
+	}
+	return k
+}
+
+func relationKey(from string, to string) []byte {
+	return []byte(key(from) + keySep + key(to))
+}
+
+func number(text string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		return int(^uint(0) >> 1)
+	}
+	return n
+}
+
+func matches(text string, words []string) bool {
+	text = normalize(text)
+	for _, word := range words {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func normalize(text string) string {
+	return strings.ReplaceAll(strings.ToLower(text), "ё", "е")
+}
@@ -0,0 +1,160 @@
+package localdb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func importTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "cache", "flibusta.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	dump, err := os.Open("testdata/lib.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dump.Close()
+	stats, err := db.Import(dump)
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	wantRows := map[string]int{"libbook": 4, "libavtorname": 4, "libavtor": 6, "libtranslator": 1, "libseqname": 1, "libseq": 2, "libgenrelist": 2, "libgenre": 3}
+	if !reflect.DeepEqual(stats.Rows, wantRows) || stats.Skipped["librate"] != 2 {
+		t.Errorf("Import() stats = %+v", stats)
+	}
+	return db
+}
+
+func TestDB_Book(t *testing.T) {
+	db := importTestDB(t)
+	book, err := db.Book("12345")
+	if err != nil {
+		t.Fatalf("Book() error = %v", err)
+	}
+	if book.Title != "Двенадцать стульев" || book.Year != "1928" || book.Size != 1000 {
+		t.Errorf("Book() = %+v", book.Book)
+	}
+	var authors []string
+	for _, author := range book.Authors {
+		authors = append(authors, author.String())
+	}
+	if want := []string{"Илья Арнольдович Ильф", "Евгений Петрович Петров"}; !reflect.DeepEqual(authors, want) {
+		t.Errorf("Book() authors = %v, want %v", authors, want)
+	}
+	if len(book.Series) != 1 || book.Series[0].Name != "Остап Бендер" || book.Series[0].Number != "1" {
+		t.Errorf("Book() series = %+v", book.Series)
+	}
+	if len(book.Genres) != 2 || book.Genres[0].Code != "prose_classic" || book.Genres[1].Code != "humor_prose" {
+		t.Errorf("Book() genres = %+v", book.Genres)
+	}
+
+	book, err = db.Book("175105")
+	if err != nil {
+		t.Fatalf("Book() error = %v", err)
+	}
+	if len(book.Translators) != 1 || book.Translators[0].String() != "Переводчик" {
+		t.Errorf("Book() translators = %+v", book.Translators)
+	}
+
+	if _, err = db.Book("1"); err != ErrNotFound {
+		t.Errorf("Book() of missing book error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDB_AuthorSeries(t *testing.T) {
+	db := importTestDB(t)
+	author, books, err := db.Author("2")
+	if err != nil {
+		t.Fatalf("Author() error = %v", err)
+	}
+	var ids []string
+	for _, book := range books {
+		ids = append(ids, book.ID)
+	}
+	if author.LastName != "Ильф" || !reflect.DeepEqual(ids, []string{"12345", "12346"}) {
+		t.Errorf("Author() = %+v, books %v", author, ids)
+	}
+
+	series, seriesBooks, err := db.Series("7")
+	if err != nil {
+		t.Fatalf("Series() error = %v", err)
+	}
+	var numbers []string
+	for _, book := range seriesBooks {
+		numbers = append(numbers, book.Number+":"+book.Title)
+	}
+	if series.Name != "Остап Бендер" || !reflect.DeepEqual(numbers, []string{"1:Двенадцать стульев", "2:Золотой телёнок"}) {
+		t.Errorf("Series() = %+v, books %v", series, numbers)
+	}
+}
+
+func TestDB_Search(t *testing.T) {
+	db := importTestDB(t)
+	books, err := db.SearchBooks("стульев", 0)
+	if err != nil || len(books) != 1 || books[0].ID != "12345" {
+		t.Errorf("SearchBooks() = %+v, %v, want only not deleted book", books, err)
+	}
+	books, _ = db.SearchBooks("теленок", 0)
+	if len(books) != 1 || books[0].ID != "12346" {
+		t.Errorf("SearchBooks() with ё = %+v", books)
+	}
+	authors, _ := db.SearchAuthors("петров", 0)
+	if len(authors) != 1 || authors[0].ID != "3" {
+		t.Errorf("SearchAuthors() = %+v", authors)
+	}
+	series, _ := db.SearchSeries("бендер", 1)
+	if len(series) != 1 || series[0].ID != "7" {
+		t.Errorf("SearchSeries() = %+v", series)
+	}
+	if books, _ = db.SearchBooks("", 0); len(books) != 0 {
+		t.Errorf("SearchBooks() with empty query = %+v", books)
+	}
+}
+
+func TestDB_Reimport(t *testing.T) {
+	db := importTestDB(t)
+	dump := "CREATE TABLE `libseq` (`BookId` int, `SeqId` int, `SeqNumb` int);\nINSERT INTO `libseq` VALUES (12345,7,3);"
+	if _, err := db.Import(strings.NewReader(dump)); err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	_, books, err := db.Series("7")
+	if err != nil || len(books) != 1 || books[0].Number != "3" {
+		t.Errorf("Series() after reimport = %+v, %v", books, err)
+	}
+	stats, _ := db.Stats()
+	if stats["books"] != 4 || stats["series_books"] != 1 {
+		t.Errorf("Stats() = %v", stats)
+	}
+}
+
+func TestDB_FailedImport(t *testing.T) {
+	db := importTestDB(t)
+	dump := "CREATE TABLE `libseq` (`BookId` int, `SeqId` int, `SeqNumb` int);\nINSERT INTO `libseq` VALUES (12345,7,3),(12346,7,"
+	if _, err := db.Import(strings.NewReader(dump)); err == nil {
+		t.Fatal("Import() of truncated dump error = nil")
+	}
+	_, books, err := db.Series("7")
+	if err != nil || len(books) != 2 || books[0].Number != "1" {
+		t.Errorf("Series() after failed import = %+v, %v, want previous data", books, err)
+	}
+	var buckets []string
+	_ = db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if strings.HasPrefix(string(name), "series_books") {
+				buckets = append(buckets, string(name))
+			}
+			return nil
+		})
+	})
+	if len(buckets) != 1 {
+		t.Errorf("series_books buckets after failed import = %v, want staged one dropped", buckets)
+	}
+}
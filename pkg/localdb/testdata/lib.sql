@@ -0,0 +1,78 @@
+-- Excerpt of lib.*.sql dumps
+DROP TABLE IF EXISTS `libbook`;
+CREATE TABLE `libbook` (
+  `BookId` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `FileSize` int(10) unsigned NOT NULL DEFAULT '0',
+  `Time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `Title` varchar(254) NOT NULL DEFAULT '',
+  `Title1` varchar(254) NOT NULL DEFAULT '',
+  `Lang` char(2) NOT NULL DEFAULT 'ru',
+  `FileType` char(4) NOT NULL DEFAULT 'fb2',
+  `Year` smallint(6) NOT NULL DEFAULT '0',
+  `Deleted` char(1) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`BookId`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libbook` VALUES (175105,2890123,'2009-11-29 18:41:08','Война и мир','','ru','fb2',1869,'0'),(12345,1000,'2005-01-01 00:00:00','Двенадцать стульев','','ru','fb2',1928,'0'),(12346,1000,'2005-01-02 00:00:00','Золотой телёнок','','ru','fb2',1931,'0'),(12347,10,'2005-01-03 00:00:00','Двенадцать стульев (дубль)','','ru','fb2',0,'1');
+
+CREATE TABLE `libavtorname` (
+  `AvtorId` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `FirstName` varchar(99) NOT NULL DEFAULT '',
+  `MiddleName` varchar(99) NOT NULL DEFAULT '',
+  `LastName` varchar(99) NOT NULL DEFAULT '',
+  `NickName` varchar(33) NOT NULL DEFAULT '',
+  PRIMARY KEY (`AvtorId`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libavtorname` VALUES (1,'Лев','Николаевич','Толстой',''),(2,'Илья','Арнольдович','Ильф',''),(3,'Евгений','Петрович','Петров',''),(4,'','','','Переводчик');
+
+CREATE TABLE `libavtor` (
+  `BookId` int(10) unsigned NOT NULL DEFAULT '0',
+  `AvtorId` int(10) unsigned NOT NULL DEFAULT '0',
+  `Pos` tinyint(4) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`BookId`,`AvtorId`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libavtor` VALUES (175105,1,0),(12345,3,1),(12345,2,0),(12346,2,0),(12346,3,1),(12347,2,0);
+
+CREATE TABLE `libtranslator` (
+  `BookId` int(10) unsigned NOT NULL DEFAULT '0',
+  `TranslatorId` int(10) unsigned NOT NULL DEFAULT '0',
+  `Pos` tinyint(4) NOT NULL DEFAULT '0'
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libtranslator` VALUES (175105,4,0);
+
+CREATE TABLE `libseqname` (
+  `SeqId` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `SeqName` varchar(254) NOT NULL DEFAULT '',
+  PRIMARY KEY (`SeqId`)
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libseqname` VALUES (7,'Остап Бендер');
+
+CREATE TABLE `libseq` (
+  `BookId` int(10) unsigned NOT NULL,
+  `SeqId` int(10) unsigned NOT NULL,
+  `SeqNumb` int(10) NOT NULL,
+  `Level` tinyint(1) unsigned NOT NULL DEFAULT '0',
+  `Type` tinyint(1) unsigned NOT NULL DEFAULT '0'
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libseq` VALUES (12346,7,2,0,0),(12345,7,1,0,0);
+
+CREATE TABLE `libgenrelist` (
+  `GenreId` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `GenreCode` varchar(45) NOT NULL DEFAULT '',
+  `GenreDesc` varchar(99) NOT NULL DEFAULT '',
+  `GenreMeta` varchar(45) NOT NULL DEFAULT ''
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libgenrelist` VALUES (1,'prose_classic','Классическая проза','Проза'),(2,'humor_prose','Юмористическая проза','Юмор');
+
+CREATE TABLE `libgenre` (
+  `Id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `BookId` int(10) unsigned NOT NULL DEFAULT '0',
+  `GenreId` int(10) unsigned NOT NULL DEFAULT '0'
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `libgenre` VALUES (1,175105,1),(2,12345,2),(3,12345,1);
+
+CREATE TABLE `librate` (
+  `BookId` int(10) unsigned NOT NULL,
+  `UserId` int(10) unsigned NOT NULL,
+  `Rate` char(1) NOT NULL
+) ENGINE=MyISAM DEFAULT CHARSET=utf8;
+INSERT INTO `librate` VALUES (175105,1,'5'),(175105,2,'4');
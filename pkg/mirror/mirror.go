@@ -0,0 +1,224 @@
+// Package mirror keeps a local copy of the library up to date with daily update archives.
+package mirror
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+)
+
+const (
+	DirEnvKey  = "FLIBUSTA_MIRROR_DIR"
+	stateFile  = ".sync.json"
+	archiveDir = ".daily"
+	// Books are grouped by thousands of ID: 590/590123.fb2
+	booksPerDir = 1000
+)
+
+var bookFileRe = regexp.MustCompile(`^([0-9]+)\.[A-Za-z0-9.]+$`)
+
+// Source lists and downloads daily archives, FlibustaClient is the real one.
+type Source interface {
+	DailyArchives() ([]string, error)
+	DailyArchive(name string, respProcessor func(stream io.Reader) error) error
+}
+
+// Applied describes archive extracted to the mirror.
+type Applied struct {
+	Books int       `json:"books"`
+	Time  time.Time `json:"time"`
+}
+
+// Mirror is a directory with books extracted from daily archives.
+// Applied archives are recorded in state file, so interrupted sync continues
+// with the archive it was working on.
+type Mirror struct {
+	Dir          string
+	Source       Source
+	KeepArchives bool
+	Applied      map[string]Applied
+}
+
+// Open reads sync state of mirror directory, new directory has nothing applied.
+func Open(dir string, source Source) (*Mirror, error) {
+	m := &Mirror{Dir: dir, Source: source, Applied: map[string]Applied{}}
+	data, err := os.ReadFile(filepath.Join(dir, stateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &m.Applied)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse sync state %s: %w", stateFile, err)
+	}
+	return m, nil
+}
+
+// Pending lists archives available on the site which are not applied yet.
+func (m *Mirror) Pending() ([]string, error) {
+	names, err := m.Source.DailyArchives()
+	if err != nil {
+		return nil, err
+	}
+	var pending []string
+	for _, name := range names {
+		if _, ok := m.Applied[name]; !ok {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// Apply downloads archive, unless it was downloaded by interrupted sync,
+// verifies it and extracts books. State is saved after every archive.
+func (m *Mirror) Apply(name string) (int, error) {
+	archivePath := filepath.Join(m.Dir, archiveDir, name)
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		// Not downloaded yet, or broken download
+		archive, err = m.download(name, archivePath)
+		if err != nil {
+			return 0, err
+		}
+	}
+	err = verify(archive)
+	if err != nil {
+		_ = archive.Close()
+		_ = os.Remove(archivePath)
+		return 0, fmt.Errorf("%s is broken: %w", name, err)
+	}
+	books, err := m.extract(archive)
+	_ = archive.Close()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+
+	m.Applied[name] = Applied{Books: books, Time: time.Now()}
+	err = m.save()
+	if err != nil {
+		return books, err
+	}
+	if !m.KeepArchives {
+		_ = os.Remove(archivePath)
+	}
+	return books, nil
+}
+
+// download streams archive to temporary file next to the archive, it is renamed when download is complete.
+func (m *Mirror) download(name string, archivePath string) (*zip.ReadCloser, error) {
+	err := os.MkdirAll(filepath.Dir(archivePath), 0755)
+	if err != nil {
+		return nil, err
+	}
+	err = m.Source.DailyArchive(name, func(stream io.Reader) error {
+		return fsutil.WriteAtomic(archivePath, 0644, true, func(w io.Writer) error {
+			_, err := io.Copy(w, stream)
+			return err
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		_ = os.Remove(archivePath)
+		return nil, fmt.Errorf("%s is not a valid archive: %w", name, err)
+	}
+	return archive, nil
+}
+
+// verify reads every entry, archive/zip checks CRC of entry when it is read to the end.
+func verify(archive *zip.ReadCloser) error {
+	for _, entry := range archive.File {
+		content, err := entry.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		_, err = io.Copy(io.Discard, content)
+		_ = content.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Name, err)
+		}
+	}
+	return nil
+}
+
+// extract writes every book atomically, so extraction can be repeated after interruption.
+func (m *Mirror) extract(archive *zip.ReadCloser) (int, error) {
+	books := 0
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		target, err := m.BookPath(entry.Name)
+		if err != nil {
+			return books, err
+		}
+		err = os.MkdirAll(filepath.Dir(target), 0755)
+		if err != nil {
+			return books, err
+		}
+		content, err := entry.Open()
+		if err != nil {
+			return books, err
+		}
+		err = fsutil.WriteAtomic(target, 0644, true, func(w io.Writer) error {
+			_, err := io.Copy(w, content)
+			return err
+		})
+		_ = content.Close()
+		if err != nil {
+			return books, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+		books++
+	}
+	return books, nil
+}
+
+// BookPath places books named by ID into directories by thousands,
+// other files go to `other` directory.
+func (m *Mirror) BookPath(entryName string) (string, error) {
+	name := path.Base(strings.ReplaceAll(entryName, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("unsafe file name in archive: %s", entryName)
+	}
+	match := bookFileRe.FindStringSubmatch(name)
+	if match == nil {
+		return filepath.Join(m.Dir, "other", name), nil
+	}
+	var id int
+	_, _ = fmt.Sscanf(match[1], "%d", &id)
+	return filepath.Join(m.Dir, fmt.Sprintf("%03d", id/booksPerDir), name), nil
+}
+
+// AppliedNames returns applied archives in order of names.
+func (m *Mirror) AppliedNames() []string {
+	names := make([]string, 0, len(m.Applied))
+	for name := range m.Applied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Mirror) save() error {
+	data, err := json.MarshalIndent(m.Applied, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(filepath.Join(m.Dir, stateFile), data, 0644, true)
+}
@@ -0,0 +1,205 @@
+package mirror
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/iotest"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+type fakeSource struct {
+	archives  map[string][]byte
+	names     []string
+	downloads []string
+	// interrupted archives fail in the middle of download
+	interrupted map[string]bool
+}
+
+func (s *fakeSource) DailyArchives() ([]string, error) {
+	return s.names, nil
+}
+
+func (s *fakeSource) DailyArchive(name string, respProcessor func(stream io.Reader) error) error {
+	s.downloads = append(s.downloads, name)
+	data, ok := s.archives[name]
+	if !ok {
+		return client.ErrNotFound
+	}
+	if s.interrupted[name] {
+		return respProcessor(io.MultiReader(bytes.NewReader(data[:len(data)/2]), iotest.ErrReader(errInterrupted)))
+	}
+	return respProcessor(bytes.NewReader(data))
+}
+
+var errInterrupted = errors.New("connection reset")
+
+func zipped(t *testing.T, files ...string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	archive := zip.NewWriter(buf)
+	for _, name := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte("<FictionBook>" + name + "</FictionBook>"))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newSource(t *testing.T) *fakeSource {
+	return &fakeSource{
+		names: []string{"f.fb2.590001-590290.zip", "f.fb2.590291-590511.zip"},
+		archives: map[string][]byte{
+			"f.fb2.590001-590290.zip": zipped(t, "590001.fb2", "590290.fb2"),
+			"f.fb2.590291-590511.zip": zipped(t, "590291.fb2", "readme.txt"),
+		},
+	}
+}
+
+func TestMirror_Sync(t *testing.T) {
+	dir := t.TempDir()
+	source := newSource(t)
+	m, err := Open(dir, source)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	pending, err := m.Pending()
+	if err != nil || !reflect.DeepEqual(pending, source.names) {
+		t.Fatalf("Pending() = %v, %v", pending, err)
+	}
+	books, err := m.Apply(pending[0])
+	if err != nil || books != 2 {
+		t.Fatalf("Apply() = %d, %v", books, err)
+	}
+	for _, name := range []string{"590/590001.fb2", "590/590290.fb2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Book %s is not extracted: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, archiveDir, pending[0])); !os.IsNotExist(err) {
+		t.Errorf("Archive is kept after apply")
+	}
+
+	// Sync started again sees only new archive
+	m, err = Open(dir, source)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	pending, _ = m.Pending()
+	if !reflect.DeepEqual(pending, []string{"f.fb2.590291-590511.zip"}) {
+		t.Errorf("Pending() after apply = %v", pending)
+	}
+	if _, err = m.Apply(pending[0]); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other", "readme.txt")); err != nil {
+		t.Errorf("Other file is not extracted: %v", err)
+	}
+	if m.Applied["f.fb2.590291-590511.zip"].Books != 2 {
+		t.Errorf("Applied = %+v", m.Applied)
+	}
+}
+
+func TestMirror_Resume(t *testing.T) {
+	dir := t.TempDir()
+	source := newSource(t)
+	name := source.names[0]
+	// Archive was downloaded, but sync was interrupted before it was applied
+	_ = os.MkdirAll(filepath.Join(dir, archiveDir), 0755)
+	_ = os.WriteFile(filepath.Join(dir, archiveDir, name), source.archives[name], 0644)
+
+	m, _ := Open(dir, source)
+	m.KeepArchives = true
+	if _, err := m.Apply(name); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(source.downloads) != 0 {
+		t.Errorf("Downloaded archive is fetched again: %v", source.downloads)
+	}
+	if _, err := os.Stat(filepath.Join(dir, archiveDir, name)); err != nil {
+		t.Errorf("Archive is removed with KeepArchives")
+	}
+}
+
+func TestMirror_BrokenArchive(t *testing.T) {
+	dir := t.TempDir()
+	source := newSource(t)
+	name := source.names[0]
+	data := append([]byte{}, source.archives[name]...)
+	// Damage content of the first file, central directory is intact
+	copy(data[30+len("590001.fb2"):], "XXXX")
+	source.archives[name] = data
+
+	m, _ := Open(dir, source)
+	_, err := m.Apply(name)
+	if !errors.Is(err, zip.ErrChecksum) {
+		t.Fatalf("Apply() error = %v, want checksum error", err)
+	}
+	if _, ok := m.Applied[name]; ok {
+		t.Errorf("Broken archive is marked as applied")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "590", "590001.fb2")); !os.IsNotExist(err) {
+		t.Errorf("Books of broken archive are extracted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, archiveDir, name)); !os.IsNotExist(err) {
+		t.Errorf("Broken archive is kept")
+	}
+}
+
+func TestMirror_InterruptedDownload(t *testing.T) {
+	dir := t.TempDir()
+	source := newSource(t)
+	name := source.names[0]
+	source.interrupted = map[string]bool{name: true}
+
+	m, _ := Open(dir, source)
+	if _, err := m.Apply(name); !errors.Is(err, errInterrupted) {
+		t.Fatalf("Apply() error = %v, want download error", err)
+	}
+	// Neither archive nor its partial download is left
+	entries, _ := os.ReadDir(filepath.Join(dir, archiveDir))
+	if len(entries) != 0 {
+		t.Errorf("Files left after interrupted download: %v", entries)
+	}
+
+	source.interrupted = nil
+	if _, err := m.Apply(name); err != nil {
+		t.Fatalf("Apply() after interrupted download error = %v", err)
+	}
+}
+
+func TestMirror_BookPath(t *testing.T) {
+	m := &Mirror{Dir: "books"}
+	tests := []struct {
+		entry   string
+		want    string
+		wantErr bool
+	}{
+		{"590123.fb2", filepath.Join("books", "590", "590123.fb2"), false},
+		{"12.fb2", filepath.Join("books", "000", "12.fb2"), false},
+		{"1234567.fb2.zip", filepath.Join("books", "1234", "1234567.fb2.zip"), false},
+		{"dir/../../590123.fb2", filepath.Join("books", "590", "590123.fb2"), false},
+		{"readme.txt", filepath.Join("books", "other", "readme.txt"), false},
+		{"../", "", true},
+		{".hidden", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, err := m.BookPath(tt.entry)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("BookPath() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package pager
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+)
+
+const appConfigDir = "flibusta-cli"
+
+// Bookmark remembers reading position as block index, so it survives terminal resize.
+type Bookmark struct {
+	Block   int       `json:"block"`
+	Updated time.Time `json:"updated"`
+}
+
+// Bookmarks are reading positions per book ID stored in JSON file.
+type Bookmarks struct {
+	fileName string
+	Books    map[string]Bookmark
+}
+
+// DefaultBookmarksFile is located in user config directory.
+func DefaultBookmarksFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appConfigDir, "bookmarks.json"), nil
+}
+
+// LoadBookmarks reads bookmarks file, missing file means no bookmarks yet.
+func LoadBookmarks(fileName string) (*Bookmarks, error) {
+	bookmarks := &Bookmarks{fileName: fileName, Books: map[string]Bookmark{}}
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return bookmarks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &bookmarks.Books)
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+func (b *Bookmarks) Get(bookID string) (Bookmark, bool) {
+	bookmark, ok := b.Books[bookID]
+	return bookmark, ok
+}
+
+func (b *Bookmarks) Set(bookID string, block int) {
+	b.Books[bookID] = Bookmark{Block: block, Updated: time.Now()}
+}
+
+func (b *Bookmarks) Save() error {
+	data, err := json.MarshalIndent(b.Books, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(b.fileName), 0755)
+	if err != nil {
+		return err
+	}
+	return fsutil.WriteFileAtomic(b.fileName, data, 0644, true)
+}
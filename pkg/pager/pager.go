@@ -0,0 +1,148 @@
+package pager
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const helpText = `Enter/f - next page, b - previous page, g/G - top/bottom,
+/text - search, n - next match, m - save bookmark, q - quit, h - help`
+
+// Pager shows lines page by page and reads commands line by line,
+// so it works in any terminal without raw mode.
+type Pager struct {
+	Lines     []Line
+	Height    int
+	BookID    string
+	Bookmarks *Bookmarks
+
+	in     *bufio.Scanner
+	out    io.Writer
+	top    int
+	search string
+}
+
+func New(lines []Line, height int, in io.Reader, out io.Writer) *Pager {
+	if height < 2 {
+		height = 2
+	}
+	return &Pager{
+		Lines:  lines,
+		Height: height,
+		in:     bufio.NewScanner(in),
+		out:    out,
+	}
+}
+
+// Run shows book from bookmarked position until user quits or input ends.
+// Position is saved when pager exits.
+func (p *Pager) Run() error {
+	p.restore()
+	for {
+		p.show()
+		fmt.Fprintf(p.out, "-- %d%% -- ", p.percent())
+		if !p.in.Scan() {
+			fmt.Fprintln(p.out)
+			return p.save()
+		}
+		command := strings.TrimSpace(p.in.Text())
+		switch {
+		case command == "" || command == "f":
+			p.scroll(p.pageSize())
+		case command == "b":
+			p.scroll(-p.pageSize())
+		case command == "g":
+			p.top = 0
+		case command == "G":
+			p.scroll(len(p.Lines))
+		case strings.HasPrefix(command, "/"):
+			p.search = strings.TrimPrefix(command, "/")
+			p.find()
+		case command == "n":
+			p.find()
+		case command == "m":
+			if err := p.save(); err != nil {
+				fmt.Fprintln(p.out, "cannot save bookmark:", err)
+			}
+		case command == "q":
+			return p.save()
+		default:
+			fmt.Fprintln(p.out, helpText)
+		}
+	}
+}
+
+func (p *Pager) pageSize() int {
+	// Last line is used by prompt
+	return p.Height - 1
+}
+
+func (p *Pager) show() {
+	end := p.top + p.pageSize()
+	if end > len(p.Lines) {
+		end = len(p.Lines)
+	}
+	for _, line := range p.Lines[p.top:end] {
+		fmt.Fprintln(p.out, line.Text)
+	}
+}
+
+func (p *Pager) scroll(delta int) {
+	p.top += delta
+	if last := len(p.Lines) - p.pageSize(); p.top > last {
+		p.top = last
+	}
+	if p.top < 0 {
+		p.top = 0
+	}
+}
+
+// find moves to the next line containing search text, case insensitive.
+func (p *Pager) find() {
+	if p.search == "" {
+		return
+	}
+	needle := strings.ToLower(p.search)
+	for i := p.top + 1; i < len(p.Lines); i++ {
+		if strings.Contains(strings.ToLower(p.Lines[i].Text), needle) {
+			p.top = i
+			p.scroll(0)
+			return
+		}
+	}
+	fmt.Fprintf(p.out, "pattern not found: %s\n", p.search)
+}
+
+func (p *Pager) percent() int {
+	if len(p.Lines) <= p.pageSize() {
+		return 100
+	}
+	return (p.top + p.pageSize()) * 100 / len(p.Lines)
+}
+
+func (p *Pager) restore() {
+	if p.Bookmarks == nil {
+		return
+	}
+	bookmark, ok := p.Bookmarks.Get(p.BookID)
+	if !ok {
+		return
+	}
+	for i, line := range p.Lines {
+		if line.Block >= bookmark.Block {
+			p.top = i
+			break
+		}
+	}
+	p.scroll(0)
+}
+
+func (p *Pager) save() error {
+	if p.Bookmarks == nil || len(p.Lines) == 0 {
+		return nil
+	}
+	p.Bookmarks.Set(p.BookID, p.Lines[p.top].Block)
+	return p.Bookmarks.Save()
+}
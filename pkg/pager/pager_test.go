@@ -0,0 +1,79 @@
+package pager
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testLines(count int) (lines []Line) {
+	for i := 0; i < count; i++ {
+		lines = append(lines, Line{Text: fmt.Sprintf("line %d", i), Block: i})
+	}
+	return lines
+}
+
+func TestPager_Run(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantTop int
+	}{
+		{"Quit right away", "q\n", 0},
+		{"Next page", "\nq\n", 4},
+		{"Next and previous page", "f\nb\nq\n", 0},
+		{"Do not scroll past the end", "\n\n\n\n\nq\n", 6},
+		{"Bottom", "G\nq\n", 6},
+		{"Search", "/LINE 7\nq\n", 6},
+		{"Search next", "/line 1\nn\nq\n", 1},
+		{"Input ends", "\n", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			p := New(testLines(10), 5, strings.NewReader(tt.input), out)
+			if err := p.Run(); err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if p.top != tt.wantTop {
+				t.Errorf("Run() top = %v, want %v", p.top, tt.wantTop)
+			}
+		})
+	}
+}
+
+func TestPager_Bookmarks(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config", "bookmarks.json")
+	bookmarks, err := LoadBookmarks(fileName)
+	if err != nil {
+		t.Fatalf("LoadBookmarks() error = %v", err)
+	}
+
+	p := New(testLines(20), 5, strings.NewReader("\n\nq\n"), &bytes.Buffer{})
+	p.BookID = "123"
+	p.Bookmarks = bookmarks
+	if err = p.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	bookmarks, err = LoadBookmarks(fileName)
+	if err != nil {
+		t.Fatalf("LoadBookmarks() error = %v", err)
+	}
+	bookmark, ok := bookmarks.Get("123")
+	if !ok || bookmark.Block != 8 {
+		t.Fatalf("Bookmark = %v, %v, want block 8", bookmark, ok)
+	}
+
+	p = New(testLines(20), 5, strings.NewReader("q\n"), &bytes.Buffer{})
+	p.BookID = "123"
+	p.Bookmarks = bookmarks
+	if err = p.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if p.top != 8 {
+		t.Errorf("Run() restored top = %v, want 8", p.top)
+	}
+}
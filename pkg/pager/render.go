@@ -0,0 +1,101 @@
+package pager
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+const (
+	paragraphIndent = "  "
+	// Narrower text is not readable, and words are cut to width
+	MinWidth = 10
+)
+
+// Line is a wrapped line of text with index of a block it belongs to.
+// Block index does not depend on width, so it is used for bookmarks.
+type Line struct {
+	Text  string
+	Block int
+}
+
+// Render wraps book text to given width, headings are underlined. Width is at least MinWidth.
+func Render(book *client.BookText, width int) (lines []Line) {
+	if width < MinWidth {
+		width = MinWidth
+	}
+	if book.Title != "" {
+		for _, text := range wrap(book.Title, width) {
+			lines = append(lines, Line{Text: text})
+		}
+		lines = append(lines, Line{Text: underline(book.Title, width, "=")}, Line{})
+	}
+	for i, block := range book.Blocks {
+		if block.Heading {
+			lines = append(lines, Line{Block: i})
+			for _, headingLine := range strings.Split(block.Text, "\n") {
+				for _, text := range wrap(headingLine, width) {
+					lines = append(lines, Line{Text: text, Block: i})
+				}
+			}
+			lines = append(lines, Line{Text: underline(block.Text, width, "-"), Block: i}, Line{Block: i})
+			continue
+		}
+		for _, text := range wrap(paragraphIndent+strings.ReplaceAll(block.Text, "\n", " "), width) {
+			lines = append(lines, Line{Text: text, Block: i})
+		}
+	}
+	return lines
+}
+
+// wrap splits text by words so every line fits width.
+// Leading spaces are kept as indent, words longer than width are cut.
+func wrap(text string, width int) (lines []string) {
+	indent := text[:len(text)-len(strings.TrimLeft(text, " "))]
+	line, empty := indent, true
+	for _, word := range strings.Fields(text) {
+		for utf8.RuneCountInString(word) > width {
+			if !empty {
+				lines = append(lines, line)
+				line, empty = "", true
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+		if word == "" {
+			continue
+		}
+		switch {
+		case empty && utf8.RuneCountInString(line+word) <= width:
+			line += word
+		case empty:
+			line = word
+		case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+		empty = false
+	}
+	if !empty {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// underline returns separator as long as the longest line of the text.
+func underline(text string, width int, symbol string) string {
+	longest := 0
+	for _, line := range strings.Split(text, "\n") {
+		if length := utf8.RuneCountInString(line); length > longest {
+			longest = length
+		}
+	}
+	if longest > width {
+		longest = width
+	}
+	return strings.Repeat(symbol, longest)
+}
@@ -0,0 +1,101 @@
+package pager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+func Test_wrap(t *testing.T) {
+	type args struct {
+		text  string
+		width int
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			"Empty",
+			args{"", 10},
+			nil,
+		},
+		{
+			"Fits",
+			args{"short text", 10},
+			[]string{"short text"},
+		},
+		{
+			"Cyrillic is counted by runes",
+			args{"Война и мир", 11},
+			[]string{"Война и мир"},
+		},
+		{
+			"Wrap by words",
+			args{"Война и мир том первый", 10},
+			[]string{"Война и", "мир том", "первый"},
+		},
+		{
+			"Keep indent",
+			args{"  Война и мир", 10},
+			[]string{"  Война и", "мир"},
+		},
+		{
+			"Cut long word",
+			args{"a abcdefghij", 4},
+			[]string{"a", "abcd", "efgh", "ij"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wrap(tt.args.text, tt.args.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("wrap() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	book := &client.BookText{
+		Title: "Книга",
+		Blocks: []client.TextBlock{
+			{Heading: true, Text: "Автор\nГлава"},
+			{Text: "Первый абзац текста"},
+		},
+	}
+	want := []Line{
+		{Text: "Книга"},
+		{Text: "====="},
+		{},
+		{},
+		{Text: "Автор"},
+		{Text: "Глава"},
+		{Text: "-----"},
+		{},
+		{Text: "  Первый абзац", Block: 1},
+		{Text: "текста", Block: 1},
+	}
+	if got := Render(book, 14); !reflect.DeepEqual(got, want) {
+		t.Errorf("Render() = %v, want %v", got, want)
+	}
+}
+
+func TestRender_narrow(t *testing.T) {
+	book := &client.BookText{Title: "Книга", Blocks: []client.TextBlock{{Text: "Первый абзац текста"}}}
+	want := []Line{
+		{Text: "Книга"},
+		{Text: "====="},
+		{},
+		{Text: "  Первый", Block: 0},
+		{Text: "абзац", Block: 0},
+		{Text: "текста", Block: 0},
+	}
+	// Zero width used to hang and negative one to panic
+	for _, width := range []int{0, -5, 3, MinWidth} {
+		if got := Render(book, width); !reflect.DeepEqual(got, want) {
+			t.Errorf("Render() with width %d = %v, want %v", width, got, want)
+		}
+	}
+}
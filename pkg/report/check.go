@@ -0,0 +1,163 @@
+package report
+
+import (
+	"fmt"
+	"reflect"
+	tparse "text/template/parse"
+)
+
+// checker walks parsed template and resolves fields against result type.
+// Type of dot is nil where it cannot be known without data, fields are not checked there.
+type checker struct {
+	root reflect.Type
+}
+
+func (c *checker) list(list *tparse.ListNode, dot reflect.Type) error {
+	if list == nil {
+		return nil
+	}
+	for _, node := range list.Nodes {
+		if err := c.node(node, dot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *checker) node(node tparse.Node, dot reflect.Type) error {
+	switch node := node.(type) {
+	case *tparse.ActionNode:
+		_, err := c.pipe(node.Pipe, dot)
+		return err
+	case *tparse.IfNode:
+		return c.branch(&node.BranchNode, dot, dot)
+	case *tparse.WithNode:
+		inner, err := c.pipe(node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.branch(&node.BranchNode, dot, inner)
+	case *tparse.RangeNode:
+		inner, err := c.pipe(node.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.branch(&node.BranchNode, dot, elem(inner))
+	case *tparse.TemplateNode:
+		_, err := c.pipe(node.Pipe, dot)
+		return err
+	}
+	return nil
+}
+
+func (c *checker) branch(node *tparse.BranchNode, dot reflect.Type, inner reflect.Type) error {
+	if _, err := c.pipe(node.Pipe, dot); err != nil {
+		return err
+	}
+	if err := c.list(node.List, inner); err != nil {
+		return err
+	}
+	return c.list(node.ElseList, dot)
+}
+
+// pipe checks fields of every command and returns type of the pipeline when it is a field or dot.
+func (c *checker) pipe(pipe *tparse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if pipe == nil {
+		return nil, nil
+	}
+	var result reflect.Type
+	for _, cmd := range pipe.Cmds {
+		result = nil
+		for _, arg := range cmd.Args {
+			t, err := c.arg(arg, dot)
+			if err != nil {
+				return nil, err
+			}
+			if len(cmd.Args) == 1 {
+				result = t
+			}
+		}
+	}
+	return result, nil
+}
+
+func (c *checker) arg(arg tparse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch arg := arg.(type) {
+	case *tparse.DotNode:
+		return dot, nil
+	case *tparse.FieldNode:
+		return field(dot, arg.Ident)
+	case *tparse.VariableNode:
+		if arg.Ident[0] == "$" {
+			return field(c.root, arg.Ident[1:])
+		}
+	case *tparse.ChainNode:
+		if pipe, ok := arg.Node.(*tparse.PipeNode); ok {
+			t, err := c.pipe(pipe, dot)
+			if err != nil {
+				return nil, err
+			}
+			return field(t, arg.Field)
+		}
+	case *tparse.PipeNode:
+		return c.pipe(arg, dot)
+	}
+	return nil, nil
+}
+
+// field resolves chain of field or method names, like `.Book.Title`.
+func field(t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if t == nil {
+			return nil, nil
+		}
+		if method, ok := t.MethodByName(name); ok && t.Kind() != reflect.Interface {
+			t = result(method.Type)
+			continue
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if method, ok := reflect.PtrTo(t).MethodByName(name); ok {
+			t = result(method.Type)
+			continue
+		}
+		switch t.Kind() {
+		case reflect.Interface:
+			return nil, nil
+		case reflect.Map:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if f, ok := t.FieldByName(name); ok && f.PkgPath == "" {
+				t = f.Type
+				continue
+			}
+		}
+		return nil, fmt.Errorf("can't evaluate field %s in type %s", name, t)
+	}
+	return t, nil
+}
+
+// result is type of the first value returned by method
+func result(method reflect.Type) reflect.Type {
+	if method.NumOut() == 0 {
+		return nil
+	}
+	return method.Out(0)
+}
+
+// elem is type of range element
+func elem(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return t.Elem()
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package report renders command results with user templates, like `{{.ID}}\t{{.Title}}`.
+package report
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/slivtime/flibusta-cli/pkg/filename"
+)
+
+// Funcs are helpers available in templates.
+var Funcs = template.FuncMap{
+	"join":          strings.Join,
+	"truncate":      Truncate,
+	"transliterate": filename.Transliterate,
+	"humanize":      HumanizeSize,
+}
+
+// Template prints every result with a line break after it.
+type Template struct {
+	tpl *template.Template
+}
+
+// Parse reads template given on the command line, `\t` and `\n` stand for tab and line break
+// as shells do not expand them in quotes.
+func Parse(text string) (*Template, error) {
+	text = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(text)
+	return parse("--template", text)
+}
+
+// ParseFile reads template from file as is.
+func ParseFile(fileName string) (*Template, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return parse(fileName, string(data))
+}
+
+func parse(name string, text string) (*Template, error) {
+	tpl, err := template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &Template{tpl}, nil
+}
+
+// Check reports fields which results like sample do not have before any request is made.
+// Template is not executed, so e.g. index of a list which is empty in sample passes.
+func (t *Template) Check(sample interface{}) error {
+	root := reflect.TypeOf(sample)
+	c := &checker{root: root}
+	err := c.list(t.tpl.Tree.Root, root)
+	if err != nil {
+		return fmt.Errorf("invalid template for %T: %w", sample, err)
+	}
+	return nil
+}
+
+func (t *Template) Execute(w io.Writer, data interface{}) error {
+	buf := &strings.Builder{}
+	err := t.tpl.Execute(buf, data)
+	if err != nil {
+		return err
+	}
+	text := buf.String()
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	_, err = io.WriteString(w, text)
+	return err
+}
+
+// Truncate shortens text to limit characters, cut text ends with ellipsis.
+func Truncate(limit int, text string) string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
+
+var sizeUnits = []string{"B", "KiB", "MiB", "GiB", "TiB"}
+
+// HumanizeSize formats size in bytes like `2.2 MiB`. Numbers in strings are accepted too,
+// other values are printed as is.
+func HumanizeSize(size interface{}) string {
+	var bytes float64
+	switch value := size.(type) {
+	case int:
+		bytes = float64(value)
+	case int64:
+		bytes = float64(value)
+	case uint64:
+		bytes = float64(value)
+	case float64:
+		bytes = value
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return value
+		}
+		bytes = parsed
+	default:
+		return fmt.Sprint(size)
+	}
+	unit := 0
+	for bytes >= 1024 && unit < len(sizeUnits)-1 {
+		bytes /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", int64(bytes), sizeUnits[unit])
+	}
+	return fmt.Sprintf("%.1f %s", bytes, sizeUnits[unit])
+}
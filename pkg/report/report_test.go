@@ -0,0 +1,154 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type book struct {
+	ID      string
+	Title   string
+	Authors []string
+	Size    int64
+}
+
+func TestTemplate(t *testing.T) {
+	data := book{ID: "325729", Title: "Нежить", Authors: []string{"Джон Джозеф Адамс", "Стивен Кинг"}, Size: 2317312}
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"Tab escape", `{{.ID}}\t{{.Title}}`, "325729\tНежить\n", false},
+		{"Line break is not doubled", `{{.ID}}\n`, "325729\n", false},
+		{"Join", `{{join .Authors ", "}}`, "Джон Джозеф Адамс, Стивен Кинг\n", false},
+		{"Truncate", `{{truncate 4 .Title}}`, "Неж…\n", false},
+		{"Transliterate", `{{transliterate .Title}}`, "Nezhit\n", false},
+		{"Humanize", `{{humanize .Size}}`, "2.2 MiB\n", false},
+		{"Pipeline", `{{.Title | transliterate | truncate 3}}`, "Ne…\n", false},
+		{"Syntax error", `{{.ID`, "", true},
+		{"Unknown function", `{{upper .ID}}`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := Parse(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			buf := &bytes.Buffer{}
+			err = tpl.Execute(buf, data)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type shelf struct {
+	Name  string
+	Books []*book
+	Tags  map[string]string
+}
+
+func (s shelf) First() *book {
+	return s.Books[0]
+}
+
+func TestTemplate_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{"Fields", `{{.Name}} {{truncate 10 .Name}}`, false},
+		{"Unknown field", `{{.Name}} {{.Rating}}`, true},
+		{"Index of empty list", `{{index .Books 0}}`, false},
+		{"Fields of range element", `{{range .Books}}{{.Title}} {{join .Authors ", "}}{{end}}`, false},
+		{"Unknown field of range element", `{{range .Books}}{{.Rating}}{{end}}`, true},
+		{"Root variable in range", `{{range .Books}}{{$.Name}}{{end}}`, false},
+		{"With", `{{with .First}}{{.Size}}{{end}}`, false},
+		{"Unknown field of method result", `{{.First.Rating}}`, true},
+		{"Unknown field in else", `{{with .First}}{{.Title}}{{else}}{{.Title}}{{end}}`, true},
+		{"Map key", `{{.Tags.genre}}`, false},
+		{"Field of function result", `{{(index .Books 0).Title}}`, false},
+		{"Field of string", `{{.Name.Length}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Sample has no books, executing the template would fail
+			if err := tpl.Check(shelf{}); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "book.tpl")
+	err := os.WriteFile(fileName, []byte("{{.ID}}\\t{{.Title}}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := ParseFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	_ = tpl.Execute(buf, book{ID: "1", Title: "T"})
+	// Escapes are expanded only on command line
+	if got := buf.String(); got != "1\\tT\n" {
+		t.Errorf("Execute() = %q", got)
+	}
+}
+
+func TestHumanizeSize(t *testing.T) {
+	tests := []struct {
+		size interface{}
+		want string
+	}{
+		{0, "0 B"},
+		{int64(1023), "1023 B"},
+		{1536, "1.5 KiB"},
+		{int64(5 << 30), "5.0 GiB"},
+		{"2048", "2.0 KiB"},
+		{"2263K, 595 с.", "2263K, 595 с."},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := HumanizeSize(tt.size); got != tt.want {
+			t.Errorf("HumanizeSize(%v) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		limit int
+		text  string
+		want  string
+	}{
+		{10, "Нежить", "Нежить"},
+		{6, "Нежить", "Нежить"},
+		{5, "Нежить", "Нежи…"},
+		{7, "Война и мир", "Война…"},
+		{0, "Нежить", "Нежить"},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.limit, tt.text); got != tt.want {
+			t.Errorf("Truncate(%d, %q) = %q, want %q", tt.limit, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type searchResponse struct {
+	Query string            `json:"query"`
+	Books []client.ListItem `json:"books"`
+}
+
+func (s *Server) apiSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		s.writeJSON(w, http.StatusBadRequest, apiError{"q parameter is required"})
+		return
+	}
+	books, err := s.search(query)
+	if err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	if books == nil {
+		books = []client.ListItem{}
+	}
+	s.writeJSON(w, http.StatusOK, searchResponse{Query: query, Books: books})
+}
+
+// apiBooks routes /books/{id} and /books/{id}/download/{format}
+func (s *Server) apiBooks(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/books/"), "/"), "/")
+	if (len(parts) == 1 && parts[0] != "" || len(parts) == 3) && !siteIDRe.MatchString(parts[0]) {
+		s.writeJSON(w, http.StatusBadRequest, apiError{"invalid id"})
+		return
+	}
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		info, err := s.info(parts[0])
+		if err != nil {
+			s.writeAPIError(w, err)
+			return
+		}
+		s.writeJSON(w, http.StatusOK, info)
+	case len(parts) == 3 && parts[1] == "download":
+		if !client.BookFormatRe.MatchString(parts[2]) {
+			s.writeJSON(w, http.StatusBadRequest, apiError{"invalid book format"})
+			return
+		}
+		result, err := s.Client.Download(parts[0], parts[2])
+		if err != nil {
+			s.writeAPIError(w, err)
+			return
+		}
+		s.serveBook(w, result, parts[2])
+	default:
+		s.writeJSON(w, http.StatusNotFound, apiError{"not found"})
+	}
+}
+
+func (s *Server) apiAuthor(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.apiID(w, r, "/authors/")
+	if !ok {
+		return
+	}
+	author, err := s.author(id)
+	if err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, author)
+}
+
+func (s *Server) apiSeries(w http.ResponseWriter, r *http.Request) {
+	id, ok := s.apiID(w, r, "/series/")
+	if !ok {
+		return
+	}
+	series, err := s.series(id)
+	if err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, series)
+}
+
+// apiDocument describes only endpoints which are served with the backend.
+func (s *Server) apiDocument(w http.ResponseWriter, r *http.Request) {
+	document := openAPIDocument
+	if !s.Backend.Supports(client.AuthorListing) || !s.Backend.Supports(client.SeriesListing) {
+		var err error
+		document, err = s.filterAPIDocument()
+		if err != nil {
+			s.httpError(w, err)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write(document)
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
+
+func (s *Server) filterAPIDocument() ([]byte, error) {
+	document := map[string]interface{}{}
+	err := json.Unmarshal(openAPIDocument, &document)
+	if err != nil {
+		return nil, err
+	}
+	paths, _ := document["paths"].(map[string]interface{})
+	if !s.Backend.Supports(client.AuthorListing) {
+		delete(paths, "/authors/{id}")
+	}
+	if !s.Backend.Supports(client.SeriesListing) {
+		delete(paths, "/series/{id}")
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// apiID is the single path element after prefix, it must be a number.
+func (s *Server) apiID(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/")
+	if id == "" || strings.Contains(id, "/") {
+		s.writeJSON(w, http.StatusNotFound, apiError{"not found"})
+		return "", false
+	}
+	if !siteIDRe.MatchString(id) {
+		s.writeJSON(w, http.StatusBadRequest, apiError{"invalid id"})
+		return "", false
+	}
+	return id, true
+}
+
+func (s *Server) writeAPIError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == http.StatusBadGateway {
+		s.logUpstreamError(err)
+	}
+	s.writeJSON(w, status, apiError{err.Error()})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(append(data, '\n'))
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
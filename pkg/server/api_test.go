@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+func apiServer(t *testing.T) *Server {
+	server := testServer(t)
+	server.API = true
+	return server
+}
+
+func TestServer_API(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantStatus int
+		want       string
+	}{
+		{"/search?q=test", http.StatusOK, `{"query":"test","books":[{"id":"1","title":"Нежить","authors":["Джон Джозеф Адамс"]},{"id":"2","title":"Сборник","authors":null,"links":[{"format":"pdf","type":"","href":"/b/2/download"}]}]}`},
+		{"/search", http.StatusBadRequest, `{"error":"q parameter is required"}`},
+		{"/books/1", http.StatusOK, `{"id":"1","title":"Нежить","authors":null,"genre":"","series":"","series_number":"","year":"","annotation":"","size":"","formats":["fb2","epub"],"cover":"/i/1/cover.jpg"}`},
+		{"/books/404", http.StatusNotFound, `{"error":"nothing found"}`},
+		{"/books/1/download/EPUB!", http.StatusBadRequest, `{"error":"invalid book format"}`},
+		{"/books/1/read", http.StatusNotFound, `{"error":"not found"}`},
+		{"/books/abc", http.StatusBadRequest, `{"error":"invalid id"}`},
+		{"/books/abc/download/fb2", http.StatusBadRequest, `{"error":"invalid id"}`},
+		{"/authors/a1", http.StatusBadRequest, `{"error":"invalid id"}`},
+		{"/authors/1", http.StatusNotFound, `{"error":"nothing found"}`},
+		{"/series/7", http.StatusOK, `{"id":"7","name":"Остап Бендер","books":[{"id":"3","title":"Двенадцать стульев","authors":null}]}`},
+		{"/series/", http.StatusNotFound, `{"error":"not found"}`},
+	}
+	server := apiServer(t)
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			recorder := get(t, server, tt.target)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			if got := recorder.Body.String(); got != tt.want+"\n" {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// Request paths are cleaned by the mux, handler must reject ids with dots anyway
+func TestServer_APIDotID(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	apiServer(t).apiBooks(recorder, httptest.NewRequest(http.MethodGet, "/books/../download/fb2", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, body %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServer_APIDisabled(t *testing.T) {
+	if code := get(t, testServer(t), "/search?q=test").Code; code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 without --api", code)
+	}
+}
+
+func TestServer_APIDownload(t *testing.T) {
+	recorder := get(t, apiServer(t), "/books/1/download/fb2")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "book 1" {
+		t.Errorf("download = %d %q", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServer_APICache(t *testing.T) {
+	server := apiServer(t)
+	for i := 0; i < 3; i++ {
+		get(t, server, "/books/1")
+	}
+	if calls := server.Client.(*testClient).infoCalls; calls != 1 {
+		t.Errorf("Info() called %d times, want 1", calls)
+	}
+}
+
+func apiPaths(t *testing.T, server *Server) []string {
+	recorder := get(t, server, "/openapi.json")
+	document := struct {
+		Paths map[string]interface{} `json:"paths"`
+	}{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for path := range document.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	paths := apiPaths(t, apiServer(t))
+	want := []string{"/authors/{id}", "/books/{id}", "/books/{id}/download/{format}", "/search", "/series/{id}"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
+
+// HTML backend only searches, listings are neither advertised nor served
+func TestServer_HTMLBackend(t *testing.T) {
+	server := apiServer(t)
+	server.Backend, _ = client.NewBackend(client.HTMLBackend, &client.FlibustaClient{})
+
+	paths := apiPaths(t, server)
+	want := []string{"/books/{id}", "/books/{id}/download/{format}", "/search"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	for _, target := range []string{"/authors/1", "/series/7", "/opds/author/1", "/opds/series/7", "/opds/new"} {
+		if code := get(t, server, target).Code; code != http.StatusNotFound {
+			t.Errorf("%s status = %d, want 404", target, code)
+		}
+	}
+	result := readFeed(t, get(t, server, "/opds"))
+	if len(result.Entries) != 1 || result.Entries[0].Links[0].Href != "/opds/library" {
+		t.Errorf("root entries = %+v, want only library", result.Entries)
+	}
+}
+
+var _ Client = &client.FlibustaClient{}
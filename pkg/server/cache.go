@@ -0,0 +1,97 @@
+package server
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCacheTTL = 10 * time.Minute
+	maxCacheEntries = 1000
+)
+
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// call is a fetch in progress, other requests of the key wait for its result.
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cache keeps listings and book info shared by all handlers, so repeated requests
+// from devices and tools do not go to mirrors again. Errors are not cached.
+// The least recently used entries are dropped when the cache is full.
+type cache struct {
+	mu  sync.Mutex
+	ttl time.Duration
+	now func() time.Time
+	// order lists entries from the most to the least recently used
+	order   *list.List
+	entries map[string]*list.Element
+	calls   map[string]*call
+}
+
+func newCache(ttl time.Duration, now func() time.Time) *cache {
+	return &cache{
+		ttl:     ttl,
+		now:     now,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+		calls:   map[string]*call{},
+	}
+}
+
+// get returns cached value or fetches it, concurrent requests of the same key share one fetch.
+func (c *cache) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	if c.ttl <= 0 {
+		return fetch()
+	}
+	c.mu.Lock()
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if c.now().Before(entry.expires) {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			return entry.value, nil
+		}
+		c.remove(element)
+	}
+	if running, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-running.done
+		return running.value, running.err
+	}
+	running := &call{done: make(chan struct{})}
+	c.calls[key] = running
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		if running.err == nil {
+			c.add(key, running.value)
+		}
+		c.mu.Unlock()
+		close(running.done)
+	}()
+	running.value, running.err = fetch()
+	return running.value, running.err
+}
+
+func (c *cache) add(key string, value interface{}) {
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: c.now().Add(c.ttl)})
+	for c.order.Len() > maxCacheEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *cache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
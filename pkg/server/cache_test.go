@@ -0,0 +1,94 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache_Get(t *testing.T) {
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	c := newCache(time.Minute, func() time.Time { return now })
+	calls := 0
+	fetch := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := c.get("key", fetch)
+		if err != nil || got != 1 {
+			t.Fatalf("get() = %v, %v, want cached 1", got, err)
+		}
+	}
+	now = now.Add(2 * time.Minute)
+	if got, _ := c.get("key", fetch); got != 2 {
+		t.Errorf("get() after expiration = %v, want 2", got)
+	}
+
+	failed := errors.New("failed")
+	_, err := c.get("error", func() (interface{}, error) { return nil, failed })
+	if err != failed {
+		t.Errorf("get() error = %v", err)
+	}
+	if _, ok := c.entries["error"]; ok {
+		t.Error("error is cached")
+	}
+}
+
+func TestCache_GetConcurrent(t *testing.T) {
+	c := newCache(time.Minute, time.Now)
+	var fetches int32
+	release := make(chan struct{})
+	fetch := func() (interface{}, error) {
+		atomic.AddInt32(&fetches, 1)
+		<-release
+		return "info", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]interface{}, 2)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = c.get("info:1", fetch)
+		}(i)
+	}
+	// Both requests are waiting for the site before it answers
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want 1", fetches)
+	}
+	for i, result := range results {
+		if result != "info" {
+			t.Errorf("result %d = %v", i, result)
+		}
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	c := newCache(time.Minute, time.Now)
+	value := func() (interface{}, error) { return "value", nil }
+	for i := 0; i < maxCacheEntries; i++ {
+		c.get(strconv.Itoa(i), value)
+	}
+	// Recently used entry is kept, the least recently used one is dropped
+	c.get("0", value)
+	c.get("new", value)
+
+	if len(c.entries) != maxCacheEntries {
+		t.Errorf("entries = %d, want %d", len(c.entries), maxCacheEntries)
+	}
+	for key, want := range map[string]bool{"0": true, "1": false, "2": true, "new": true} {
+		if _, ok := c.entries[key]; ok != want {
+			t.Errorf("entry %s cached = %v, want %v", key, ok, want)
+		}
+	}
+}
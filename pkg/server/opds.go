@@ -0,0 +1,350 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+)
+
+const (
+	opdsPath        = "/opds"
+	atomNamespace   = "http://www.w3.org/2005/Atom"
+	opdsNamespace   = "http://opds-spec.org/2010/catalog"
+	acquisitionRel  = "http://opds-spec.org/acquisition"
+	navigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	acquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	openSearchType  = "application/opensearchdescription+xml"
+)
+
+// Formats offered for books when backend does not know acquisition links
+var defaultFormats = []string{client.Fb2, client.Epub, client.Mobi}
+
+var formatMediaTypes = map[string]string{
+	client.Fb2:    "application/x-fictionbook+xml",
+	client.Fb2Zip: "application/fb2+zip",
+	client.Epub:   "application/epub+zip",
+	client.Mobi:   "application/x-mobipocket-ebook",
+	"pdf":         "application/pdf",
+	"djvu":        "image/vnd.djvu",
+	"rtf":         "application/rtf",
+	"doc":         "application/msword",
+	"txt":         "text/plain",
+	"html":        "text/html",
+}
+
+type feed struct {
+	XMLName xml.Name `xml:"feed"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Opds    string   `xml:"xmlns:opds,attr"`
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Links   []link   `xml:"link"`
+	Entries []entry  `xml:"entry"`
+	kind    string
+}
+
+type entry struct {
+	ID      string   `xml:"id"`
+	Title   string   `xml:"title"`
+	Updated string   `xml:"updated"`
+	Authors []author `xml:"author"`
+	Content *content `xml:"content,omitempty"`
+	Links   []link   `xml:"link"`
+}
+
+type author struct {
+	Name string `xml:"name"`
+}
+
+type content struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+type link struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+type openSearchDescription struct {
+	XMLName     xml.Name      `xml:"OpenSearchDescription"`
+	Xmlns       string        `xml:"xmlns,attr"`
+	ShortName   string        `xml:"ShortName"`
+	Description string        `xml:"Description"`
+	Url         openSearchUrl `xml:"Url"`
+}
+
+type openSearchUrl struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+func mediaType(format string, zipped bool) string {
+	if zipped && (format == client.Fb2 || format == client.Fb2Zip) {
+		return formatMediaTypes[client.Fb2Zip]
+	}
+	if known, ok := formatMediaTypes[format]; ok {
+		return known
+	}
+	return "application/octet-stream"
+}
+
+// opdsRoot is navigation feed linking search, new arrivals when backend lists them and local library.
+func (s *Server) opdsRoot(w http.ResponseWriter, r *http.Request) {
+	root := s.newFeed("root", s.Title, navigationType)
+	root.Links = append(root.Links, link{Rel: "search", Href: opdsPath + "/opensearch.xml", Type: openSearchType})
+	root.Entries = []entry{}
+	if s.Backend.Supports(client.NewListing) {
+		root.Entries = append(root.Entries, s.navigationEntry("new", "Новые поступления", opdsPath+"/new"))
+	}
+	if s.LibraryFile != "" {
+		root.Entries = append(root.Entries, s.navigationEntry("library", "Моя библиотека", opdsPath+"/library"))
+	}
+	s.writeFeed(w, root)
+}
+
+// opds routes /opds/<kind>/<id>[/<format>], listings not supported by backend are not found
+func (s *Server) opds(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, opdsPath), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		s.opdsRoot(w, r)
+	case len(parts) == 1 && parts[0] == "opensearch.xml":
+		s.openSearch(w, r)
+	case len(parts) == 1 && parts[0] == "search":
+		s.opdsSearch(w, r)
+	case len(parts) == 1 && parts[0] == "new" && s.Backend.Supports(client.NewListing):
+		s.opdsNew(w, r)
+	case len(parts) == 1 && parts[0] == "library":
+		s.libraryFeed(w, r)
+	case len(parts) == 2 && parts[0] == "author" && s.Backend.Supports(client.AuthorListing):
+		s.opdsAuthor(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "series" && s.Backend.Supports(client.SeriesListing):
+		s.opdsSeries(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "book":
+		s.download(w, parts[1], parts[2])
+	case len(parts) == 3 && parts[0] == "library":
+		s.libraryFile(w, r, parts[1], parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) openSearch(w http.ResponseWriter, r *http.Request) {
+	description := openSearchDescription{
+		Xmlns:       "http://a9.com/-/spec/opensearch/1.1/",
+		ShortName:   s.Title,
+		Description: "Поиск книг",
+		Url: openSearchUrl{
+			Type:     acquisitionType,
+			Template: opdsPath + "/search?q={searchTerms}",
+		},
+	}
+	s.writeXML(w, openSearchType, description)
+}
+
+func (s *Server) opdsSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "q parameter is required", http.StatusBadRequest)
+		return
+	}
+	books, err := s.search(query)
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	s.writeBooks(w, "search:"+query, "Поиск: "+query, books)
+}
+
+func (s *Server) opdsNew(w http.ResponseWriter, r *http.Request) {
+	books, err := s.newArrivals()
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	s.writeBooks(w, "new", "Новые поступления", books)
+}
+
+func (s *Server) opdsAuthor(w http.ResponseWriter, r *http.Request, id string) {
+	if !checkID(w, id) {
+		return
+	}
+	result, err := s.author(id)
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	s.writeBooks(w, "author:"+id, result.Name, result.Books)
+}
+
+func (s *Server) opdsSeries(w http.ResponseWriter, r *http.Request, id string) {
+	if !checkID(w, id) {
+		return
+	}
+	result, err := s.series(id)
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	s.writeBooks(w, "series:"+id, result.Name, result.Books)
+}
+
+func (s *Server) libraryFeed(w http.ResponseWriter, r *http.Request) {
+	books, err := s.library()
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	result := s.newFeed("library", "Моя библиотека", acquisitionType)
+	for _, book := range books.List() {
+		if !book.Exists() {
+			continue
+		}
+		result.Entries = append(result.Entries, s.libraryEntry(book))
+	}
+	s.writeFeed(w, result)
+}
+
+func (s *Server) libraryFile(w http.ResponseWriter, r *http.Request, id string, format string) {
+	books, err := s.library()
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	book := books.Owned(id, format)
+	if book == nil {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(book.Path)
+	if err != nil {
+		s.httpError(w, fmt.Errorf("%w: %v", client.ErrNotFound, err))
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", mediaType(book.Format, strings.HasSuffix(book.Path, ".zip")))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(path.Base(book.Path))))
+	http.ServeContent(w, r, "", stat.ModTime(), file)
+}
+
+func (s *Server) writeBooks(w http.ResponseWriter, id string, title string, books []client.ListItem) {
+	result := s.newFeed(id, title, acquisitionType)
+	for _, book := range books {
+		result.Entries = append(result.Entries, s.bookEntry(book))
+	}
+	s.writeFeed(w, result)
+}
+
+func (s *Server) newFeed(id string, title string, kind string) *feed {
+	return &feed{
+		Xmlns:   atomNamespace,
+		Opds:    opdsNamespace,
+		ID:      "urn:flibusta-cli:" + id,
+		Title:   title,
+		Updated: s.updated(),
+		Links:   []link{{Rel: "start", Href: opdsPath, Type: navigationType}},
+		kind:    kind,
+	}
+}
+
+func (s *Server) navigationEntry(id string, title string, href string) entry {
+	return entry{
+		ID:      "urn:flibusta-cli:" + id,
+		Title:   title,
+		Updated: s.updated(),
+		Links:   []link{{Rel: "subsection", Href: href, Type: acquisitionType}},
+	}
+}
+
+// bookEntry links every format to download proxy.
+func (s *Server) bookEntry(book client.ListItem) entry {
+	result := entry{
+		ID:      "urn:flibusta:book:" + book.ID,
+		Title:   book.Title,
+		Updated: s.updated(),
+		Authors: authors(book.Authors),
+	}
+	formats := defaultFormats
+	if len(book.Links) > 0 {
+		formats = nil
+		for _, bookLink := range book.Links {
+			formats = append(formats, bookLink.Format)
+		}
+	}
+	for _, format := range formats {
+		result.Links = append(result.Links, link{
+			Rel:   acquisitionRel,
+			Href:  path.Join(opdsPath, "book", book.ID, format),
+			Type:  mediaType(format, format == client.Fb2),
+			Title: format,
+		})
+	}
+	return result
+}
+
+func (s *Server) libraryEntry(book *library.Entry) entry {
+	result := entry{
+		ID:      "urn:flibusta:book:" + book.ID,
+		Title:   book.Info.Title,
+		Updated: book.Added.UTC().Format(time.RFC3339),
+		Authors: authors(book.Info.Authors),
+		Links: []link{{
+			Rel:   acquisitionRel,
+			Href:  path.Join(opdsPath, "library", book.ID, book.Format),
+			Type:  mediaType(book.Format, strings.HasSuffix(book.Path, ".zip")),
+			Title: book.Format,
+		}},
+	}
+	if result.Title == "" {
+		result.Title = path.Base(book.Path)
+	}
+	if book.Info.Annotation != "" {
+		result.Content = &content{Type: "text", Text: book.Info.Annotation}
+	}
+	return result
+}
+
+func authors(names []string) (result []author) {
+	for _, name := range names {
+		result = append(result, author{Name: name})
+	}
+	return result
+}
+
+func (s *Server) updated() string {
+	return s.now().UTC().Format(time.RFC3339)
+}
+
+func (s *Server) writeFeed(w http.ResponseWriter, result *feed) {
+	s.writeXML(w, result.kind+";charset=utf-8", result)
+}
+
+func (s *Server) writeXML(w http.ResponseWriter, contentType string, value interface{}) {
+	data, err := xml.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, err = w.Write(append([]byte(xml.Header), data...))
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
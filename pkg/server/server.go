@@ -0,0 +1,284 @@
+// Package server serves the library to other devices and programs over HTTP.
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+)
+
+const (
+	ListenEnvKey  = "FLIBUSTA_LISTEN"
+	DefaultListen = ":8080"
+)
+
+// Client fetches book pages and files, FlibustaClient does it through configured proxy.
+type Client interface {
+	Info(id string, respProcessor func(stream io.Reader) (*client.InfoResult, error)) (*client.InfoResult, error)
+	Download(id string, bookFormat string) (*client.DownloadResult, error)
+	Cover(info *client.InfoResult) (*client.DownloadResult, error)
+}
+
+// Server answers requests with listings of the backend and files of the local library.
+type Server struct {
+	Title   string
+	Backend client.Backend
+	Client  Client
+	// Library file is read on every request, so books saved by `get` show up without restart.
+	// Empty name disables the library catalog.
+	LibraryFile string
+	// API enables JSON endpoints next to OPDS catalog
+	API bool
+	// Web enables browser UI at /ui/
+	Web bool
+	// Logger gets served requests and failures, it writes to stderr by default
+	Logger client.Logger
+
+	now   func() time.Time
+	cache *cache
+}
+
+func New(backend client.Backend, flibusta Client, libraryFile string) *Server {
+	return &Server{
+		Title:       "Flibusta",
+		Backend:     backend,
+		Client:      flibusta,
+		LibraryFile: libraryFile,
+		Logger:      client.NewTextLogger(os.Stderr, client.LevelInfo),
+		now:         time.Now,
+		cache:       newCache(DefaultCacheTTL, time.Now),
+	}
+}
+
+// SetCacheTTL changes how long listings and book info are reused, zero disables the cache.
+func (s *Server) SetCacheTTL(ttl time.Duration) {
+	s.cache = newCache(ttl, s.now)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(opdsPath, s.opdsRoot)
+	mux.HandleFunc(opdsPath+"/", s.opds)
+	if s.API {
+		mux.HandleFunc("/search", s.apiSearch)
+		mux.HandleFunc("/books/", s.apiBooks)
+		if s.Backend.Supports(client.AuthorListing) {
+			mux.HandleFunc("/authors/", s.apiAuthor)
+		}
+		if s.Backend.Supports(client.SeriesListing) {
+			mux.HandleFunc("/series/", s.apiSeries)
+		}
+		mux.HandleFunc("/openapi.json", s.apiDocument)
+	}
+	start := opdsPath
+	if s.Web {
+		mux.HandleFunc(webPath, s.web)
+		start = webPath
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, start, http.StatusFound)
+	})
+	return s.logRequests(mux)
+}
+
+// ListenAndServe blocks serving requests on the address, like `:8080`.
+func (s *Server) ListenAndServe(addr string) error {
+	s.Logger.Log(client.LevelInfo, "serve OPDS catalog", client.Field{Key: "url", Value: "http://" + displayAddr(addr) + opdsPath})
+	if s.API {
+		s.Logger.Log(client.LevelInfo, "serve JSON API", client.Field{Key: "url", Value: "http://" + displayAddr(addr)}, client.Field{Key: "document", Value: "/openapi.json"})
+	}
+	if s.Web {
+		s.Logger.Log(client.LevelInfo, "serve web UI", client.Field{Key: "url", Value: "http://" + displayAddr(addr) + webPath})
+	}
+	return http.ListenAndServe(addr, s.Handler())
+}
+
+func displayAddr(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+// statusRecorder remembers response status and size for request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(data)
+	r.size += n
+	return n, err
+}
+
+// logRequests logs every request with fields remote, method, path, status, bytes and duration.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		s.Logger.Log(client.LevelInfo, "served",
+			client.Field{Key: "remote", Value: r.RemoteAddr},
+			client.Field{Key: "method", Value: r.Method},
+			client.Field{Key: "path", Value: r.URL.RequestURI()},
+			client.Field{Key: "status", Value: recorder.status},
+			client.Field{Key: "bytes", Value: recorder.size},
+			client.Field{Key: "duration", Value: time.Since(start).Round(time.Millisecond)},
+		)
+	})
+}
+
+// logWriteError logs response which could not be sent, usually client has gone.
+func (s *Server) logWriteError(err error) {
+	s.Logger.Log(client.LevelWarn, "cannot write response", client.Field{Key: "error", Value: err})
+}
+
+// logUpstreamError logs failure of the site, the client gets bad gateway status.
+func (s *Server) logUpstreamError(err error) {
+	s.Logger.Log(client.LevelError, "site request failed", client.Field{Key: "error", Value: err})
+}
+
+func (s *Server) library() (*library.Library, error) {
+	if s.LibraryFile == "" {
+		return nil, errLibraryDisabled
+	}
+	return library.Load(s.LibraryFile)
+}
+
+var errLibraryDisabled = errors.New("library is disabled")
+
+func (s *Server) search(query string) ([]client.ListItem, error) {
+	value, err := s.cache.get("search:"+query, func() (interface{}, error) {
+		return s.Backend.Search(query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *value.(*[]client.ListItem), nil
+}
+
+func (s *Server) newArrivals() ([]client.ListItem, error) {
+	value, err := s.cache.get("new", func() (interface{}, error) {
+		return s.Backend.NewArrivals()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *value.(*[]client.ListItem), nil
+}
+
+func (s *Server) author(id string) (*client.AuthorResult, error) {
+	value, err := s.cache.get("author:"+id, func() (interface{}, error) {
+		return s.Backend.Author(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*client.AuthorResult), nil
+}
+
+func (s *Server) series(id string) (*client.SeriesResult, error) {
+	value, err := s.cache.get("series:"+id, func() (interface{}, error) {
+		return s.Backend.Series(id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*client.SeriesResult), nil
+}
+
+func (s *Server) info(id string) (*client.InfoResult, error) {
+	value, err := s.cache.get("info:"+id, func() (interface{}, error) {
+		return s.Client.Info(id, client.ParseInfo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*client.InfoResult), nil
+}
+
+// siteIDRe matches ids of books, authors and series on the site
+var siteIDRe = regexp.MustCompile(`^[0-9]+$`)
+
+// checkID rejects ids which are not numbers, so requests never put them into site URLs and cache keys.
+func checkID(w http.ResponseWriter, id string) bool {
+	if siteIDRe.MatchString(id) {
+		return true
+	}
+	http.Error(w, "invalid id", http.StatusBadRequest)
+	return false
+}
+
+// errorStatus maps client errors to HTTP status codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, client.ErrNotFound), errors.Is(err, errLibraryDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, client.ErrUnsupported):
+		return http.StatusNotImplemented
+	}
+	return http.StatusBadGateway
+}
+
+func (s *Server) httpError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == http.StatusBadGateway {
+		s.logUpstreamError(err)
+	}
+	http.Error(w, err.Error(), status)
+}
+
+// serveBook writes downloaded book as attachment.
+func (s *Server) serveBook(w http.ResponseWriter, result *client.DownloadResult, format string) {
+	name := result.Name
+	if name == "" {
+		name = "book." + format
+	}
+	w.Header().Set("Content-Type", mediaType(format, result.IsZipped()))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(name)))
+	w.Header().Set("Content-Length", fmt.Sprint(len(result.File)))
+	_, err := w.Write(result.File)
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
+
+// download proxies book file from mirrors, so devices do not need Tor.
+func (s *Server) download(w http.ResponseWriter, id string, format string) {
+	if !checkID(w, id) {
+		return
+	}
+	if !client.BookFormatRe.MatchString(format) {
+		http.Error(w, "invalid book format", http.StatusBadRequest)
+		return
+	}
+	result, err := s.Client.Download(id, format)
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	s.serveBook(w, result, format)
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/library"
+)
+
+type testBackend struct{}
+
+func (b *testBackend) Name() string {
+	return "test"
+}
+
+// Supports all listings except new arrivals
+func (b *testBackend) Supports(listing string) bool {
+	return listing != client.NewListing
+}
+
+func (b *testBackend) Search(query string) (*[]client.ListItem, error) {
+	return &[]client.ListItem{
+		{ID: "1", Title: "Нежить", Authors: []string{"Джон Джозеф Адамс"}},
+		{ID: "2", Title: "Сборник", Links: []client.Link{{Format: "pdf", Href: "/b/2/download"}}},
+	}, nil
+}
+
+func (b *testBackend) Author(id string) (*client.AuthorResult, error) {
+	return nil, client.ErrNotFound
+}
+
+func (b *testBackend) Series(id string) (*client.SeriesResult, error) {
+	return &client.SeriesResult{ID: id, Name: "Остап Бендер", Books: []client.ListItem{{ID: "3", Title: "Двенадцать стульев"}}}, nil
+}
+
+func (b *testBackend) NewArrivals() (*[]client.ListItem, error) {
+	return nil, client.ErrUnsupported
+}
+
+// recordingLogger keeps messages with their fields
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Log(level client.Level, message string, fields ...client.Field) {
+	line := level.String() + " " + message
+	for _, field := range fields {
+		if field.Key != "duration" {
+			line += fmt.Sprintf(" %s=%v", field.Key, field.Value)
+		}
+	}
+	l.messages = append(l.messages, line)
+}
+
+type testClient struct {
+	infoCalls int
+}
+
+func (c *testClient) Info(id string, respProcessor func(stream io.Reader) (*client.InfoResult, error)) (*client.InfoResult, error) {
+	c.infoCalls++
+	if id == "404" {
+		return nil, client.ErrNotFound
+	}
+	return &client.InfoResult{ID: id, Title: "Нежить", Formats: []string{client.Fb2, client.Epub}, Cover: "/i/1/cover.jpg"}, nil
+}
+
+func (c *testClient) Cover(info *client.InfoResult) (*client.DownloadResult, error) {
+	return &client.DownloadResult{Name: "cover.jpg", File: []byte("\xff\xd8\xff\xe0cover")}, nil
+}
+
+func (c *testClient) Download(id string, bookFormat string) (*client.DownloadResult, error) {
+	return &client.DownloadResult{Name: id + "." + bookFormat, File: []byte("book " + id)}, nil
+}
+
+func testServer(t *testing.T) *Server {
+	dir := t.TempDir()
+	bookFile := filepath.Join(dir, "book.epub")
+	err := os.WriteFile(bookFile, []byte("epub"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books, err := library.Load(filepath.Join(dir, "library.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := library.NewEntry(client.InfoResult{ID: "10", Title: "Моя книга", Annotation: "Аннотация"}, client.Epub, bookFile, []byte("epub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	books.Add(entry)
+	missing, _ := library.NewEntry(client.InfoResult{ID: "11", Title: "Удалена"}, client.Epub, filepath.Join(dir, "missing.epub"), nil)
+	books.Add(missing)
+	err = books.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := New(&testBackend{}, &testClient{}, filepath.Join(dir, "library.json"))
+	server.Logger = &recordingLogger{}
+	server.now = func() time.Time { return time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC) }
+	return server
+}
+
+func get(t *testing.T, server *Server, target string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	return recorder
+}
+
+func readFeed(t *testing.T, recorder *httptest.ResponseRecorder) *feed {
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", recorder.Code, recorder.Body.String())
+	}
+	result := &feed{}
+	err := xml.Unmarshal(recorder.Body.Bytes(), result)
+	if err != nil {
+		t.Fatalf("cannot parse feed: %v", err)
+	}
+	return result
+}
+
+func TestServer_Root(t *testing.T) {
+	server := testServer(t)
+	result := readFeed(t, get(t, server, "/opds"))
+	var hrefs []string
+	for _, e := range result.Entries {
+		hrefs = append(hrefs, e.Links[0].Href)
+	}
+	// Test backend does not list new arrivals
+	if !reflect.DeepEqual(hrefs, []string{"/opds/library"}) {
+		t.Errorf("root entries = %v", hrefs)
+	}
+
+	server.LibraryFile = ""
+	result = readFeed(t, get(t, server, "/opds/"))
+	if len(result.Entries) != 0 {
+		t.Errorf("root entries without library = %v", result.Entries)
+	}
+
+	if code := get(t, server, "/").Code; code != http.StatusFound {
+		t.Errorf("/ status = %d, want redirect", code)
+	}
+}
+
+func TestServer_Search(t *testing.T) {
+	result := readFeed(t, get(t, testServer(t), "/opds/search?q=%D0%9D%D0%B5%D0%B6%D0%B8%D1%82%D1%8C"))
+	if result.Title != "Поиск: Нежить" || len(result.Entries) != 2 {
+		t.Fatalf("search feed = %+v", result)
+	}
+	want := []link{
+		{Rel: acquisitionRel, Href: "/opds/book/1/fb2", Type: "application/fb2+zip", Title: "fb2"},
+		{Rel: acquisitionRel, Href: "/opds/book/1/epub", Type: "application/epub+zip", Title: "epub"},
+		{Rel: acquisitionRel, Href: "/opds/book/1/mobi", Type: "application/x-mobipocket-ebook", Title: "mobi"},
+	}
+	if !reflect.DeepEqual(result.Entries[0].Links, want) {
+		t.Errorf("default links = %+v, want %+v", result.Entries[0].Links, want)
+	}
+	if got := result.Entries[1].Links[0].Href; got != "/opds/book/2/pdf" {
+		t.Errorf("backend link = %v", got)
+	}
+	if result.Entries[0].Authors[0].Name != "Джон Джозеф Адамс" || result.Entries[0].Updated != "2021-06-01T00:00:00Z" {
+		t.Errorf("entry = %+v", result.Entries[0])
+	}
+}
+
+func TestServer_Status(t *testing.T) {
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/opds/search", http.StatusBadRequest},
+		{"/opds/author/1", http.StatusNotFound},
+		{"/opds/series/7", http.StatusOK},
+		{"/opds/new", http.StatusNotFound},
+		{"/opds/opensearch.xml", http.StatusOK},
+		{"/opds/book/1/EPUB!", http.StatusBadRequest},
+		{"/opds/book/abc/fb2", http.StatusBadRequest},
+		{"/opds/series/7abc", http.StatusBadRequest},
+		{"/opds/library/11/epub", http.StatusNotFound},
+		{"/opds/library/10/fb2", http.StatusNotFound},
+		{"/opds/unknown", http.StatusNotFound},
+	}
+	server := testServer(t)
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := get(t, server, tt.target).Code; got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Download(t *testing.T) {
+	recorder := get(t, testServer(t), "/opds/book/1/epub")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "book 1" {
+		t.Fatalf("download = %d %q", recorder.Code, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Content-Type"); got != "application/epub+zip" {
+		t.Errorf("Content-Type = %v", got)
+	}
+	if got := recorder.Header().Get("Content-Disposition"); !strings.Contains(got, "1.epub") {
+		t.Errorf("Content-Disposition = %v", got)
+	}
+}
+
+func TestServer_Library(t *testing.T) {
+	server := testServer(t)
+	result := readFeed(t, get(t, server, "/opds/library"))
+	// Entry of deleted file is not listed
+	if len(result.Entries) != 1 || result.Entries[0].Title != "Моя книга" || result.Entries[0].Content.Text != "Аннотация" {
+		t.Fatalf("library feed = %+v", result.Entries)
+	}
+	href := result.Entries[0].Links[0].Href
+	recorder := get(t, server, href)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "epub" {
+		t.Errorf("library file %s = %d %q", href, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestServer_Log(t *testing.T) {
+	server := testServer(t)
+	get(t, server, "/opds/series/7")
+	get(t, server, "/opds/unknown")
+	want := []string{
+		"INFO served remote=192.0.2.1:1234 method=GET path=/opds/series/7 status=200 bytes=",
+		"INFO served remote=192.0.2.1:1234 method=GET path=/opds/unknown status=404 bytes=19",
+	}
+	messages := server.Logger.(*recordingLogger).messages
+	if len(messages) != len(want) {
+		t.Fatalf("messages = %q", messages)
+	}
+	for i := range want {
+		if !strings.HasPrefix(messages[i], want[i]) {
+			t.Errorf("message = %q, want %q", messages[i], want[i])
+		}
+	}
+}
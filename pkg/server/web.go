@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+const webPath = "/ui/"
+
+//go:embed web
+var webFiles embed.FS
+
+var webTemplates = template.Must(template.New("web").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).ParseFS(webFiles, "web/*.html"))
+
+// page is data of every web UI template.
+type page struct {
+	Site  string
+	Title string
+	Query string
+	Error string
+	Books []client.ListItem
+	Book  *client.InfoResult
+}
+
+// web routes /ui/, /ui/book/{id}, /ui/cover/{id}, /ui/download/{id}?format= and /ui/static/
+func (s *Server) web(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, webPath), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		s.webSearch(w, r)
+	case len(parts) == 2 && parts[0] == "book":
+		s.webBook(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "cover":
+		s.webCover(w, r, parts[1])
+	case len(parts) == 2 && parts[0] == "download":
+		s.download(w, parts[1], r.URL.Query().Get("format"))
+	case len(parts) == 2 && parts[0] == "static":
+		static, _ := fs.Sub(webFiles, "web/static")
+		http.StripPrefix(webPath+"static/", http.FileServer(http.FS(static))).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) webSearch(w http.ResponseWriter, r *http.Request) {
+	data := &page{Site: s.Title, Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+	status := http.StatusOK
+	if data.Query != "" {
+		data.Title = data.Query
+		books, err := s.search(data.Query)
+		if err != nil {
+			status = errorStatus(err)
+			data.Error = err.Error()
+		}
+		data.Books = books
+	}
+	s.renderPage(w, status, "search.html", data)
+}
+
+func (s *Server) webBook(w http.ResponseWriter, r *http.Request, id string) {
+	if !checkID(w, id) {
+		return
+	}
+	data := &page{Site: s.Title}
+	info, err := s.info(id)
+	if err != nil {
+		data.Error = err.Error()
+		s.renderPage(w, errorStatus(err), "book.html", data)
+		return
+	}
+	data.Title = info.Title
+	data.Book = info
+	s.renderPage(w, http.StatusOK, "book.html", data)
+}
+
+func (s *Server) webCover(w http.ResponseWriter, r *http.Request, id string) {
+	if !checkID(w, id) {
+		return
+	}
+	info, err := s.info(id)
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	value, err := s.cache.get("cover:"+id, func() (interface{}, error) {
+		return s.Client.Cover(info)
+	})
+	if err != nil {
+		s.httpError(w, err)
+		return
+	}
+	cover := value.(*client.DownloadResult)
+	w.Header().Set("Content-Type", http.DetectContentType(cover.File))
+	w.Header().Set("Cache-Control", "max-age=86400")
+	_, err = w.Write(cover.File)
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
+
+// renderPage executes template to buffer first, so template error does not leave half written page.
+func (s *Server) renderPage(w http.ResponseWriter, status int, name string, data *page) {
+	buf := &bytes.Buffer{}
+	err := webTemplates.ExecuteTemplate(buf, name, data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		s.logWriteError(err)
+	}
+}
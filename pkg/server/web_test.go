@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func webServer(t *testing.T) *Server {
+	server := testServer(t)
+	server.Web = true
+	return server
+}
+
+func TestServer_Web(t *testing.T) {
+	tests := []struct {
+		target     string
+		wantStatus int
+		wantBody   []string
+	}{
+		{"/ui/", http.StatusOK, []string{`<input type="search" name="q" value=""`}},
+		{"/ui/?q=%3Cb%3E", http.StatusOK, []string{`value="&lt;b&gt;"`, `<a href="/ui/book/1">Нежить</a>`, "Джон Джозеф Адамс"}},
+		{"/ui/book/1", http.StatusOK, []string{`<img class="cover" src="/ui/cover/1"`, `<option value="epub">epub</option>`, `action="/ui/download/1"`}},
+		{"/ui/book/404", http.StatusNotFound, []string{`<p class="error">nothing found</p>`}},
+		{"/ui/download/1?format=epub", http.StatusOK, []string{"book 1"}},
+		{"/ui/download/1", http.StatusBadRequest, nil},
+		{"/ui/book/abc", http.StatusBadRequest, nil},
+		{"/ui/cover/abc", http.StatusBadRequest, nil},
+		{"/ui/download/abc?format=epub", http.StatusBadRequest, nil},
+		{"/ui/static/style.css", http.StatusOK, []string{".cover"}},
+		{"/ui/static/search.html", http.StatusNotFound, nil},
+		{"/ui/unknown", http.StatusNotFound, nil},
+	}
+	server := webServer(t)
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			recorder := get(t, server, tt.target)
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", recorder.Code, tt.wantStatus)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(recorder.Body.String(), want) {
+					t.Errorf("body does not contain %s:\n%s", want, recorder.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestServer_WebCover(t *testing.T) {
+	recorder := get(t, webServer(t), "/ui/cover/1")
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("cover = %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+}
+
+func TestServer_WebStart(t *testing.T) {
+	recorder := get(t, webServer(t), "/")
+	if location := recorder.Header().Get("Location"); location != webPath {
+		t.Errorf("/ redirects to %s, want %s", location, webPath)
+	}
+	if code := get(t, testServer(t), "/ui/").Code; code != http.StatusNotFound {
+		t.Errorf("/ui/ status = %d without --web", code)
+	}
+}
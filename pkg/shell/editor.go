@@ -0,0 +1,228 @@
+package shell
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slivtime/flibusta-cli/pkg/terminal"
+)
+
+// Completer returns words which may replace the last word of the line.
+type Completer func(line string) []string
+
+// Editor reads lines with history and completion. When input is not a terminal,
+// lines are read as is, so commands can be piped into the shell.
+type Editor struct {
+	History  []string
+	Complete Completer
+
+	in     *os.File
+	out    io.Writer
+	reader *bufio.Reader
+	keys   []terminal.Key
+	rest   []byte
+}
+
+func NewEditor(in *os.File, out io.Writer) *Editor {
+	return &Editor{in: in, out: out, reader: bufio.NewReader(in)}
+}
+
+// ReadLine returns next line without line break, io.EOF when input ends or Ctrl-D is pressed on empty line.
+func (e *Editor) ReadLine(prompt string) (string, error) {
+	restore, err := terminal.MakeRaw(int(e.in.Fd()))
+	if err != nil {
+		return e.readPlain(prompt)
+	}
+	defer restore()
+
+	state := &lineState{history: e.History, historyIndex: len(e.History), complete: e.Complete}
+	e.draw(prompt, state)
+	for {
+		k, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+		result := state.handle(k)
+		switch result {
+		case lineDone:
+			io.WriteString(e.out, "\r\n")
+			line := string(state.line)
+			e.remember(line)
+			return line, nil
+		case lineEOF:
+			io.WriteString(e.out, "\r\n")
+			return "", io.EOF
+		case lineCancel:
+			io.WriteString(e.out, "^C\r\n")
+			state = &lineState{history: e.History, historyIndex: len(e.History), complete: e.Complete}
+		case lineCandidates:
+			io.WriteString(e.out, "\r\n"+strings.Join(state.candidates, "  ")+"\r\n")
+		}
+		e.draw(prompt, state)
+	}
+}
+
+func (e *Editor) readPlain(prompt string) (string, error) {
+	line, err := e.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	e.remember(line)
+	return line, nil
+}
+
+func (e *Editor) readKey() (terminal.Key, error) {
+	for len(e.keys) == 0 {
+		buf := make([]byte, 64)
+		n, err := e.reader.Read(buf)
+		if err != nil {
+			return terminal.Key{}, err
+		}
+		e.keys, e.rest = terminal.ParseKeys(append(e.rest, buf[:n]...))
+	}
+	k := e.keys[0]
+	e.keys = e.keys[1:]
+	return k, nil
+}
+
+func (e *Editor) remember(line string) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+	if len(e.History) > 0 && e.History[len(e.History)-1] == line {
+		return
+	}
+	e.History = append(e.History, line)
+}
+
+// draw redraws the line and puts cursor to its position.
+func (e *Editor) draw(prompt string, state *lineState) {
+	text := "\r" + prompt + string(state.line) + "\x1b[K"
+	if back := len(state.line) - state.pos; back > 0 {
+		text += fmt.Sprintf("\x1b[%dD", back)
+	}
+	io.WriteString(e.out, text)
+}
+
+type lineResult int
+
+const (
+	lineEditing lineResult = iota
+	lineDone
+	lineEOF
+	lineCancel
+	lineCandidates
+)
+
+// lineState is the line being edited.
+type lineState struct {
+	line []rune
+	pos  int
+
+	history      []string
+	historyIndex int
+	// Line typed before browsing history
+	draft []rune
+
+	complete   Completer
+	candidates []string
+}
+
+func (s *lineState) handle(k terminal.Key) lineResult {
+	switch k.Code {
+	case terminal.KeyRune:
+		s.line = append(s.line[:s.pos], append([]rune{k.Rune}, s.line[s.pos:]...)...)
+		s.pos++
+	case terminal.KeyBackspace:
+		if s.pos > 0 {
+			s.line = append(s.line[:s.pos-1], s.line[s.pos:]...)
+			s.pos--
+		}
+	case terminal.KeyLeft:
+		if s.pos > 0 {
+			s.pos--
+		}
+	case terminal.KeyRight:
+		if s.pos < len(s.line) {
+			s.pos++
+		}
+	case terminal.KeyUp:
+		s.browse(-1)
+	case terminal.KeyDown:
+		s.browse(1)
+	case terminal.KeyTab:
+		return s.completeWord()
+	case terminal.KeyEnter:
+		return lineDone
+	case terminal.KeyQuit:
+		return lineCancel
+	case terminal.KeyEOF:
+		if len(s.line) == 0 {
+			return lineEOF
+		}
+	}
+	return lineEditing
+}
+
+func (s *lineState) browse(delta int) {
+	index := s.historyIndex + delta
+	if index < 0 || index > len(s.history) {
+		return
+	}
+	if s.historyIndex == len(s.history) {
+		s.draft = s.line
+	}
+	s.historyIndex = index
+	if index == len(s.history) {
+		s.line = s.draft
+	} else {
+		s.line = []rune(s.history[index])
+	}
+	s.pos = len(s.line)
+}
+
+// completeWord completes the word before cursor, when there are several candidates
+// their common prefix is inserted and candidates are listed.
+func (s *lineState) completeWord() lineResult {
+	if s.complete == nil {
+		return lineEditing
+	}
+	before := string(s.line[:s.pos])
+	candidates := s.complete(before)
+	if len(candidates) == 0 {
+		return lineEditing
+	}
+	word := before[strings.LastIndex(before, " ")+1:]
+	completion := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		completion += " "
+	}
+	if !strings.HasPrefix(completion, word) {
+		return lineEditing
+	}
+	insert := []rune(strings.TrimPrefix(completion, word))
+	s.line = append(s.line[:s.pos], append(insert, s.line[s.pos:]...)...)
+	s.pos += len(insert)
+	if len(candidates) > 1 && len(insert) == 0 {
+		s.candidates = candidates
+		return lineCandidates
+	}
+	return lineEditing
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
@@ -0,0 +1,162 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/terminal"
+)
+
+// pipeEditor reads input which is not a terminal
+func pipeEditor(t *testing.T, input string) *Editor {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	go func() {
+		io.WriteString(w, input)
+		w.Close()
+	}()
+	return NewEditor(r, io.Discard)
+}
+
+func TestEditor_ReadLinePipe(t *testing.T) {
+	editor := pipeEditor(t, "search Нежить\r\n\nsearch Нежить \nget #1")
+	var lines []string
+	for {
+		line, err := editor.ReadLine(Prompt)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	want := []string{"search Нежить", "", "search Нежить ", "get #1"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	// Empty and repeated lines are not remembered
+	if want := []string{"search Нежить", "get #1"}; !reflect.DeepEqual(editor.History, want) {
+		t.Errorf("History = %q, want %q", editor.History, want)
+	}
+}
+
+func typeKeys(s *lineState, text string) {
+	for _, r := range text {
+		s.handle(terminal.Key{Code: terminal.KeyRune, Rune: r})
+	}
+}
+
+func press(s *lineState, codes ...terminal.KeyCode) (result lineResult) {
+	for _, code := range codes {
+		result = s.handle(terminal.Key{Code: code})
+	}
+	return result
+}
+
+func TestLineState_Editing(t *testing.T) {
+	s := &lineState{}
+	typeKeys(s, "get 1")
+	press(s, terminal.KeyLeft, terminal.KeyLeft)
+	typeKeys(s, "ы")
+	press(s, terminal.KeyBackspace, terminal.KeyBackspace, terminal.KeyRight, terminal.KeyRight, terminal.KeyRight)
+	typeKeys(s, "2")
+	if string(s.line) != "ge 12" || s.pos != 5 {
+		t.Errorf("line = %q at %d", string(s.line), s.pos)
+	}
+	if result := press(s, terminal.KeyEOF); result != lineEditing {
+		t.Errorf("Ctrl-D on not empty line = %v", result)
+	}
+	if result := press(s, terminal.KeyEnter); result != lineDone {
+		t.Errorf("Enter = %v", result)
+	}
+	if result := press(&lineState{}, terminal.KeyEOF); result != lineEOF {
+		t.Errorf("Ctrl-D on empty line = %v", result)
+	}
+	if result := press(s, terminal.KeyQuit); result != lineCancel {
+		t.Errorf("Ctrl-C = %v", result)
+	}
+}
+
+func TestLineState_History(t *testing.T) {
+	history := []string{"search Нежить", "get #1"}
+	s := &lineState{history: history, historyIndex: len(history)}
+	typeKeys(s, "inf")
+	steps := []struct {
+		key  terminal.KeyCode
+		want string
+	}{
+		{terminal.KeyUp, "get #1"},
+		{terminal.KeyUp, "search Нежить"},
+		{terminal.KeyUp, "search Нежить"},
+		{terminal.KeyDown, "get #1"},
+		{terminal.KeyDown, "inf"},
+		{terminal.KeyDown, "inf"},
+	}
+	for i, step := range steps {
+		press(s, step.key)
+		if string(s.line) != step.want || s.pos != len(s.line) {
+			t.Errorf("step %d line = %q at %d, want %q", i, string(s.line), s.pos, step.want)
+		}
+	}
+}
+
+func TestLineState_Complete(t *testing.T) {
+	words := []string{"search", "series", "new"}
+	complete := func(line string) []string {
+		var candidates []string
+		for _, word := range words {
+			if len(word) >= len(line) && word[:len(line)] == line {
+				candidates = append(candidates, word)
+			}
+		}
+		return candidates
+	}
+	tests := []struct {
+		name       string
+		typed      string
+		want       string
+		result     lineResult
+		candidates []string
+	}{
+		{"single candidate", "n", "new ", lineEditing, nil},
+		{"common prefix", "s", "se", lineEditing, nil},
+		{"several candidates listed", "se", "se", lineCandidates, []string{"search", "series"}},
+		{"no candidates", "x", "x", lineEditing, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &lineState{complete: complete}
+			typeKeys(s, tt.typed)
+			result := press(s, terminal.KeyTab)
+			if string(s.line) != tt.want || s.pos != len(s.line) || result != tt.result {
+				t.Errorf("line = %q at %d result %v, want %q result %v", string(s.line), s.pos, result, tt.want, tt.result)
+			}
+			if !reflect.DeepEqual(s.candidates, tt.candidates) {
+				t.Errorf("candidates = %v, want %v", s.candidates, tt.candidates)
+			}
+		})
+	}
+}
+
+func Test_commonPrefix(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"search"}, "search"},
+		{[]string{"search", "series"}, "se"},
+		{[]string{"нежить", "небо"}, "не"},
+		{[]string{"get", "new"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commonPrefix(tt.words); got != tt.want {
+			t.Errorf("commonPrefix(%v) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/fsutil"
+)
+
+const (
+	appConfigDir = "flibusta-cli"
+	// Older lines are dropped when history is saved
+	maxHistory = 1000
+)
+
+// DefaultHistoryFile is located in user config directory.
+func DefaultHistoryFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, appConfigDir, "shell_history"), nil
+}
+
+// LoadHistory reads one command per line, missing file means empty history.
+func LoadHistory(fileName string) ([]string, error) {
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	return history, nil
+}
+
+func SaveHistory(fileName string, history []string) error {
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	err := os.MkdirAll(filepath.Dir(fileName), 0755)
+	if err != nil {
+		return err
+	}
+	data := strings.Join(history, "\n") + "\n"
+	return fsutil.WriteFileAtomic(fileName, []byte(data), 0600, true)
+}
@@ -0,0 +1,36 @@
+package shell
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "config", "shell_history")
+	history, err := LoadHistory(fileName)
+	if err != nil || history != nil {
+		t.Fatalf("LoadHistory() of missing file = %v, %v", history, err)
+	}
+	var lines []string
+	for i := 0; i < maxHistory+5; i++ {
+		lines = append(lines, fmt.Sprintf("get %d", i))
+	}
+	err = SaveHistory(fileName, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Existing file is overwritten
+	err = SaveHistory(fileName, lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	history, err = LoadHistory(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(history, lines[5:]) {
+		t.Errorf("LoadHistory() has %d lines from %q", len(history), history[0])
+	}
+}
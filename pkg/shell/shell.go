@@ -0,0 +1,304 @@
+// Package shell is an interactive command line with history, completion and
+// references to books of the last listing.
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+const Prompt = "flibusta> "
+
+// Books runs requests of the shell with one long lived client. Download saves the book unless library has it.
+// Commands of listings which are not supported are not offered.
+type Books interface {
+	Supports(listing string) bool
+	Search(query string) ([]client.ListItem, error)
+	Author(id string) (*client.AuthorResult, error)
+	Series(id string) (*client.SeriesResult, error)
+	NewArrivals() ([]client.ListItem, error)
+	Info(id string) (*client.InfoResult, error)
+	Download(id string, format string) (*client.SavedBook, error)
+}
+
+type command struct {
+	args  string
+	usage string
+	run   func(s *Shell, args []string) error
+	// listing of backend the command needs, empty when command is always available
+	listing string
+}
+
+var commands map[string]command
+
+// Commands are registered in init as help refers to them
+func init() {
+	commands = map[string]command{
+		"search": {"<query>", "search books", (*Shell).search, ""},
+		"author": {"<id>", "list books of the author", (*Shell).author, client.AuthorListing},
+		"series": {"<id>", "list books of the series", (*Shell).series, client.SeriesListing},
+		"new":    {"", "list new arrivals", (*Shell).newArrivals, client.NewListing},
+		"info":   {"<id|#n>", "show book info", (*Shell).info, ""},
+		"get":    {"<id|#n> [format]", "download book", (*Shell).get, ""},
+		"list":   {"", "show the last listing again", (*Shell).list, ""},
+		"help":   {"", "show commands", (*Shell).help, ""},
+		"exit":   {"", "leave the shell", nil, ""},
+		"quit":   {"", "leave the shell", nil, ""},
+	}
+}
+
+// errExit is returned by exit command
+var errExit = errors.New("exit")
+
+type Shell struct {
+	books  Books
+	out    io.Writer
+	format string
+	// Books of the last listing, `#1` is the first one
+	last []client.ListItem
+}
+
+// New creates shell, format is used by `get` when none is given.
+func New(books Books, format string, out io.Writer) *Shell {
+	return &Shell{books: books, out: out, format: format}
+}
+
+// Run reads commands until exit or end of input. Failed commands are reported and the shell goes on.
+func (s *Shell) Run(editor *Editor) error {
+	editor.Complete = s.Complete
+	for {
+		line, err := editor.ReadLine(Prompt)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = s.Execute(line)
+		if err == errExit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(s.out, "error:", err)
+		}
+	}
+}
+
+// Execute runs one command line, empty line does nothing.
+func (s *Shell) Execute(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := strings.ToLower(fields[0])
+	cmd, ok := commands[name]
+	if !ok || !s.available(cmd) {
+		return fmt.Errorf("unknown command %q, type help to see commands", fields[0])
+	}
+	if cmd.run == nil {
+		return errExit
+	}
+	return cmd.run(s, fields[1:])
+}
+
+// Complete suggests command names for the first word and formats for the last argument of get.
+func (s *Shell) Complete(line string) []string {
+	fields := strings.Fields(line)
+	word := ""
+	if len(fields) > 0 && !strings.HasSuffix(line, " ") {
+		word = fields[len(fields)-1]
+		fields = fields[:len(fields)-1]
+	}
+	var words []string
+	switch {
+	case len(fields) == 0:
+		words = s.commandNames()
+	case len(fields) == 1 && (fields[0] == "info" || fields[0] == "get"):
+		for i := range s.last {
+			words = append(words, "#"+strconv.Itoa(i+1))
+		}
+	case len(fields) == 2 && fields[0] == "get":
+		words = s.formats(fields[1])
+	}
+	var candidates []string
+	for _, candidate := range words {
+		if strings.HasPrefix(candidate, word) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// available tells whether backend supports listing of the command
+func (s *Shell) available(cmd command) bool {
+	return cmd.listing == "" || s.books.Supports(cmd.listing)
+}
+
+// commandNames lists available commands in order of names.
+func (s *Shell) commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name, cmd := range commands {
+		if s.available(cmd) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// formats of the book from the last listing, OPDS backend fills them.
+func (s *Shell) formats(ref string) []string {
+	id, err := s.resolve(ref)
+	if err != nil {
+		return nil
+	}
+	for _, item := range s.last {
+		if item.ID != id {
+			continue
+		}
+		var formats []string
+		for _, link := range item.Links {
+			formats = append(formats, link.Format)
+		}
+		return formats
+	}
+	return nil
+}
+
+// resolve turns `#n` into ID of n-th book of the last listing, other arguments are IDs.
+func (s *Shell) resolve(ref string) (string, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return ref, nil
+	}
+	n, err := strconv.Atoi(ref[1:])
+	if err != nil || n < 1 || n > len(s.last) {
+		return "", fmt.Errorf("no %s in the last listing", ref)
+	}
+	return s.last[n-1].ID, nil
+}
+
+func (s *Shell) search(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: search <query>")
+	}
+	items, err := s.books.Search(strings.Join(args, " "))
+	if err != nil {
+		return err
+	}
+	return s.show(items)
+}
+
+func (s *Shell) author(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: author <id>")
+	}
+	author, err := s.books.Author(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, "author:", author.Name)
+	return s.show(author.Books)
+}
+
+func (s *Shell) series(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: series <id>")
+	}
+	series, err := s.books.Series(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, "series:", series.Name)
+	return s.show(series.Books)
+}
+
+func (s *Shell) newArrivals(args []string) error {
+	items, err := s.books.NewArrivals()
+	if err != nil {
+		return err
+	}
+	return s.show(items)
+}
+
+func (s *Shell) info(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: info <id|#n>")
+	}
+	id, err := s.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	info, err := s.books.Info(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(s.out, info.String())
+	return nil
+}
+
+func (s *Shell) get(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: get <id|#n> [format]")
+	}
+	id, err := s.resolve(args[0])
+	if err != nil {
+		return err
+	}
+	format := s.format
+	if len(args) == 2 {
+		format = args[1]
+	}
+	book, err := s.books.Download(id, format)
+	if err != nil {
+		return err
+	}
+	if book.Owned {
+		fmt.Fprintln(s.out, "already in library:", book.Path)
+	} else {
+		fmt.Fprintln(s.out, "saved:", book.Path)
+	}
+	for _, warning := range book.Warnings {
+		fmt.Fprintln(s.out, "warning:", warning)
+	}
+	return nil
+}
+
+func (s *Shell) list(args []string) error {
+	if len(s.last) == 0 {
+		return errors.New("nothing listed yet")
+	}
+	s.print()
+	return nil
+}
+
+func (s *Shell) help(args []string) error {
+	for _, name := range s.commandNames() {
+		cmd := commands[name]
+		fmt.Fprintf(s.out, "  %-26s %s\n", strings.TrimSpace(name+" "+cmd.args), cmd.usage)
+	}
+	fmt.Fprintln(s.out, "  #n refers to n-th book of the last listing, e.g. get #3 epub")
+	return nil
+}
+
+// show remembers listing so its books can be referred by number.
+func (s *Shell) show(items []client.ListItem) error {
+	if len(items) == 0 {
+		return client.ErrNotFound
+	}
+	s.last = items
+	s.print()
+	return nil
+}
+
+func (s *Shell) print() {
+	for i := range s.last {
+		fmt.Fprintf(s.out, "#%-3d %s\n", i+1, s.last[i].String())
+	}
+}
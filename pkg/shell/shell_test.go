@@ -0,0 +1,229 @@
+package shell
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+)
+
+var testItems = []client.ListItem{
+	{ID: "325729", Title: "Нежить", Authors: []string{"Джон Джозеф Адамс"}, Links: []client.Link{
+		{Format: client.Fb2}, {Format: client.Epub},
+	}},
+	{ID: "175105", Title: "Война и мир", Authors: []string{"Лев Толстой"}},
+}
+
+// fakeBooks records requests
+type fakeBooks struct {
+	requests []string
+	// searchOnly backend does not list authors, series and new arrivals
+	searchOnly bool
+}
+
+func (b *fakeBooks) Supports(string) bool {
+	return !b.searchOnly
+}
+
+func (b *fakeBooks) Search(query string) ([]client.ListItem, error) {
+	b.requests = append(b.requests, "search "+query)
+	if query == "missing" {
+		return nil, nil
+	}
+	return testItems, nil
+}
+
+func (b *fakeBooks) Author(id string) (*client.AuthorResult, error) {
+	b.requests = append(b.requests, "author "+id)
+	return &client.AuthorResult{ID: id, Name: "Лев Толстой", Books: testItems[1:]}, nil
+}
+
+func (b *fakeBooks) Series(id string) (*client.SeriesResult, error) {
+	b.requests = append(b.requests, "series "+id)
+	return &client.SeriesResult{ID: id, Name: "Антология", Books: testItems[:1]}, nil
+}
+
+func (b *fakeBooks) NewArrivals() ([]client.ListItem, error) {
+	b.requests = append(b.requests, "new")
+	return testItems, nil
+}
+
+func (b *fakeBooks) Info(id string) (*client.InfoResult, error) {
+	b.requests = append(b.requests, "info "+id)
+	return &client.InfoResult{ID: id, Title: "Нежить"}, nil
+}
+
+// Download reports book 12345 as owned and fb2 books as not recorded in library
+func (b *fakeBooks) Download(id string, format string) (*client.SavedBook, error) {
+	b.requests = append(b.requests, "get "+id+" "+format)
+	book := &client.SavedBook{Path: id + "." + format, Owned: id == "12345"}
+	if format == client.Fb2 {
+		book.Warnings = []string{"book is not recorded in library: disk full"}
+	}
+	return book, nil
+}
+
+func TestShell_Execute(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		requests []string
+		output   string
+		wantErr  string
+	}{
+		{
+			name:     "search lists numbered books",
+			lines:    []string{"search Нежить  сборник"},
+			requests: []string{"search Нежить сборник"},
+			output:   "#1   325729: Нежить <Джон Джозеф Адамс>",
+		},
+		{
+			name:     "reference to last listing",
+			lines:    []string{"search Нежить", "get #2", "info #1"},
+			requests: []string{"search Нежить", "get 175105 epub", "info 325729"},
+			output:   "saved: 175105.epub",
+		},
+		{
+			name:     "listing of author replaces references",
+			lines:    []string{"search Нежить", "author 123", "get #1 fb2"},
+			requests: []string{"search Нежить", "author 123", "get 175105 fb2"},
+			output:   "author: Лев Толстой",
+		},
+		{
+			name:     "warning after saved book",
+			lines:    []string{"get 175105 fb2"},
+			requests: []string{"get 175105 fb2"},
+			output:   "saved: 175105.fb2\nwarning: book is not recorded in library: disk full\n",
+		},
+		{
+			name:     "plain id",
+			lines:    []string{"GET 12345"},
+			requests: []string{"get 12345 epub"},
+			output:   "already in library: 12345.epub",
+		},
+		{
+			name:    "reference without listing",
+			lines:   []string{"get #1"},
+			wantErr: "no #1 in the last listing",
+		},
+		{
+			name:     "reference out of range",
+			lines:    []string{"new", "info #3"},
+			requests: []string{"new"},
+			wantErr:  "no #3 in the last listing",
+		},
+		{
+			name:     "nothing found keeps listing",
+			lines:    []string{"series 7", "search missing"},
+			requests: []string{"series 7", "search missing"},
+			wantErr:  "nothing found",
+		},
+		{
+			name:    "missing arguments",
+			lines:   []string{"info"},
+			wantErr: "usage: info <id|#n>",
+		},
+		{
+			name:    "unknown command",
+			lines:   []string{"download 1"},
+			wantErr: `unknown command "download"`,
+		},
+		{
+			name:  "empty line",
+			lines: []string{"   "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := &fakeBooks{}
+			out := &bytes.Buffer{}
+			s := New(books, client.Epub, out)
+			var err error
+			for _, line := range tt.lines {
+				err = s.Execute(line)
+			}
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(books.requests, tt.requests) {
+				t.Errorf("requests = %v, want %v", books.requests, tt.requests)
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output = %q, want %q", out.String(), tt.output)
+			}
+		})
+	}
+}
+
+func TestShell_Complete(t *testing.T) {
+	s := New(&fakeBooks{}, client.Epub, &bytes.Buffer{})
+	if err := s.Execute("search Нежить"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"se", []string{"search", "series"}},
+		{"q", []string{"quit"}},
+		{"get ", []string{"#1", "#2"}},
+		{"info #2", []string{"#2"}},
+		{"get #1 ", []string{client.Epub, client.Fb2}},
+		{"get #1 e", []string{client.Epub}},
+		{"get #2 ", nil},
+		{"search ", nil},
+		{"x", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := s.Complete(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Complete() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShell_SearchOnly(t *testing.T) {
+	books := &fakeBooks{searchOnly: true}
+	out := &bytes.Buffer{}
+	s := New(books, client.Epub, out)
+	for _, line := range []string{"author 1", "series 7", "new"} {
+		if err := s.Execute(line); err == nil || !strings.Contains(err.Error(), "unknown command") {
+			t.Errorf("Execute(%q) error = %v, want unknown command", line, err)
+		}
+	}
+	if len(books.requests) != 0 {
+		t.Errorf("requests = %v, want none", books.requests)
+	}
+	if got := s.Complete("se"); !reflect.DeepEqual(got, []string{"search"}) {
+		t.Errorf("Complete() = %v, want only search", got)
+	}
+	if err := s.Execute("help"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "author") || strings.Contains(out.String(), "new arrivals") {
+		t.Errorf("help offers unsupported commands: %s", out.String())
+	}
+}
+
+func TestShell_Run(t *testing.T) {
+	books := &fakeBooks{}
+	out := &bytes.Buffer{}
+	editor := pipeEditor(t, "search Нежить\nbogus\nget #1\nexit\nnew\n")
+	err := New(books, client.Fb2, out).Run(editor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"search Нежить", "get 325729 fb2"}
+	if !reflect.DeepEqual(books.requests, want) {
+		t.Errorf("requests = %v, want %v", books.requests, want)
+	}
+	if !strings.Contains(out.String(), `error: unknown command "bogus"`) {
+		t.Errorf("output = %q", out.String())
+	}
+	if len(editor.History) != 4 {
+		t.Errorf("history = %v", editor.History)
+	}
+}
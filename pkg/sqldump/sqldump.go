@@ -0,0 +1,594 @@
+// Package sqldump reads rows from MySQL dump files without loading whole file into memory.
+package sqldump
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Value is a column value, NULL is not the same as empty string.
+type Value struct {
+	Text string
+	Null bool
+}
+
+// Row is one tuple of INSERT statement.
+type Row struct {
+	Table   string
+	Columns []string
+	Values  []Value
+}
+
+// SyntaxError tells where dump could not be parsed.
+type SyntaxError struct {
+	Line    int
+	Message string
+}
+
+// Reader returns rows of INSERT statements one by one.
+// Column names are taken from CREATE TABLE statement or from INSERT column list.
+type Reader struct {
+	in      *bufio.Reader
+	line    int
+	columns map[string][]string
+	// Current INSERT statement
+	table      string
+	rowColumns []string
+	inInsert   bool
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("sql dump line %d: %s", e.Line, e.Message)
+}
+
+// Get returns column value, empty string for NULL or unknown column.
+func (r *Row) Get(column string) string {
+	for i, name := range r.Columns {
+		if name == column && i < len(r.Values) {
+			return r.Values[i].Text
+		}
+	}
+	return ""
+}
+
+// NewReader reads plain or gzipped dump.
+func NewReader(stream io.Reader) (*Reader, error) {
+	in := bufio.NewReaderSize(stream, 64*1024)
+	magic, err := in.Peek(len(gzipMagic))
+	if err == nil && bytes.Equal(magic, gzipMagic) {
+		unzipped, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, err
+		}
+		in = bufio.NewReaderSize(unzipped, 64*1024)
+	}
+	return &Reader{in: in, line: 1, columns: map[string][]string{}}, nil
+}
+
+// Columns returns table columns from CREATE TABLE statement read so far.
+func (r *Reader) Columns(table string) []string {
+	return r.columns[table]
+}
+
+// Next returns the next row, io.EOF at the end of dump.
+func (r *Reader) Next() (*Row, error) {
+	for {
+		if r.inInsert {
+			row, err := r.readTuple()
+			if err != nil || row != nil {
+				return row, err
+			}
+			continue
+		}
+		err := r.readStatement()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readStatement handles statement start, INSERT leaves reader before the first tuple.
+func (r *Reader) readStatement() error {
+	err := r.skipSpace()
+	if err != nil {
+		return err
+	}
+	c, err := r.peek()
+	if err != nil {
+		return err
+	}
+	if c == ';' {
+		_, _ = r.read()
+		return nil
+	}
+	word, err := r.readWord()
+	if err != nil {
+		return err
+	}
+	switch strings.ToUpper(word) {
+	case "CREATE":
+		return r.readCreate()
+	case "INSERT", "REPLACE":
+		return r.readInsert()
+	}
+	return r.skipStatement()
+}
+
+func (r *Reader) readCreate() error {
+	word, err := r.readWord()
+	if err != nil || !strings.EqualFold(word, "TABLE") {
+		return r.skipStatement()
+	}
+	if _, err = r.readWords(); err != nil {
+		return err
+	}
+	table, err := r.readTableName()
+	if err != nil {
+		return err
+	}
+	if err = r.expect('('); err != nil {
+		return err
+	}
+	var columns []string
+	for {
+		if err = r.skipSpace(); err != nil {
+			return err
+		}
+		c, err := r.peek()
+		if err != nil {
+			return err
+		}
+		if c == ')' {
+			break
+		}
+		quoted := c == '`'
+		name, err := r.readWord()
+		if err != nil {
+			return err
+		}
+		if quoted || !isIndexKeyword(name) {
+			columns = append(columns, name)
+		}
+		// Skip type and options of column, or index definition
+		end, err := r.skipUntil(",)")
+		if err != nil {
+			return err
+		}
+		if end == ')' {
+			break
+		}
+	}
+	r.columns[table] = columns
+	return r.skipStatement()
+}
+
+func (r *Reader) readInsert() error {
+	if _, err := r.readWords("IGNORE", "INTO"); err != nil {
+		return err
+	}
+	table, err := r.readTableName()
+	if err != nil {
+		return err
+	}
+	r.table = table
+	r.rowColumns = r.columns[table]
+	if err = r.skipSpace(); err != nil {
+		return err
+	}
+	if c, _ := r.peek(); c == '(' {
+		_, _ = r.read()
+		var columns []string
+		for {
+			if err = r.skipSpace(); err != nil {
+				return err
+			}
+			name, err := r.readWord()
+			if err != nil {
+				return err
+			}
+			columns = append(columns, name)
+			if err = r.skipSpace(); err != nil {
+				return err
+			}
+			c, err := r.read()
+			if err != nil {
+				return err
+			}
+			if c == ')' {
+				break
+			}
+			if c != ',' {
+				return r.syntaxError("expected , in column list, got %q", c)
+			}
+		}
+		r.rowColumns = columns
+	}
+	if err = r.skipSpace(); err != nil {
+		return err
+	}
+	word, err := r.readWord()
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(word, "VALUES") && !strings.EqualFold(word, "VALUE") {
+		return r.syntaxError("expected VALUES, got %q", word)
+	}
+	r.inInsert = true
+	return nil
+}
+
+// readTuple returns next row of INSERT, nil row means the statement has ended.
+func (r *Reader) readTuple() (*Row, error) {
+	if err := r.skipSpace(); err != nil {
+		return nil, r.unexpectedEOF(err)
+	}
+	c, err := r.read()
+	if err != nil {
+		return nil, r.unexpectedEOF(err)
+	}
+	switch c {
+	case ',':
+		return r.readTuple()
+	case ';':
+		r.inInsert = false
+		return nil, nil
+	case '(':
+	default:
+		return nil, r.syntaxError("expected tuple, got %q", c)
+	}
+
+	row := &Row{Table: r.table, Columns: r.rowColumns}
+	for {
+		value, err := r.readValue()
+		if err != nil {
+			return nil, r.unexpectedEOF(err)
+		}
+		row.Values = append(row.Values, value)
+		if err = r.skipSpace(); err != nil {
+			return nil, r.unexpectedEOF(err)
+		}
+		c, err := r.read()
+		if err != nil {
+			return nil, r.unexpectedEOF(err)
+		}
+		if c == ')' {
+			return row, nil
+		}
+		if c != ',' {
+			return nil, r.syntaxError("expected , between values, got %q", c)
+		}
+	}
+}
+
+func (r *Reader) readValue() (Value, error) {
+	if err := r.skipSpace(); err != nil {
+		return Value{}, err
+	}
+	c, err := r.peek()
+	if err != nil {
+		return Value{}, err
+	}
+	if c == '\'' || c == '"' {
+		text, err := r.readString()
+		return Value{Text: text}, err
+	}
+	token := &strings.Builder{}
+	for {
+		c, err := r.peek()
+		if err != nil {
+			return Value{}, err
+		}
+		if c == ',' || c == ')' || isSpace(c) {
+			break
+		}
+		_, _ = r.read()
+		token.WriteByte(c)
+	}
+	if strings.EqualFold(token.String(), "NULL") {
+		return Value{Null: true}, nil
+	}
+	return Value{Text: token.String()}, nil
+}
+
+// readString decodes quoted string with MySQL escapes.
+func (r *Reader) readString() (string, error) {
+	quote, _ := r.read()
+	text := &strings.Builder{}
+	for {
+		c, err := r.read()
+		if err != nil {
+			return "", err
+		}
+		switch c {
+		case '\\':
+			c, err = r.read()
+			if err != nil {
+				return "", err
+			}
+			text.WriteString(unescape(c))
+		case quote:
+			// Doubled quote is a quote itself
+			if next, err := r.peek(); err == nil && next == quote {
+				_, _ = r.read()
+				text.WriteByte(quote)
+				continue
+			}
+			return text.String(), nil
+		default:
+			text.WriteByte(c)
+		}
+	}
+}
+
+func unescape(c byte) string {
+	switch c {
+	case '0':
+		return "\x00"
+	case 'b':
+		return "\b"
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case 'Z':
+		return "\x1a"
+	case '%', '_':
+		// Escaped only for LIKE patterns, backslash is kept
+		return "\\" + string(c)
+	}
+	return string(c)
+}
+
+// readWord reads keyword, identifier or `quoted identifier`.
+func (r *Reader) readWord() (string, error) {
+	if err := r.skipSpace(); err != nil {
+		return "", err
+	}
+	c, err := r.peek()
+	if err != nil {
+		return "", err
+	}
+	word := &strings.Builder{}
+	if c == '`' {
+		_, _ = r.read()
+		for {
+			c, err = r.read()
+			if err != nil {
+				return "", err
+			}
+			if c == '`' {
+				if next, err := r.peek(); err == nil && next == '`' {
+					_, _ = r.read()
+					word.WriteByte(c)
+					continue
+				}
+				return word.String(), nil
+			}
+			word.WriteByte(c)
+		}
+	}
+	for {
+		c, err = r.peek()
+		if err != nil || !isWordChar(c) {
+			break
+		}
+		_, _ = r.read()
+		word.WriteByte(c)
+	}
+	if word.Len() == 0 {
+		return "", r.syntaxError("expected word, got %q", c)
+	}
+	return word.String(), nil
+}
+
+// readWords skips optional keywords, e.g. `IF NOT EXISTS`, and returns the last one read.
+func (r *Reader) readWords(keywords ...string) (string, error) {
+	last := ""
+	for {
+		if err := r.skipSpace(); err != nil {
+			return last, err
+		}
+		c, err := r.peek()
+		if err != nil || !isWordChar(c) {
+			return last, err
+		}
+		data, err := r.in.Peek(32)
+		if err != nil && err != io.EOF {
+			return last, err
+		}
+		word := strings.ToUpper(leadingWord(data))
+		if !contains(keywords, word) && !contains([]string{"IF", "NOT", "EXISTS"}, word) {
+			return last, nil
+		}
+		if _, err = r.readWord(); err != nil {
+			return last, err
+		}
+		last = word
+	}
+}
+
+// readTableName reads `db`.`table` and returns table name.
+func (r *Reader) readTableName() (string, error) {
+	name, err := r.readWord()
+	if err != nil {
+		return "", err
+	}
+	if c, _ := r.peek(); c == '.' {
+		_, _ = r.read()
+		return r.readWord()
+	}
+	return name, nil
+}
+
+func (r *Reader) expect(expected byte) error {
+	if err := r.skipSpace(); err != nil {
+		return err
+	}
+	c, err := r.read()
+	if err != nil {
+		return err
+	}
+	if c != expected {
+		return r.syntaxError("expected %q, got %q", expected, c)
+	}
+	return nil
+}
+
+// skipUntil skips to one of stop characters outside of quotes and parentheses and returns it.
+func (r *Reader) skipUntil(stop string) (byte, error) {
+	depth := 0
+	for {
+		c, err := r.peek()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case c == '\'' || c == '"':
+			if _, err = r.readString(); err != nil {
+				return 0, err
+			}
+			continue
+		case c == '`':
+			if _, err = r.readWord(); err != nil {
+				return 0, err
+			}
+			continue
+		case c == '(':
+			depth++
+		case c == ')' && depth > 0:
+			depth--
+		case depth == 0 && strings.IndexByte(stop, c) >= 0:
+			_, _ = r.read()
+			return c, nil
+		}
+		_, _ = r.read()
+	}
+}
+
+func (r *Reader) skipStatement() error {
+	_, err := r.skipUntil(";")
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// skipSpace skips white space and comments.
+func (r *Reader) skipSpace() error {
+	for {
+		c, err := r.peek()
+		if err != nil {
+			return err
+		}
+		switch {
+		case isSpace(c):
+			_, _ = r.read()
+		case c == '#':
+			err = r.skipLine()
+		case c == '-' && (r.startsWith("-- ") || r.startsWith("--\n")):
+			err = r.skipLine()
+		case c == '/' && r.startsWith("/*"):
+			err = r.skipComment()
+		default:
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Reader) skipLine() error {
+	for {
+		c, err := r.read()
+		if err != nil || c == '\n' {
+			return err
+		}
+	}
+}
+
+func (r *Reader) skipComment() error {
+	_, _ = r.read()
+	_, _ = r.read()
+	prev := byte(0)
+	for {
+		c, err := r.read()
+		if err != nil {
+			return err
+		}
+		if prev == '*' && c == '/' {
+			return nil
+		}
+		prev = c
+	}
+}
+
+func (r *Reader) startsWith(prefix string) bool {
+	data, _ := r.in.Peek(len(prefix))
+	return string(data) == prefix
+}
+
+func (r *Reader) peek() (byte, error) {
+	data, err := r.in.Peek(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+func (r *Reader) read() (byte, error) {
+	c, err := r.in.ReadByte()
+	if c == '\n' {
+		r.line++
+	}
+	return c, err
+}
+
+func (r *Reader) syntaxError(format string, args ...interface{}) error {
+	return &SyntaxError{Line: r.line, Message: fmt.Sprintf(format, args...)}
+}
+
+func (r *Reader) unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return r.syntaxError("unexpected end of dump inside INSERT into %s", r.table)
+	}
+	return err
+}
+
+func isIndexKeyword(word string) bool {
+	return contains([]string{"PRIMARY", "KEY", "UNIQUE", "INDEX", "FULLTEXT", "SPATIAL", "CONSTRAINT", "FOREIGN", "CHECK"}, strings.ToUpper(word))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func leadingWord(data []byte) string {
+	for i, c := range data {
+		if !isWordChar(c) {
+			return string(data[:i])
+		}
+	}
+	return string(data)
+}
+
+func contains(list []string, item string) bool {
+	for _, value := range list {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package sqldump
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readAll(t *testing.T, stream io.Reader) ([]*Row, *Reader, error) {
+	t.Helper()
+	reader, err := NewReader(stream)
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	var rows []*Row
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			return rows, reader, nil
+		}
+		if err != nil {
+			return rows, reader, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestReader(t *testing.T) {
+	data, err := os.ReadFile("testdata/libbook.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gzipped := &bytes.Buffer{}
+	w := gzip.NewWriter(gzipped)
+	_, _ = w.Write(data)
+	_ = w.Close()
+
+	columns := []string{"BookId", "FileSize", "Time", "Title", "Title1", "Lang", "FileType", "Year", "Deleted", "keywords"}
+	for name, stream := range map[string]io.Reader{"plain": bytes.NewReader(data), "gzip": gzipped} {
+		t.Run(name, func(t *testing.T) {
+			rows, reader, err := readAll(t, stream)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if !reflect.DeepEqual(reader.Columns("libbook"), columns) {
+				t.Errorf("Columns() = %v, want %v", reader.Columns("libbook"), columns)
+			}
+			if len(rows) != 4 {
+				t.Fatalf("Next() rows = %d, want 4", len(rows))
+			}
+			if got := rows[0]; got.Table != "libbook" || got.Get("Title") != "Война и мир" || got.Get("Year") != "1869" || !got.Values[9].Null {
+				t.Errorf("Row 0 = %+v", got)
+			}
+			if got := rows[1].Get("keywords"); got != "фэнтези, 'нежить'" {
+				t.Errorf("Row 1 keywords = %q", got)
+			}
+			if got := rows[2].Get("Title"); got != "Escapes: \\ \"q\" \n\t 50\\% it's" {
+				t.Errorf("Row 2 title = %q", got)
+			}
+			if got := rows[2].Get("keywords"); got != "a;b,(c)" {
+				t.Errorf("Row 2 keywords = %q", got)
+			}
+			if got := rows[3]; !reflect.DeepEqual(got.Columns, []string{"BookId", "Title"}) || got.Get("Title") != "Частичная вставка" {
+				t.Errorf("Row 3 = %+v", got)
+			}
+		})
+	}
+}
+
+func TestReader_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		line int
+	}{
+		{"Unterminated string", "INSERT INTO `t` VALUES (1,'abc", 1},
+		{"Missing comma", "INSERT INTO `t` VALUES\n(1 2);", 2},
+		{"Missing tuple", "INSERT INTO `t` VALUES 1;", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := readAll(t, strings.NewReader(tt.dump))
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) || syntaxErr.Line != tt.line {
+				t.Errorf("Next() error = %v, want syntax error at line %d", err, tt.line)
+			}
+		})
+	}
+}
+
+func TestReader_CreateIfNotExists(t *testing.T) {
+	dump := "CREATE TABLE IF NOT EXISTS `flibusta`.`libseqname` (`SeqId` int, `SeqName` varchar(254), UNIQUE KEY `name` (`SeqName`));\n" +
+		"INSERT INTO libseqname VALUES (7,'Остап Бендер');"
+	rows, _, err := readAll(t, strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("Next() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Get("SeqId") != "7" || rows[0].Get("SeqName") != "Остап Бендер" {
+		t.Errorf("Next() rows = %+v", rows)
+	}
+}
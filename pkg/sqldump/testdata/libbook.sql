@@ -0,0 +1,41 @@
+-- MySQL dump 10.13  Distrib 5.5.62, for Linux (x86_64)
+--
+-- Host: localhost    Database: flibusta
+-- ------------------------------------------------------
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40101 SET NAMES utf8 */;
+
+--
+-- Table structure for table `libbook`
+--
+
+DROP TABLE IF EXISTS `libbook`;
+/*!40101 SET @saved_cs_client     = @@character_set_client */;
+CREATE TABLE `libbook` (
+  `BookId` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `FileSize` int(10) unsigned NOT NULL DEFAULT '0',
+  `Time` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `Title` varchar(254) CHARACTER SET utf8 NOT NULL DEFAULT '',
+  `Title1` varchar(254) NOT NULL DEFAULT '',
+  `Lang` char(2) NOT NULL DEFAULT 'ru',
+  `FileType` char(4) NOT NULL DEFAULT 'fb2',
+  `Year` smallint(6) NOT NULL DEFAULT '0',
+  `Deleted` char(1) NOT NULL DEFAULT '0',
+  `keywords` varchar(255) DEFAULT NULL COMMENT 'tags, separated by ,;',
+  PRIMARY KEY (`BookId`),
+  KEY `Title` (`Title`),
+  KEY `FileSize` (`FileSize`,`Deleted`)
+) ENGINE=MyISAM AUTO_INCREMENT=600000 DEFAULT CHARSET=utf8;
+/*!40101 SET character_set_client = @saved_cs_client */;
+
+--
+-- Dumping data for table `libbook`
+--
+
+LOCK TABLES `libbook` WRITE;
+/*!40000 ALTER TABLE `libbook` DISABLE KEYS */;
+INSERT INTO `libbook` VALUES (175105,2890123,'2009-11-29 18:41:08','Война и мир','','ru','fb2',1869,'0',NULL),(325729,412345,'2012-05-01 10:00:00','Нежить','','ru','fb2',2012,'0','фэнтези, \'нежить\''),
+(12345,100,'2001-01-01 00:00:00','Escapes: \\ \"q\" \n\t 50\% it''s','','en','fb2',0,'1','a;b,(c)');
+INSERT INTO `libbook` (`BookId`,`Title`) VALUES (1,'Частичная вставка');
+/*!40000 ALTER TABLE `libbook` ENABLE KEYS */;
+UNLOCK TABLES;
@@ -0,0 +1,88 @@
+// Package terminal switches terminal to raw mode and reads pressed keys.
+package terminal
+
+import (
+	"unicode/utf8"
+)
+
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyTab
+	KeyBackspace
+	KeyEscape
+	// Ctrl-C
+	KeyQuit
+	// Ctrl-D
+	KeyEOF
+	KeyUnknown
+)
+
+// Key is a key pressed in raw mode, Rune is set for KeyRune.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// ParseKeys splits raw terminal input to keys. Arrows come as `ESC [ A`,
+// lone ESC is Escape key. Incomplete UTF-8 at the end is returned as rest.
+func ParseKeys(data []byte) (keys []Key, rest []byte) {
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == 0x1b:
+			if len(data) >= 3 && (data[1] == '[' || data[1] == 'O') {
+				keys = append(keys, Key{Code: arrowKey(data[2])})
+				data = data[3:]
+				continue
+			}
+			keys = append(keys, Key{Code: KeyEscape})
+			data = data[1:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+			data = data[1:]
+		case b == '\t':
+			keys = append(keys, Key{Code: KeyTab})
+			data = data[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+			data = data[1:]
+		case b == 0x03:
+			keys = append(keys, Key{Code: KeyQuit})
+			data = data[1:]
+		case b == 0x04:
+			keys = append(keys, Key{Code: KeyEOF})
+			data = data[1:]
+		case b < 0x20:
+			keys = append(keys, Key{Code: KeyUnknown})
+			data = data[1:]
+		default:
+			if !utf8.FullRune(data) {
+				return keys, data
+			}
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, Key{Code: KeyRune, Rune: r})
+			data = data[size:]
+		}
+	}
+	return keys, nil
+}
+
+func arrowKey(b byte) KeyCode {
+	switch b {
+	case 'A':
+		return KeyUp
+	case 'B':
+		return KeyDown
+	case 'C':
+		return KeyRight
+	case 'D':
+		return KeyLeft
+	}
+	return KeyUnknown
+}
@@ -0,0 +1,32 @@
+package terminal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantKeys []Key
+		wantRest string
+	}{
+		{"Letters", "нa", []Key{{Code: KeyRune, Rune: 'н'}, {Code: KeyRune, Rune: 'a'}}, ""},
+		{"Arrows", "\x1b[A\x1b[B\x1bOC\x1b[D", []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}, {Code: KeyLeft}}, ""},
+		{"Escape", "\x1b", []Key{{Code: KeyEscape}}, ""},
+		{"Controls", "\r\t\x7f\x03\x04\x01", []Key{{Code: KeyEnter}, {Code: KeyTab}, {Code: KeyBackspace}, {Code: KeyQuit}, {Code: KeyEOF}, {Code: KeyUnknown}}, ""},
+		{"Incomplete rune", "a\xd0", []Key{{Code: KeyRune, Rune: 'a'}}, "\xd0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKeys, gotRest := ParseKeys([]byte(tt.data))
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("ParseKeys() keys = %v, want %v", gotKeys, tt.wantKeys)
+			}
+			if string(gotRest) != tt.wantRest {
+				t.Errorf("ParseKeys() rest = %q, want %q", gotRest, tt.wantRest)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package terminal
+
+import "errors"
+
+var errNoTerminal = errors.New("interactive mode needs a Unix terminal")
+
+func MakeRaw(fd int) (func() error, error) {
+	return nil, errNoTerminal
+}
+
+func Size(fd int) (width int, height int, err error) {
+	return 0, 0, errNoTerminal
+}
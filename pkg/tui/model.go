@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/terminal"
+)
+
+var spinner = []string{"|", "/", "-", "\\"}
+
+// Messages change the model, they come from keyboard and from requests running in background.
+type (
+	searchDue struct {
+		query string
+	}
+	searchDone struct {
+		query string
+		items []client.ListItem
+		err   error
+	}
+	infoDone struct {
+		id   string
+		info *client.InfoResult
+		err  error
+	}
+	downloadDone struct {
+		book *client.SavedBook
+		err  error
+	}
+	tick struct{}
+)
+
+// Commands are requested by the model and run by App.
+type (
+	scheduleSearch struct {
+		query string
+	}
+	runSearch struct {
+		query string
+	}
+	runInfo struct {
+		id string
+	}
+	runDownload struct {
+		id     string
+		format string
+	}
+)
+
+// model is the state of the screen, it is changed only by update in one goroutine.
+type model struct {
+	preferred string
+	query     string
+	searched  string
+	searching bool
+	items     []client.ListItem
+	selected  int
+	infos     map[string]*client.InfoResult
+	failures  map[string]error
+	loading   map[string]bool
+	format    int
+	// Book ID being downloaded
+	downloading string
+	frame       int
+	status      string
+	quit        bool
+}
+
+func newModel(query string, preferred string) *model {
+	return &model{
+		preferred: preferred,
+		query:     query,
+		infos:     map[string]*client.InfoResult{},
+		failures:  map[string]error{},
+		loading:   map[string]bool{},
+	}
+}
+
+func (m *model) start() []interface{} {
+	if strings.TrimSpace(m.query) == "" {
+		m.status = "Type to search"
+		return nil
+	}
+	return m.search()
+}
+
+func (m *model) update(msg interface{}) []interface{} {
+	switch msg := msg.(type) {
+	case terminal.Key:
+		return m.press(msg)
+	case searchDue:
+		if msg.query != m.query || msg.query == m.searched {
+			return nil
+		}
+		return m.search()
+	case searchDone:
+		if msg.query != m.query {
+			// Query has changed while request was running
+			return nil
+		}
+		m.searching = false
+		if msg.err != nil {
+			m.status = "Search failed: " + msg.err.Error()
+			return nil
+		}
+		m.items = msg.items
+		m.selected = 0
+		m.status = fmt.Sprintf("%d books found", len(m.items))
+		return m.selectBook(0)
+	case infoDone:
+		delete(m.loading, msg.id)
+		if msg.err != nil {
+			m.failures[msg.id] = msg.err
+			return nil
+		}
+		m.infos[msg.id] = msg.info
+		if book := m.current(); book != nil && book.ID == msg.id {
+			m.format = m.preferredFormat(msg.info)
+		}
+	case downloadDone:
+		m.downloading = ""
+		if msg.err != nil {
+			m.status = "Download failed: " + msg.err.Error()
+			return nil
+		}
+		if msg.book.Owned {
+			m.status = "Already in library: " + msg.book.Path
+		} else {
+			m.status = "Saved at " + msg.book.Path
+		}
+		for _, warning := range msg.book.Warnings {
+			m.status += "; warning: " + warning
+		}
+	case tick:
+		m.frame++
+	}
+	return nil
+}
+
+func (m *model) press(k terminal.Key) []interface{} {
+	switch k.Code {
+	case terminal.KeyQuit, terminal.KeyEOF, terminal.KeyEscape:
+		m.quit = true
+	case terminal.KeyRune:
+		m.query += string(k.Rune)
+		return m.queryChanged()
+	case terminal.KeyBackspace:
+		if m.query == "" {
+			return nil
+		}
+		runes := []rune(m.query)
+		m.query = string(runes[:len(runes)-1])
+		return m.queryChanged()
+	case terminal.KeyUp:
+		return m.selectBook(m.selected - 1)
+	case terminal.KeyDown:
+		return m.selectBook(m.selected + 1)
+	case terminal.KeyLeft:
+		m.moveFormat(-1)
+	case terminal.KeyRight, terminal.KeyTab:
+		m.moveFormat(1)
+	case terminal.KeyEnter:
+		return m.download()
+	}
+	return nil
+}
+
+func (m *model) queryChanged() []interface{} {
+	if strings.TrimSpace(m.query) == "" {
+		return nil
+	}
+	return []interface{}{scheduleSearch{m.query}}
+}
+
+func (m *model) search() []interface{} {
+	m.searched = m.query
+	m.searching = true
+	m.status = "Searching " + m.query
+	return []interface{}{runSearch{m.query}}
+}
+
+// selectBook moves selection and asks for book info when it is not known yet.
+func (m *model) selectBook(index int) []interface{} {
+	if len(m.items) == 0 {
+		return nil
+	}
+	if index < 0 || index >= len(m.items) {
+		return nil
+	}
+	m.selected = index
+	id := m.items[index].ID
+	if info, ok := m.infos[id]; ok {
+		m.format = m.preferredFormat(info)
+		return nil
+	}
+	m.format = 0
+	if m.loading[id] || m.failures[id] != nil {
+		return nil
+	}
+	m.loading[id] = true
+	return []interface{}{runInfo{id}}
+}
+
+func (m *model) current() *client.ListItem {
+	if m.selected >= len(m.items) {
+		return nil
+	}
+	return &m.items[m.selected]
+}
+
+func (m *model) currentInfo() *client.InfoResult {
+	book := m.current()
+	if book == nil {
+		return nil
+	}
+	return m.infos[book.ID]
+}
+
+func (m *model) preferredFormat(info *client.InfoResult) int {
+	for i, format := range info.Formats {
+		if format == m.preferred {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *model) moveFormat(delta int) {
+	info := m.currentInfo()
+	if info == nil || len(info.Formats) == 0 {
+		return
+	}
+	m.format = (m.format + delta + len(info.Formats)) % len(info.Formats)
+}
+
+func (m *model) download() []interface{} {
+	if m.downloading != "" {
+		m.status = "Wait until download is finished"
+		return nil
+	}
+	info := m.currentInfo()
+	if info == nil {
+		m.status = "Book info is not loaded yet"
+		return nil
+	}
+	if len(info.Formats) == 0 {
+		m.status = "Book is not available for download"
+		return nil
+	}
+	m.downloading = info.ID
+	m.status = ""
+	return []interface{}{runDownload{id: info.ID, format: info.Formats[m.format]}}
+}
+
+// busy tells if spinner is shown.
+func (m *model) busy() bool {
+	return m.searching || m.downloading != "" || len(m.loading) > 0
+}
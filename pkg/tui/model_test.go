@@ -0,0 +1,146 @@
+package tui
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/terminal"
+)
+
+var testItems = []client.ListItem{
+	{ID: "1", Title: "Нежить", Authors: []string{"Джон Джозеф Адамс"}},
+	{ID: "2", Title: "Двенадцать стульев", Authors: []string{"Илья Ильф", "Евгений Петров"}},
+}
+
+func typeText(m *model, text string) (commands []interface{}) {
+	for _, r := range text {
+		commands = m.update(terminal.Key{Code: terminal.KeyRune, Rune: r})
+	}
+	return commands
+}
+
+func TestModel_Search(t *testing.T) {
+	m := newModel("", client.Epub)
+	if commands := m.start(); commands != nil {
+		t.Errorf("start() without query = %v", commands)
+	}
+	commands := typeText(m, "не")
+	if !reflect.DeepEqual(commands, []interface{}{scheduleSearch{"не"}}) {
+		t.Fatalf("typing = %v", commands)
+	}
+	// Search of older query is skipped
+	if commands := m.update(searchDue{"н"}); commands != nil {
+		t.Errorf("searchDue of old query = %v", commands)
+	}
+	commands = m.update(searchDue{"не"})
+	if !reflect.DeepEqual(commands, []interface{}{runSearch{"не"}}) || !m.busy() {
+		t.Fatalf("searchDue = %v", commands)
+	}
+	// Results of old query are dropped
+	m.update(searchDone{query: "н", items: testItems[1:]})
+	if len(m.items) != 0 {
+		t.Errorf("items of old query are shown")
+	}
+	commands = m.update(searchDone{query: "не", items: testItems})
+	if !reflect.DeepEqual(commands, []interface{}{runInfo{"1"}}) {
+		t.Errorf("searchDone = %v, want info of first book", commands)
+	}
+	if m.status != "2 books found" {
+		t.Errorf("status = %v", m.status)
+	}
+
+	m.update(terminal.Key{Code: terminal.KeyBackspace})
+	if m.query != "н" {
+		t.Errorf("backspace query = %v", m.query)
+	}
+}
+
+func TestModel_SelectAndDownload(t *testing.T) {
+	m := newModel("test", client.Epub)
+	m.update(searchDone{query: "test", items: testItems})
+
+	if commands := m.update(terminal.Key{Code: terminal.KeyEnter}); commands != nil || m.status != "Book info is not loaded yet" {
+		t.Errorf("download before info = %v, status %v", commands, m.status)
+	}
+	m.update(infoDone{id: "1", info: &client.InfoResult{ID: "1", Formats: []string{client.Fb2, client.Epub, client.Mobi}}})
+	if m.format != 1 {
+		t.Errorf("preferred format is not selected: %v", m.format)
+	}
+	m.update(terminal.Key{Code: terminal.KeyRight})
+	m.update(terminal.Key{Code: terminal.KeyRight})
+	if m.format != 0 {
+		t.Errorf("format selection does not wrap: %v", m.format)
+	}
+
+	commands := m.update(terminal.Key{Code: terminal.KeyEnter})
+	if !reflect.DeepEqual(commands, []interface{}{runDownload{id: "1", format: client.Fb2}}) {
+		t.Fatalf("download = %v", commands)
+	}
+	if commands := m.update(terminal.Key{Code: terminal.KeyEnter}); commands != nil {
+		t.Errorf("second download while downloading = %v", commands)
+	}
+	m.update(downloadDone{book: &client.SavedBook{Path: "/books/1.fb2.zip", Warnings: []string{"book is not recorded in library: disk full"}}})
+	if m.downloading != "" || m.status != "Saved at /books/1.fb2.zip; warning: book is not recorded in library: disk full" {
+		t.Errorf("after download status = %v", m.status)
+	}
+	m.update(downloadDone{book: &client.SavedBook{Path: "/books/1.epub", Owned: true}})
+	if m.status != "Already in library: /books/1.epub" {
+		t.Errorf("owned book status = %v", m.status)
+	}
+
+	commands = m.update(terminal.Key{Code: terminal.KeyDown})
+	if !reflect.DeepEqual(commands, []interface{}{runInfo{"2"}}) || m.selected != 1 {
+		t.Errorf("down = %v, selected %d", commands, m.selected)
+	}
+	m.update(infoDone{id: "2", err: errors.New("mirror is down")})
+	m.update(terminal.Key{Code: terminal.KeyUp})
+	if commands := m.update(terminal.Key{Code: terminal.KeyDown}); commands != nil {
+		t.Errorf("failed info is requested again: %v", commands)
+	}
+	if commands := m.update(terminal.Key{Code: terminal.KeyDown}); commands != nil || m.selected != 1 {
+		t.Errorf("selection moved past the end: %d", m.selected)
+	}
+
+	m.update(terminal.Key{Code: terminal.KeyEscape})
+	if !m.quit {
+		t.Error("escape does not quit")
+	}
+}
+
+func TestModel_Render(t *testing.T) {
+	m := newModel("test", client.Epub)
+	m.update(searchDone{query: "test", items: testItems})
+	m.update(infoDone{id: "1", info: &client.InfoResult{
+		ID:         "1",
+		Title:      "Нежить",
+		Rating:     "хорошо",
+		Size:       "2263K",
+		Formats:    []string{client.Fb2, client.Epub},
+		Annotation: "Лучшие рассказы о нежити",
+	}})
+	lines := m.render(60, 12)
+	if len(lines) != 12 {
+		t.Fatalf("render() gives %d lines", len(lines))
+	}
+	screen := strings.Join(lines, "\n")
+	for _, want := range []string{"Search: test", reverseVideo + "Нежить — Джон", "Size: 2263K", "Rating: хорошо", "Formats: fb2 [epub]", "Лучшие рассказы", "2 books found"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen does not contain %q:\n%s", want, screen)
+		}
+	}
+}
+
+func Test_fit(t *testing.T) {
+	if got := fit("Нежить", 3); got != "Неж" {
+		t.Errorf("fit() = %q", got)
+	}
+	if got := fit("Нет\nсвязи", 9); got != "Нет связи" {
+		t.Errorf("fit() = %q", got)
+	}
+	if got := fit("Нежить", 8); got != "Нежить  " {
+		t.Errorf("fit() = %q", got)
+	}
+}
@@ -0,0 +1,141 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/pager"
+)
+
+const (
+	helpText     = "↑↓ book  ←→ format  Enter download  Esc quit"
+	reverseVideo = "\x1b[7m"
+	resetStyle   = "\x1b[0m"
+	minWidth     = 40
+	minHeight    = 8
+)
+
+// render draws the whole screen as lines of exactly width characters.
+func (m *model) render(width int, height int) []string {
+	if width < minWidth {
+		width = minWidth
+	}
+	if height < minHeight {
+		height = minHeight
+	}
+	indicator := ""
+	if m.busy() {
+		indicator = spinner[m.frame%len(spinner)]
+	}
+	lines := []string{
+		fit("Search: "+m.query, width-2) + " " + fit(indicator, 1),
+		strings.Repeat("─", width),
+	}
+
+	listWidth := width * 2 / 5
+	previewWidth := width - listWidth - 3
+	bodyHeight := height - 4
+	list := m.renderList(listWidth, bodyHeight)
+	preview := m.renderPreview(previewWidth)
+	for i := 0; i < bodyHeight; i++ {
+		left := strings.Repeat(" ", listWidth)
+		if i < len(list) {
+			left = list[i]
+		}
+		right := ""
+		if i < len(preview) {
+			right = preview[i]
+		}
+		lines = append(lines, left+" │ "+fit(right, previewWidth))
+	}
+
+	status := m.status
+	if m.downloading != "" {
+		status = fmt.Sprintf("Downloading %s %s", m.downloading, spinner[m.frame%len(spinner)])
+	}
+	lines = append(lines, strings.Repeat("─", width), fit(status+"  "+helpText, width))
+	return lines
+}
+
+// renderList scrolls the list so selected book is visible and highlights it.
+func (m *model) renderList(width int, height int) []string {
+	top := 0
+	if m.selected >= height {
+		top = m.selected - height + 1
+	}
+	var lines []string
+	for i := top; i < len(m.items) && len(lines) < height; i++ {
+		item := m.items[i]
+		text := fit(item.Title+" — "+strings.Join(item.Authors, ", "), width)
+		if i == m.selected {
+			text = reverseVideo + text + resetStyle
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+func (m *model) renderPreview(width int) []string {
+	book := m.current()
+	if book == nil {
+		return nil
+	}
+	if err := m.failures[book.ID]; err != nil {
+		return []string{"Book info is not available: " + err.Error()}
+	}
+	info := m.infos[book.ID]
+	if info == nil {
+		return []string{"Loading book info…"}
+	}
+	var lines []string
+	for _, line := range pager.Render(&client.BookText{Title: info.Title}, width) {
+		lines = append(lines, line.Text)
+	}
+	fields := []struct{ name, value string }{
+		{"Authors", strings.Join(info.Authors, ", ")},
+		{"Genre", info.Genre},
+		{"Series", strings.TrimSpace(info.Series + " " + info.SeriesNumber)},
+		{"Year", info.Year},
+		{"Size", info.Size},
+		{"Rating", info.Rating},
+	}
+	for _, field := range fields {
+		if field.value != "" {
+			lines = append(lines, fit(field.name+": "+field.value, width))
+		}
+	}
+	lines = append(lines, "Formats: "+m.renderFormats(info), "")
+	if info.Annotation != "" {
+		annotation := &client.BookText{Blocks: []client.TextBlock{{Text: info.Annotation}}}
+		for _, line := range pager.Render(annotation, width) {
+			lines = append(lines, line.Text)
+		}
+	}
+	return lines
+}
+
+func (m *model) renderFormats(info *client.InfoResult) string {
+	if len(info.Formats) == 0 {
+		return "not available"
+	}
+	formats := make([]string, 0, len(info.Formats))
+	for i, format := range info.Formats {
+		if i == m.format {
+			format = "[" + format + "]"
+		}
+		formats = append(formats, format)
+	}
+	return strings.Join(formats, " ")
+}
+
+// fit cuts or pads text to width characters, line breaks of error messages are flattened.
+func fit(text string, width int) string {
+	text = strings.NewReplacer("\n", " ", "\t", " ").Replace(text)
+	length := utf8.RuneCountInString(text)
+	if length > width {
+		return string([]rune(text)[:width])
+	}
+	return text + strings.Repeat(" ", width-length)
+}
@@ -0,0 +1,139 @@
+// Package tui is a full screen terminal interface to search, preview and download books.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/slivtime/flibusta-cli/pkg/client"
+	"github.com/slivtime/flibusta-cli/pkg/terminal"
+)
+
+const (
+	searchDelay  = 400 * time.Millisecond
+	tickInterval = 150 * time.Millisecond
+)
+
+// Source runs requests of the interface. Download saves the book unless library has it.
+type Source interface {
+	Search(query string) ([]client.ListItem, error)
+	Info(id string) (*client.InfoResult, error)
+	Download(id string, format string) (*client.SavedBook, error)
+}
+
+type App struct {
+	source Source
+	model  *model
+	in     *os.File
+	out    io.Writer
+}
+
+// New creates interface starting with the query, preferred format is selected when book has it.
+func New(source Source, query string, preferred string, in *os.File, out io.Writer) *App {
+	return &App{
+		source: source,
+		model:  newModel(query, preferred),
+		in:     in,
+		out:    out,
+	}
+}
+
+// Run shows the interface until user quits.
+func (a *App) Run() error {
+	fd := int(a.in.Fd())
+	restore, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return errors.New("interactive mode needs a terminal: " + err.Error())
+	}
+	defer restore()
+	// Alternate screen keeps terminal history intact, cursor is hidden
+	io.WriteString(a.out, "\x1b[?1049h\x1b[?25l")
+	defer io.WriteString(a.out, "\x1b[?25h\x1b[?1049l")
+
+	messages := make(chan interface{}, 16)
+	go readKeys(a.in, messages)
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	a.run(a.model.start(), messages)
+	for !a.model.quit {
+		a.draw(fd)
+		var msg interface{}
+		select {
+		case msg = <-messages:
+		case <-ticker.C:
+			if !a.model.busy() {
+				continue
+			}
+			msg = tick{}
+		}
+		a.run(a.model.update(msg), messages)
+	}
+	return nil
+}
+
+// run executes commands of the model in background, results come back as messages.
+func (a *App) run(commands []interface{}, messages chan<- interface{}) {
+	for _, command := range commands {
+		switch command := command.(type) {
+		case scheduleSearch:
+			time.AfterFunc(searchDelay, func() {
+				messages <- searchDue{command.query}
+			})
+		case runSearch:
+			go func() {
+				items, err := a.source.Search(command.query)
+				messages <- searchDone{query: command.query, items: items, err: err}
+			}()
+		case runInfo:
+			go func() {
+				info, err := a.source.Info(command.id)
+				messages <- infoDone{id: command.id, info: info, err: err}
+			}()
+		case runDownload:
+			go func() {
+				book, err := a.source.Download(command.id, command.format)
+				messages <- downloadDone{book: book, err: err}
+			}()
+		}
+	}
+}
+
+func (a *App) draw(fd int) {
+	width, height, err := terminal.Size(fd)
+	if err != nil {
+		width, height = 80, 24
+	}
+	buf := &strings.Builder{}
+	buf.WriteString("\x1b[H")
+	for i, line := range a.model.render(width, height) {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		buf.WriteString(line)
+		buf.WriteString("\x1b[K")
+	}
+	io.WriteString(a.out, buf.String())
+}
+
+func readKeys(in io.Reader, messages chan<- interface{}) {
+	reader := bufio.NewReader(in)
+	buf := make([]byte, 64)
+	var rest []byte
+	for {
+		n, err := reader.Read(buf)
+		if err != nil {
+			messages <- terminal.Key{Code: terminal.KeyQuit}
+			return
+		}
+		var keys []terminal.Key
+		keys, rest = terminal.ParseKeys(append(rest, buf[:n]...))
+		for _, k := range keys {
+			messages <- k
+		}
+	}
+}